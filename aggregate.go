@@ -0,0 +1,139 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/LambdaIM/proofDP/math"
+)
+
+// constant
+const (
+	errAggregateSignaturesFmt = "Failed to aggregate signatures: %s"
+	errAggregatePubKeysFmt    = "Failed to aggregate public keys: %s"
+	errNothingToAggregate     = "nothing to aggregate"
+
+	// the domain separation prefix of the PoP, which keeps it apart from the
+	// signatures on the message hashes
+	popDomain = "proofDP/BLS/PoP/v1"
+)
+
+// AggregateSignatures aggregates the given signatures into one, which is the
+// product of all of them. The aggregate of n signers is verified by n + 1
+// pairings against distinct hashes by VerifyAggregate, or by 2 pairings
+// against the same hash by FastAggregateVerify.
+func AggregateSignatures(sigs []Signature) (Signature, error) {
+	if len(sigs) == 0 {
+		return Signature{}, fmt.Errorf(errAggregateSignaturesFmt, errNothingToAggregate)
+	}
+	res := sigs[0]
+	for _, s := range sigs[1:] {
+		res = math.EllipticMul(res, s)
+	}
+	return res, nil
+}
+
+// AggregatePubKeys aggregates the given public keys into one, which verifies
+// the aggregate signature of the same message by FastAggregateVerify. All the
+// keys should have passed VerifyPossession. An error is returned if the keys
+// sum up to the identity, e.g. {pk, -pk}, which would accept any signature.
+func AggregatePubKeys(pks []SignPubKey) (SignPubKey, error) {
+	if len(pks) == 0 {
+		return SignPubKey{}, fmt.Errorf(errAggregatePubKeysFmt, errNothingToAggregate)
+	}
+	res := pks[0].key
+	for _, pk := range pks[1:] {
+		res = math.EllipticMul(res, pk.key)
+	}
+	if res.IsInfinity() {
+		return SignPubKey{}, fmt.Errorf(errAggregatePubKeysFmt, errIdentitySignPubKey)
+	}
+	return newSignPubKey(res), nil
+}
+
+// VerifyAggregate validates if 's' is the aggregate of the signatures on the
+// hashes 'hs' by the keys 'pks' respectively, i.e.
+// e(s, g) == Prod(e(H(h_i), pk_i))
+// The hashes should be distinct, otherwise false is returned, and so are the
+// identity signature & keys.
+func VerifyAggregate(s Signature, hs [][sha256.Size]byte, pks []SignPubKey) bool {
+	if len(hs) == 0 || len(hs) != len(pks) || s.IsInfinity() {
+		return false
+	}
+
+	seen := make(map[[sha256.Size]byte]bool, len(hs))
+	ps := make([]*math.PreparedPoint, 0, len(hs)+1)
+	qs := make([]math.EllipticPoint, 0, len(hs)+1)
+	ps = append(ps, math.GetPreparedGenerator())
+	qs = append(qs, s)
+	for i, h := range hs {
+		if seen[h] || pks[i].key.IsInfinity() {
+			return false
+		}
+		seen[h] = true
+		ps = append(ps, pks[i].prepared())
+		qs = append(qs, math.EllipticNeg(math.HashToEllipticPt(h[:])))
+	}
+
+	return pairingCheck(math.QuadraticIdentity(), ps, qs)
+}
+
+// FastAggregateVerify validates if 's' is the aggregate of the signatures on
+// the same hash 'h' by the keys 'pks', which takes 2 pairings only no matter
+// how many keys there are. All the keys should have passed VerifyPossession.
+// Any identity key, the identity aggregate key & the identity signature are
+// rejected.
+func FastAggregateVerify(s Signature, h [sha256.Size]byte, pks []SignPubKey) bool {
+	for _, pk := range pks {
+		if pk.key.IsInfinity() {
+			return false
+		}
+	}
+	pk, err := AggregatePubKeys(pks)
+	if err != nil {
+		return false
+	}
+	return VerifySignature(s, h, pk)
+}
+
+// popHash returns the point a PoP signs, i.e. H(domain || pk)
+func popHash(pk SignPubKey) math.EllipticPoint {
+	return math.HashToEllipticPt(append([]byte(popDomain), pk.key.CompressedBytes()...))
+}
+
+// ProvePossession creates the proof of possession (PoP) of the key pair, i.e.
+// the signature on its own public key
+func (sk *SignPrivKey) ProvePossession() Signature {
+	return math.EllipticPowSecret(popHash(sk.Pk), sk.key)
+}
+
+// VerifyPossession validates if 'proof' is the PoP of 'pk', which should be
+// checked before 'pk' is aggregated. Without it, a signer could pick the
+// rogue key pk' = g^x / Prod(pk_i) to forge the aggregate alone. The identity
+// key is always rejected.
+func VerifyPossession(pk SignPubKey, proof Signature) bool {
+	if pk.key.IsInfinity() {
+		return false
+	}
+	// e(proof, g) == e(H(domain || pk), pk)
+	return pairingCheck(math.QuadraticIdentity(),
+		[]*math.PreparedPoint{math.GetPreparedGenerator(), pk.prepared()},
+		[]math.EllipticPoint{proof, math.EllipticNeg(popHash(pk))})
+}
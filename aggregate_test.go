@@ -0,0 +1,151 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/LambdaIM/proofDP/math"
+	"github.com/stretchr/testify/require"
+)
+
+const aggregateTestSigners = 8
+
+func getRandSignPrivKeys(t *testing.T, n int) ([]*SignPrivKey, []SignPubKey) {
+	sks := make([]*SignPrivKey, n)
+	pks := make([]SignPubKey, n)
+	for i := range sks {
+		k, err := math.RandGaloisElem()
+		require.NoError(t, err)
+		sks[i] = newSignPrivKey(k)
+		pks[i] = sks[i].Pk
+		require.True(t, VerifyPossession(pks[i], sks[i].ProvePossession()))
+	}
+	return sks, pks
+}
+
+func TestVerifyAggregate(t *testing.T) {
+	sks, pks := getRandSignPrivKeys(t, aggregateTestSigners)
+
+	hs := make([][sha256.Size]byte, len(sks))
+	sigs := make([]Signature, len(sks))
+	for i, sk := range sks {
+		hs[i] = sha256.Sum256([]byte{byte(i)})
+		sigs[i] = sk.Sign(hs[i])
+	}
+	s, err := AggregateSignatures(sigs)
+	require.NoError(t, err)
+	require.True(t, VerifyAggregate(s, hs, pks))
+
+	// the keys bound to the wrong hashes
+	pks[0], pks[1] = pks[1], pks[0]
+	require.False(t, VerifyAggregate(s, hs, pks))
+	pks[0], pks[1] = pks[1], pks[0]
+
+	// a missing signature
+	partial, err := AggregateSignatures(sigs[1:])
+	require.NoError(t, err)
+	require.False(t, VerifyAggregate(partial, hs, pks))
+	require.True(t, VerifyAggregate(partial, hs[1:], pks[1:]))
+
+	// the duplicate hashes are rejected
+	hs[1] = hs[0]
+	sigs[1] = sks[1].Sign(hs[1])
+	s, err = AggregateSignatures(sigs)
+	require.NoError(t, err)
+	require.False(t, VerifyAggregate(s, hs, pks))
+
+	require.False(t, VerifyAggregate(s, nil, nil))
+	require.False(t, VerifyAggregate(s, hs, pks[1:]))
+	_, err = AggregateSignatures(nil)
+	require.Error(t, err)
+}
+
+func TestFastAggregateVerify(t *testing.T) {
+	sks, pks := getRandSignPrivKeys(t, aggregateTestSigners)
+
+	h := sha256.Sum256([]byte("audit result"))
+	sigs := make([]Signature, len(sks))
+	for i, sk := range sks {
+		sigs[i] = sk.Sign(h)
+	}
+	s, err := AggregateSignatures(sigs)
+	require.NoError(t, err)
+	require.True(t, FastAggregateVerify(s, h, pks))
+	require.False(t, FastAggregateVerify(s, h, pks[1:]))
+	require.False(t, FastAggregateVerify(s, sha256.Sum256([]byte("another result")), pks))
+	require.False(t, FastAggregateVerify(s, h, nil))
+
+	pk, err := AggregatePubKeys(pks)
+	require.NoError(t, err)
+	require.True(t, VerifySignature(s, h, pk))
+	_, err = AggregatePubKeys(nil)
+	require.Error(t, err)
+}
+
+func TestRogueKey(t *testing.T) {
+	_, honest := getRandSignPrivKeys(t, 1)
+
+	// the rogue key pk' = g^x / pk forges the aggregate of both keys alone
+	x, err := math.RandGaloisElem()
+	require.NoError(t, err)
+	rogue := newSignPubKey(math.EllipticMul(
+		math.EllipticPowSecret(math.GetGenerator(), x), math.EllipticNeg(honest[0].key)))
+	h := sha256.Sum256([]byte("forged result"))
+	forged := math.EllipticPowSecret(math.HashToEllipticPt(h[:]), x)
+	require.True(t, FastAggregateVerify(forged, h, []SignPubKey{honest[0], rogue}))
+
+	// but no PoP can be made for the rogue key without its private key
+	require.False(t, VerifyPossession(rogue, math.EllipticPowSecret(popHash(rogue), x)))
+
+	// the PoP is no signature on any message hash, & vice versa
+	sks, pks := getRandSignPrivKeys(t, 1)
+	pop := sks[0].ProvePossession()
+	require.False(t, VerifyPossession(pks[0], sks[0].Sign(sha256.Sum256(pks[0].key.Bytes()))))
+	require.False(t, VerifySignature(pop, sha256.Sum256(pks[0].key.Bytes()), pks[0]))
+	require.False(t, VerifyPossession(honest[0], pop))
+
+	// the identity key is always rejected
+	identity := newSignPubKey(math.EllipticMul(pks[0].key, math.EllipticNeg(pks[0].key)))
+	require.False(t, VerifyPossession(identity, math.EllipticMul(pop, math.EllipticNeg(pop))))
+}
+
+func TestIdentityAggregate(t *testing.T) {
+	sks, pks := getRandSignPrivKeys(t, 1)
+
+	// -pk comes with a valid PoP made by the private key -x
+	neg := newSignPrivKey(math.GaloisSub(math.NewGaloisElem(0), sks[0].key))
+	require.True(t, VerifyPossession(neg.Pk, neg.ProvePossession()))
+
+	// but {pk, -pk} sums up to the identity, which would accept the identity
+	// signature of any message
+	_, err := AggregatePubKeys([]SignPubKey{pks[0], neg.Pk})
+	require.Error(t, err)
+	g := math.GetGenerator()
+	inf := math.EllipticMul(g, math.EllipticNeg(g))
+	h := sha256.Sum256([]byte("forged result"))
+	require.False(t, FastAggregateVerify(inf, h, []SignPubKey{pks[0], neg.Pk}))
+
+	// nor is the identity signature or an identity key accepted otherwise
+	require.False(t, FastAggregateVerify(inf, h, pks))
+	require.False(t, FastAggregateVerify(sks[0].Sign(h), h, []SignPubKey{pks[0], newSignPubKey(inf)}))
+	require.False(t, VerifyAggregate(inf, [][sha256.Size]byte{h}, pks))
+	require.False(t, VerifyAggregate(sks[0].Sign(h), [][sha256.Size]byte{h, sha256.Sum256(nil)},
+		[]SignPubKey{pks[0], newSignPubKey(inf)}))
+}
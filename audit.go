@@ -0,0 +1,116 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"errors"
+
+	"github.com/LambdaIM/proofDP/math"
+)
+
+// constant
+const (
+	errUnmatchedAudit = "unmatched challenges & proofs num of an owner"
+)
+
+// OwnerAudit holds the challenges to the files of a data owner, together with
+// the proofs against them, i.e. Proofs[i] against Chals[i].
+type OwnerAudit struct {
+	Params *PublicParams
+	Chals  []ChalSet
+	Proofs []Proof
+}
+
+// BatchAudit checks the proofs of all the data owners together, sharing one
+// final exponentiation among all the owners' PublicParams. The result tells
+// for each owner if all of its proofs are valid. Only when the combined check
+// fails, the owners are checked one by one to locate the invalid ones.
+func BatchAudit(audits []OwnerAudit) ([]bool, error) {
+	for _, a := range audits {
+		if len(a.Chals) != len(a.Proofs) {
+			return nil, errors.New(errUnmatchedAudit)
+		}
+	}
+
+	res := make([]bool, len(audits))
+	ok, err := auditCheck(audits)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		for i := range res {
+			res[i] = true
+		}
+		return res, nil
+	}
+
+	for i, a := range audits {
+		bad, err := BatchVerifyProofSets(a.Params, a.Chals, a.Proofs)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = len(bad) == 0
+	}
+	return res, nil
+}
+
+// auditCheck runs the combined small exponents test on all the owners, i.e.
+// Prod(r_i^d_i) * e(Prod(sigma_i^(gamma_i*d_i)), g) * Prod_o(e(-Y_o, v_o)) == 1
+// where Y_o is the right hand side pairing param of the owner 'o', which takes
+// one multi-pairing of (owners + 1) pairs
+func auditCheck(audits []OwnerAudit) (bool, error) {
+	var r math.QuadraticElem
+	sigmas := &batchTerms{}
+	preps := []*math.PreparedPoint{}
+	params := []math.EllipticPoint{}
+	num := 0
+	for _, a := range audits {
+		if len(a.Chals) == 0 {
+			continue
+		}
+
+		bt := &batchTerms{}
+		for i := range a.Chals {
+			if len(a.Chals[i]) == 0 {
+				return false, nil
+			}
+			if err := bt.add(a.Chals[i], a.Proofs[i]); err != nil {
+				return false, err
+			}
+		}
+
+		if num == 0 {
+			r = bt.r
+		} else {
+			r = math.QuadraticMul(r, bt.r)
+		}
+		// the sigma terms of all the owners are paired with 'g' together
+		sigmas.sigmas = append(sigmas.sigmas, bt.sigmas...)
+		sigmas.sigmaExps = append(sigmas.sigmaExps, bt.sigmaExps...)
+		preps = append(preps, a.Params.preparedV())
+		params = append(params, math.EllipticNeg(bt.rhsParam(a.Params)))
+		num++
+	}
+	if num == 0 {
+		return true, nil
+	}
+
+	preps = append(preps, math.GetPreparedGenerator())
+	params = append(params, sigmas.sigma())
+	return pairingCheck(r, preps, params), nil
+}
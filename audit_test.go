@@ -0,0 +1,82 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"testing"
+
+	"github.com/LambdaIM/proofDP/math"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	auditTestOwners = 3
+	auditTestProofs = 3
+)
+
+func genOwnerAudits(t *testing.T, badOwner int) []OwnerAudit {
+	audits := make([]OwnerAudit, auditTestOwners)
+	for o := range audits {
+		sp, err := GeneratePrivateParams(getRandSecret())
+		require.NoError(t, err)
+		u, err := math.RandEllipticPt()
+		require.NoError(t, err)
+		pp := sp.GeneratePublicParams(u)
+
+		bad := map[int]bool{}
+		if o == badOwner {
+			bad[1] = true
+		}
+		cs, ps := genBatch(t, sp, pp, auditTestProofs, bad)
+		audits[o] = OwnerAudit{
+			Params: pp,
+			Chals:  make([]ChalSet, len(cs)),
+			Proofs: ps,
+		}
+		for i := range cs {
+			audits[o].Chals[i] = ChalSet{cs[i]}
+		}
+	}
+	return audits
+}
+
+func TestBatchAudit(t *testing.T) {
+	audits := genOwnerAudits(t, -1)
+	res, err := BatchAudit(audits)
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, true, true}, res)
+
+	// the proofs of an owner do not pass under the params of another
+	audits[0].Params, audits[2].Params = audits[2].Params, audits[0].Params
+	res, err = BatchAudit(audits)
+	require.NoError(t, err)
+	require.Equal(t, []bool{false, true, false}, res)
+
+	audits = genOwnerAudits(t, 1)
+	res, err = BatchAudit(audits)
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, false, true}, res)
+
+	audits[0].Proofs = audits[0].Proofs[1:]
+	_, err = BatchAudit(audits)
+	require.Error(t, err)
+
+	res, err = BatchAudit(nil)
+	require.NoError(t, err)
+	require.Empty(t, res)
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"crypto/rand"
+	"errors"
+	"sort"
+
+	"github.com/LambdaIM/proofDP/math"
+)
+
+// constant
+const (
+	// the bit length of the random small exponents
+	smallExpBits = 64
+
+	errUnmatchedBatch = "unmatched challenges & proofs num"
+)
+
+// randSmallExp returns a random exponent of 2^smallExpBits + d, where d is
+// drawn uniformly from smallExpBits bits, thus never zero
+func randSmallExp() (math.GaloisElem, error) {
+	buf := make([]byte, 1+smallExpBits/8)
+	if _, err := rand.Read(buf[1:]); err != nil {
+		return math.GaloisElem{}, err
+	}
+	buf[0] = 1
+	return math.BytesToGaloisElem(buf), nil
+}
+
+// BatchVerifyProofs validates a batch of proofs against their challenges,
+// i.e. ps[i] against cs[i], all under the same PublicParams. The indices of
+// the invalid proofs are returned, which is empty if all of them pass.
+// The proofs are checked at once by the small exponents test, i.e. with
+// the random small d_i of randSmallExp,
+// Prod(r_i^d_i) * e(Prod(sigma_i^(gamma_i*d_i)), g) == e(Prod(X_i^(gamma_i*d_i)) * u^Sum(miu_i*d_i), v)
+// which costs 2 pairings for the whole batch, and an invalid proof passes it
+// with a chance of 2^-smallExpBits at most. The failed batches are bisected
+// to find the invalid proofs.
+func BatchVerifyProofs(pp *PublicParams, cs []Chal, ps []Proof) ([]int, error) {
+	css := make([]ChalSet, len(cs))
+	for i := range cs {
+		css[i] = ChalSet{cs[i]}
+	}
+	return BatchVerifyProofSets(pp, css, ps)
+}
+
+// BatchVerifyProofSets works in the same way that BatchVerifyProofs does,
+// on the aggregated proofs against challenge sets.
+func BatchVerifyProofSets(pp *PublicParams, css []ChalSet, ps []Proof) ([]int, error) {
+	if len(css) != len(ps) {
+		return nil, errors.New(errUnmatchedBatch)
+	}
+
+	idxs := make([]int, len(ps))
+	for i := range idxs {
+		idxs[i] = i
+	}
+
+	bad, err := bisectBatch(idxs, func(sub []int) (bool, error) {
+		return batchCheck(pp, css, ps, sub)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Ints(bad)
+	return bad, nil
+}
+
+// bisectBatch runs 'check' on the whole batch 'idxs' first. Once the batch
+// fails, it is split into halves to locate the invalid items recursively.
+func bisectBatch(idxs []int, check func([]int) (bool, error)) ([]int, error) {
+	if len(idxs) == 0 {
+		return nil, nil
+	}
+
+	ok, err := check(idxs)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return nil, nil
+	}
+	if len(idxs) == 1 {
+		return idxs, nil
+	}
+
+	mid := len(idxs) / 2
+	lhs, err := bisectBatch(idxs[:mid], check)
+	if err != nil {
+		return nil, err
+	}
+	rhs, err := bisectBatch(idxs[mid:], check)
+	if err != nil {
+		return nil, err
+	}
+	return append(lhs, rhs...), nil
+}
+
+// batchTerms accumulates the terms of the small exponents test. The points
+// & their exponents are kept for one multi-exponentiation in the end.
+type batchTerms struct {
+	num       int
+	r         math.QuadraticElem
+	sigmas    []math.EllipticPoint
+	sigmaExps []math.GaloisElem
+	chals     []math.EllipticPoint
+	chalExps  []math.GaloisElem
+	miu       math.GaloisElem
+}
+
+// add puts the proof 'p' against 'cs' into the batch with a random small exponent
+func (bt *batchTerms) add(cs ChalSet, p Proof) error {
+	d, err := randSmallExp()
+	if err != nil {
+		return err
+	}
+
+	gammaD := math.GaloisMul(math.HashQuadraticToGalois(p.r), d)
+	rD := math.QuadraticPow(p.r, d)
+	miuD := math.GaloisMul(p.miu, d)
+	if bt.num == 0 {
+		bt.r, bt.miu = rD, miuD
+	} else {
+		bt.r = math.QuadraticMul(bt.r, rD)
+		bt.miu = math.GaloisAdd(bt.miu, miuD)
+	}
+	bt.sigmas = append(bt.sigmas, p.sigma)
+	bt.sigmaExps = append(bt.sigmaExps, gammaD)
+	// X_i^(gamma_i*d_i) = Prod(H(idx_ij)^(nu_ij*gamma_i*d_i))
+	bt.chals = append(bt.chals, cs.points()...)
+	for _, nu := range cs.nus() {
+		bt.chalExps = append(bt.chalExps, math.GaloisMul(nu, gammaD))
+	}
+	bt.num++
+	return nil
+}
+
+// sigma returns Prod(sigma_i^(gamma_i*d_i)), the param paired with 'g' in the test
+func (bt *batchTerms) sigma() math.EllipticPoint {
+	return math.MultiExp(bt.sigmas, bt.sigmaExps)
+}
+
+// rhsParam returns Prod(X_i^(gamma_i*d_i)) * u^Sum(miu_i*d_i), the param
+// paired with 'v' in the test
+func (bt *batchTerms) rhsParam(pp *PublicParams) math.EllipticPoint {
+	return math.EllipticMul(math.MultiExp(bt.chals, bt.chalExps), pp.genPow(0, bt.miu))
+}
+
+// batchCheck runs the small exponents test on the proofs picked by 'idxs'
+func batchCheck(pp *PublicParams, css []ChalSet, ps []Proof, idxs []int) (bool, error) {
+	if len(idxs) == 1 {
+		return VerifyProofSet(pp, css[idxs[0]], ps[idxs[0]]), nil
+	}
+
+	bt := &batchTerms{}
+	for _, i := range idxs {
+		if len(css[i]) == 0 {
+			return false, nil
+		}
+		if err := bt.add(css[i], ps[i]); err != nil {
+			return false, err
+		}
+	}
+
+	return pairingCheck(bt.r,
+		[]*math.PreparedPoint{math.GetPreparedGenerator(), pp.preparedV()},
+		[]math.EllipticPoint{bt.sigma(), math.EllipticNeg(bt.rhsParam(pp))}), nil
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/LambdaIM/proofDP/math"
+	"github.com/stretchr/testify/require"
+)
+
+const batchTestSize = 8
+
+// genBatch creates 'n' challenges & proofs on random blocks, the blocks
+// listed in 'bad' are corrupted before proving
+func genBatch(t *testing.T, sp *PrivateParams, pp *PublicParams, n int, bad map[int]bool) ([]Chal, []Proof) {
+	cs := make([]Chal, n)
+	ps := make([]Proof, n)
+	for i := 0; i < n; i++ {
+		id := NewBlockID([]byte("batch-file"), int64(i))
+		data := getRandFile(128)
+		tag, err := GenBlockTag(sp, pp, id, bytes.NewReader(data))
+		require.NoError(t, err)
+
+		cs[i], err = GenBlockChal(id)
+		require.NoError(t, err)
+
+		if bad[i] {
+			data[0] ^= byte(1)
+		}
+		ps[i], err = Prove(pp, cs[i], tag, bytes.NewReader(data))
+		require.NoError(t, err)
+	}
+	return cs, ps
+}
+
+func TestBatchVerifyProofs(t *testing.T) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	require.NoError(t, err)
+
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	pp := sp.GeneratePublicParams(u)
+
+	cs, ps := genBatch(t, sp, pp, batchTestSize, nil)
+	bad, err := BatchVerifyProofs(pp, cs, ps)
+	require.NoError(t, err)
+	require.Empty(t, bad)
+
+	cs, ps = genBatch(t, sp, pp, batchTestSize, map[int]bool{2: true, 7: true})
+	bad, err = BatchVerifyProofs(pp, cs, ps)
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 7}, bad)
+
+	// a proof answering another challenge
+	ps[0], ps[1] = ps[1], ps[0]
+	bad, err = BatchVerifyProofs(pp, cs, ps)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1, 2, 7}, bad)
+
+	_, err = BatchVerifyProofs(pp, cs[1:], ps)
+	require.Error(t, err)
+
+	bad, err = BatchVerifyProofs(pp, nil, nil)
+	require.NoError(t, err)
+	require.Empty(t, bad)
+}
+
+func TestBatchVerifyProofSets(t *testing.T) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	require.NoError(t, err)
+
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	pp := sp.GeneratePublicParams(u)
+
+	data := getRandFile(fileTestSize)
+	tags, meta, err := TagFile(sp, pp, []byte("batch-file"), bytes.NewReader(data), fileTestBlockSize)
+	require.NoError(t, err)
+
+	css := make([]ChalSet, 3)
+	ps := make([]Proof, 3)
+	for i := range css {
+		css[i], err = GenFileChalSet(meta, []int64{int64(i), int64(i + 3), int64(i + 6)})
+		require.NoError(t, err)
+		ps[i], err = ProveFile(pp, meta, css[i], tags, bytes.NewReader(data))
+		require.NoError(t, err)
+	}
+
+	bad, err := BatchVerifyProofSets(pp, css, ps)
+	require.NoError(t, err)
+	require.Empty(t, bad)
+
+	css[1] = css[1][:2]
+	bad, err = BatchVerifyProofSets(pp, css, ps)
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, bad)
+}
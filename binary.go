@@ -0,0 +1,609 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/LambdaIM/proofDP/math"
+)
+
+// constant
+const (
+	binaryMagic   = "PfDP"
+	binaryVersion = 1
+
+	sizeOfBinaryHeader   = len(binaryMagic) + 2
+	sizeOfBinaryChecksum = 4
+	sizeOfBinaryLen      = 4
+	sizeOfBinaryInt      = 8
+
+	errBinaryTooShort       = "binary data too short"
+	errBinaryMagic          = "unknown binary magic"
+	errBinaryVersionFmt     = "unsupported binary version %d"
+	errBinaryTypeFmt        = "unmatched binary type %d"
+	errBinaryChecksum       = "binary checksum mismatch"
+	errBinaryTrailing       = "trailing bytes in binary data"
+	errBinaryFieldFmt       = "invalid binary field: %s"
+	errMarshalBinaryFmt     = "Failed to encode %s: %s"
+	errParsePrivateParamFmt = "Failed to restore PrivateParams: %s"
+	errParseSignPubKeyFmt   = "Failed to restore SignPubKey: %s"
+	errParseSignPrivKeyFmt  = "Failed to restore SignPrivKey: %s"
+	errParseTagFmt          = "Failed to restore Tag: %s"
+)
+
+// the type bytes of the binary encodings
+const (
+	binTypePublicParams byte = iota + 1
+	binTypePrivateParams
+	binTypeChal
+	binTypeChalSet
+	binTypeProof
+	binTypeSectorProof
+	binTypeFileMeta
+	binTypeBlockID
+	binTypeSignPubKey
+	binTypeSignPrivKey
+	binTypeKeyDerivationParams
+	binTypeTag
+)
+
+// binWriter builds the binary encoding of a value, i.e.
+// magic || version || type || body || checksum
+// where the checksum is the CRC-32 (IEEE) of all the bytes before it. The
+// body is made of the fixed-length fields, the big-endian integers & the
+// lists prefixed with their lengths as uint32. The first error of the fields
+// is kept & returned by seal.
+type binWriter struct {
+	buf []byte
+	err error
+}
+
+func newBinWriter(typ byte) *binWriter {
+	buf := make([]byte, 0, 256)
+	buf = append(buf, binaryMagic...)
+	buf = append(buf, binaryVersion, typ)
+	return &binWriter{buf: buf}
+}
+
+func (w *binWriter) uint32(n int) {
+	w.buf = append(w.buf, make([]byte, sizeOfBinaryLen)...)
+	binary.BigEndian.PutUint32(w.buf[len(w.buf)-sizeOfBinaryLen:], uint32(n))
+}
+
+func (w *binWriter) int64(n int64) {
+	w.buf = append(w.buf, make([]byte, sizeOfBinaryInt)...)
+	binary.BigEndian.PutUint64(w.buf[len(w.buf)-sizeOfBinaryInt:], uint64(n))
+}
+
+func (w *binWriter) bytes(b []byte) {
+	w.uint32(len(b))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *binWriter) field(m interface{ MarshalBinary() ([]byte, error) }) {
+	if w.err != nil {
+		return
+	}
+	b, err := m.MarshalBinary()
+	if err != nil {
+		w.err = err
+		return
+	}
+	w.buf = append(w.buf, b...)
+}
+
+func (w *binWriter) galois(e math.GaloisElem) {
+	w.field(&e)
+}
+
+func (w *binWriter) point(p math.EllipticPoint) {
+	w.field(&p)
+}
+
+func (w *binWriter) quadratic(e math.QuadraticElem) {
+	w.field(&e)
+}
+
+// seal appends the checksum & returns the result
+func (w *binWriter) seal() ([]byte, error) {
+	if w.err != nil {
+		return nil, w.err
+	}
+	w.buf = append(w.buf, make([]byte, sizeOfBinaryChecksum)...)
+	body := w.buf[:len(w.buf)-sizeOfBinaryChecksum]
+	binary.BigEndian.PutUint32(w.buf[len(body):], crc32.ChecksumIEEE(body))
+	return w.buf, nil
+}
+
+// binReader reads the fields of a binary encoding, the first error is kept
+// & returned by close
+type binReader struct {
+	data []byte
+	err  error
+}
+
+// openBinary validates the header & the checksum of 'data', and returns a
+// binReader over the body
+func openBinary(data []byte, typ byte) *binReader {
+	if len(data) < sizeOfBinaryHeader+sizeOfBinaryChecksum {
+		return &binReader{err: errors.New(errBinaryTooShort)}
+	}
+	if string(data[:len(binaryMagic)]) != binaryMagic {
+		return &binReader{err: errors.New(errBinaryMagic)}
+	}
+	if v := data[len(binaryMagic)]; v != binaryVersion {
+		return &binReader{err: fmt.Errorf(errBinaryVersionFmt, v)}
+	}
+	if t := data[len(binaryMagic)+1]; t != typ {
+		return &binReader{err: fmt.Errorf(errBinaryTypeFmt, t)}
+	}
+	body := data[:len(data)-sizeOfBinaryChecksum]
+	if binary.BigEndian.Uint32(data[len(body):]) != crc32.ChecksumIEEE(body) {
+		return &binReader{err: errors.New(errBinaryChecksum)}
+	}
+	return &binReader{data: body[sizeOfBinaryHeader:]}
+}
+
+func (r *binReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.data) {
+		r.err = errors.New(errBinaryTooShort)
+		return nil
+	}
+	res := r.data[:n]
+	r.data = r.data[n:]
+	return res
+}
+
+// count reads a list length, which is checked against the remaining data
+// for the items of 'size' bytes each
+func (r *binReader) count(size int) int {
+	b := r.next(sizeOfBinaryLen)
+	if r.err != nil {
+		return 0
+	}
+	n := uint64(binary.BigEndian.Uint32(b))
+	if n*uint64(size) > uint64(len(r.data)) {
+		r.err = errors.New(errBinaryTooShort)
+		return 0
+	}
+	return int(n)
+}
+
+func (r *binReader) int64() int64 {
+	b := r.next(sizeOfBinaryInt)
+	if r.err != nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+func (r *binReader) bytes() []byte {
+	return append([]byte{}, r.next(r.count(1))...)
+}
+
+func (r *binReader) field(u interface{ UnmarshalBinary([]byte) error }, size int) {
+	b := r.next(size)
+	if r.err != nil {
+		return
+	}
+	if err := u.UnmarshalBinary(b); err != nil {
+		r.err = fmt.Errorf(errBinaryFieldFmt, err.Error())
+	}
+}
+
+func (r *binReader) galois() math.GaloisElem {
+	var e math.GaloisElem
+	r.field(&e, math.GaloisElemSize)
+	return e
+}
+
+func (r *binReader) point() math.EllipticPoint {
+	var p math.EllipticPoint
+	r.field(&p, math.EllipticPointSize)
+	return p
+}
+
+func (r *binReader) quadratic() math.QuadraticElem {
+	var e math.QuadraticElem
+	r.field(&e, math.QuadraticElemSize)
+	return e
+}
+
+// close returns the first error met, or an error if any data is left
+func (r *binReader) close() error {
+	if r.err == nil && len(r.data) != 0 {
+		r.err = errors.New(errBinaryTrailing)
+	}
+	return r.err
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (pp *PublicParams) MarshalBinary() ([]byte, error) {
+	w := newBinWriter(binTypePublicParams)
+	w.point(pp.v)
+	w.point(pp.u)
+	w.quadratic(pp.e)
+	// the sector generators u_2..u_s, just as Marshal does
+	extra := pp.generators()[1:]
+	w.uint32(len(extra))
+	for i := range extra {
+		w.point(extra[i])
+	}
+	res, err := w.seal()
+	if err != nil {
+		return nil, fmt.Errorf(errMarshalBinaryFmt, "PublicParams", err.Error())
+	}
+	return res, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, all the points are
+// checked just as ParsePublicParams does
+func (pp *PublicParams) UnmarshalBinary(data []byte) error {
+	r := openBinary(data, binTypePublicParams)
+	v, u, e := r.point(), r.point(), r.quadratic()
+	var us []math.EllipticPoint
+	if n := r.count(math.EllipticPointSize); n > 0 {
+		us = append(make([]math.EllipticPoint, 0, n+1), u)
+		for i := 0; i < n; i++ {
+			us = append(us, r.point())
+		}
+	}
+	if err := r.close(); err != nil {
+		return fmt.Errorf(errParsePublicParamsFmt, err.Error())
+	}
+
+	*pp = *restorePublicParams(v, u, e, us)
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (sp *PrivateParams) MarshalBinary() ([]byte, error) {
+	w := newBinWriter(binTypePrivateParams)
+	w.galois(sp.x)
+	res, err := w.seal()
+	if err != nil {
+		return nil, fmt.Errorf(errMarshalBinaryFmt, "PrivateParams", err.Error())
+	}
+	return res, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (sp *PrivateParams) UnmarshalBinary(data []byte) error {
+	r := openBinary(data, binTypePrivateParams)
+	x := r.galois()
+	if err := r.close(); err != nil {
+		return fmt.Errorf(errParsePrivateParamFmt, err.Error())
+	}
+	sp.x = x
+	return nil
+}
+
+func (c *Chal) writeBinary(w *binWriter) {
+	w.bytes(c.idx)
+	w.galois(c.nu)
+}
+
+func readChal(r *binReader) Chal {
+	return Chal{
+		idx: r.bytes(),
+		nu:  r.galois(),
+	}
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (c *Chal) MarshalBinary() ([]byte, error) {
+	w := newBinWriter(binTypeChal)
+	c.writeBinary(w)
+	res, err := w.seal()
+	if err != nil {
+		return nil, fmt.Errorf(errMarshalBinaryFmt, "Chal", err.Error())
+	}
+	return res, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (c *Chal) UnmarshalBinary(data []byte) error {
+	r := openBinary(data, binTypeChal)
+	res := readChal(r)
+	if err := r.close(); err != nil {
+		return fmt.Errorf(errParseChalFmt, err.Error())
+	}
+	*c = res
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (cs ChalSet) MarshalBinary() ([]byte, error) {
+	w := newBinWriter(binTypeChalSet)
+	w.uint32(len(cs))
+	for i := range cs {
+		cs[i].writeBinary(w)
+	}
+	res, err := w.seal()
+	if err != nil {
+		return nil, fmt.Errorf(errMarshalBinaryFmt, "ChalSet", err.Error())
+	}
+	return res, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, an empty challenge
+// set is rejected just as ParseChalSet does
+func (cs *ChalSet) UnmarshalBinary(data []byte) error {
+	r := openBinary(data, binTypeChalSet)
+	// a Chal takes at least the length of its index & nu
+	n := r.count(sizeOfBinaryLen + math.GaloisElemSize)
+	res := make(ChalSet, n)
+	for i := range res {
+		res[i] = readChal(r)
+	}
+	if err := r.close(); err != nil {
+		return fmt.Errorf(errParseChalSetFmt, err.Error())
+	}
+	if len(res) == 0 {
+		return fmt.Errorf(errParseChalSetFmt, "empty challenge set")
+	}
+	*cs = res
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (p *Proof) MarshalBinary() ([]byte, error) {
+	w := newBinWriter(binTypeProof)
+	w.galois(p.miu)
+	w.point(p.sigma)
+	w.quadratic(p.r)
+	res, err := w.seal()
+	if err != nil {
+		return nil, fmt.Errorf(errMarshalBinaryFmt, "Proof", err.Error())
+	}
+	return res, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, the sigma & r are
+// checked just as ParseProof does
+func (p *Proof) UnmarshalBinary(data []byte) error {
+	r := openBinary(data, binTypeProof)
+	miu, sigma, gt := r.galois(), r.point(), r.quadratic()
+	if err := r.close(); err != nil {
+		return fmt.Errorf(errParseProofFmt, err.Error())
+	}
+	*p = Proof{
+		miu:   miu,
+		sigma: sigma,
+		r:     gt,
+	}
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (p *SectorProof) MarshalBinary() ([]byte, error) {
+	w := newBinWriter(binTypeSectorProof)
+	w.point(p.sigma)
+	w.quadratic(p.r)
+	w.uint32(len(p.mius))
+	for i := range p.mius {
+		w.galois(p.mius[i])
+	}
+	res, err := w.seal()
+	if err != nil {
+		return nil, fmt.Errorf(errMarshalBinaryFmt, "SectorProof", err.Error())
+	}
+	return res, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (p *SectorProof) UnmarshalBinary(data []byte) error {
+	r := openBinary(data, binTypeSectorProof)
+	sigma, gt := r.point(), r.quadratic()
+	mius := make([]math.GaloisElem, r.count(math.GaloisElemSize))
+	for i := range mius {
+		mius[i] = r.galois()
+	}
+	if err := r.close(); err != nil {
+		return fmt.Errorf(errParseSectorProofFmt, err.Error())
+	}
+	if len(mius) == 0 {
+		return fmt.Errorf(errParseSectorProofFmt, errNoSectorGiven)
+	}
+	*p = SectorProof{
+		mius:  mius,
+		sigma: sigma,
+		r:     gt,
+	}
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. Unlike Marshal, the
+// coding parameters are always kept, which are all zero for the files not
+// encoded by EncodeFile.
+func (m *FileMeta) MarshalBinary() ([]byte, error) {
+	w := newBinWriter(binTypeFileMeta)
+	w.bytes(m.fileID)
+	w.int64(m.blockSize)
+	w.int64(m.size)
+	w.int64(m.dataBlocks)
+	w.int64(m.parityBlocks)
+	w.int64(m.origSize)
+	return w.seal()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (m *FileMeta) UnmarshalBinary(data []byte) error {
+	r := openBinary(data, binTypeFileMeta)
+	res := &FileMeta{
+		fileID:       r.bytes(),
+		blockSize:    r.int64(),
+		size:         r.int64(),
+		dataBlocks:   r.int64(),
+		parityBlocks: r.int64(),
+		origSize:     r.int64(),
+	}
+	if err := r.close(); err != nil {
+		return fmt.Errorf(errParseFileMetaFmt, err.Error())
+	}
+	if err := res.validate(); err != nil {
+		return fmt.Errorf(errParseFileMetaFmt, err.Error())
+	}
+	*m = *res
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (id BlockID) MarshalBinary() ([]byte, error) {
+	w := newBinWriter(binTypeBlockID)
+	w.bytes(id.fileID)
+	w.int64(id.index)
+	return w.seal()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (id *BlockID) UnmarshalBinary(data []byte) error {
+	r := openBinary(data, binTypeBlockID)
+	fileID, index := r.bytes(), r.int64()
+	if err := r.close(); err != nil {
+		return fmt.Errorf(errParseBlockIDFmt, err.Error())
+	}
+	*id = BlockID{
+		fileID: fileID,
+		index:  index,
+	}
+	return nil
+}
+
+// MarshalTagBinary encodes a Tag in the headered binary encoding. Tag is an
+// alias of math.EllipticPoint, whose own MarshalBinary carries no header, so
+// the tags should be stored or sent with this one instead.
+func MarshalTagBinary(t Tag) ([]byte, error) {
+	w := newBinWriter(binTypeTag)
+	w.point(t)
+	res, err := w.seal()
+	if err != nil {
+		return nil, fmt.Errorf(errMarshalBinaryFmt, "Tag", err.Error())
+	}
+	return res, nil
+}
+
+// ParseTagBinary restores a Tag from the result of MarshalTagBinary, which
+// is checked to be in the subgroup of order r as ParseTag does
+func ParseTagBinary(data []byte) (Tag, error) {
+	r := openBinary(data, binTypeTag)
+	t := r.point()
+	if err := r.close(); err != nil {
+		return Tag{}, fmt.Errorf(errParseTagFmt, err.Error())
+	}
+	return t, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (pk SignPubKey) MarshalBinary() ([]byte, error) {
+	w := newBinWriter(binTypeSignPubKey)
+	w.point(pk.key)
+	res, err := w.seal()
+	if err != nil {
+		return nil, fmt.Errorf(errMarshalBinaryFmt, "SignPubKey", err.Error())
+	}
+	return res, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, the key is checked
+// to be in the subgroup of order r
+func (pk *SignPubKey) UnmarshalBinary(data []byte) error {
+	r := openBinary(data, binTypeSignPubKey)
+	key := r.point()
+	if err := r.close(); err != nil {
+		return fmt.Errorf(errParseSignPubKeyFmt, err.Error())
+	}
+	if key.IsInfinity() {
+		return fmt.Errorf(errParseSignPubKeyFmt, errIdentitySignPubKey)
+	}
+	*pk = newSignPubKey(key)
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, only the private part
+// is kept as the public key is derived from it
+func (sk *SignPrivKey) MarshalBinary() ([]byte, error) {
+	w := newBinWriter(binTypeSignPrivKey)
+	w.galois(sk.key)
+	res, err := w.seal()
+	if err != nil {
+		return nil, fmt.Errorf(errMarshalBinaryFmt, "SignPrivKey", err.Error())
+	}
+	return res, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (sk *SignPrivKey) UnmarshalBinary(data []byte) error {
+	r := openBinary(data, binTypeSignPrivKey)
+	key := r.galois()
+	if err := r.close(); err != nil {
+		return fmt.Errorf(errParseSignPrivKeyFmt, err.Error())
+	}
+	*sk = *newSignPrivKey(key)
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, where the cost params
+// are all 0 for HKDF
+func (kp *KeyDerivationParams) MarshalBinary() ([]byte, error) {
+	w := newBinWriter(binTypeKeyDerivationParams)
+	w.int64(int64(kp.kdf))
+	w.bytes(kp.salt)
+	w.bytes([]byte(kp.context))
+	for _, v := range kp.costs() {
+		w.int64(int64(v))
+	}
+	return w.seal()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (kp *KeyDerivationParams) UnmarshalBinary(data []byte) error {
+	r := openBinary(data, binTypeKeyDerivationParams)
+	kdf := r.int64()
+	res := &KeyDerivationParams{
+		salt:    r.bytes(),
+		context: string(r.bytes()),
+	}
+	// the negative costs turn out of range as uint64
+	var costs [3]uint64
+	for i := range costs {
+		costs[i] = uint64(r.int64())
+	}
+	if err := r.close(); err != nil {
+		return fmt.Errorf(errParseKeyDerivationParamsFmt, err.Error())
+	}
+	if kdf < 0 || kdf > 1<<8-1 {
+		return fmt.Errorf(errParseKeyDerivationParamsFmt, errInvalidKDF)
+	}
+	res.kdf = KDF(kdf)
+	if err := res.setCosts(costs); err != nil {
+		return fmt.Errorf(errParseKeyDerivationParamsFmt, err.Error())
+	}
+	if err := res.validate(); err != nil {
+		return fmt.Errorf(errParseKeyDerivationParamsFmt, err.Error())
+	}
+	*kp = *res
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/LambdaIM/proofDP/math"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTrip encodes 'm' & restores it into 'u'
+func roundTrip(t *testing.T, m encoding.BinaryMarshaler, u encoding.BinaryUnmarshaler) []byte {
+	data, err := m.MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, u.UnmarshalBinary(data))
+	return data
+}
+
+func TestBinaryEncoding(t *testing.T) {
+	sp, pp := getSectorParams(t)
+
+	var restoredSP PrivateParams
+	roundTrip(t, sp, &restoredSP)
+	require.Equal(t, sp.Marshal(), restoredSP.Marshal())
+	var restoredPP PublicParams
+	roundTrip(t, pp, &restoredPP)
+	require.Equal(t, pp.Marshal(), restoredPP.Marshal())
+	require.Equal(t, pp.Sectors(), restoredPP.Sectors())
+
+	data := getRandFile(3*pp.SectorBlockSize() + 7)
+	tags, meta, err := TagFileSectors(&restoredSP, &restoredPP, []byte("binary-file"), bytes.NewReader(data))
+	require.NoError(t, err)
+	var restoredMeta FileMeta
+	roundTrip(t, meta, &restoredMeta)
+	require.Equal(t, meta.Marshal(), restoredMeta.Marshal())
+
+	cs, err := GenFileChalSet(&restoredMeta, []int64{0, 2})
+	require.NoError(t, err)
+	var restoredCS ChalSet
+	roundTrip(t, cs, &restoredCS)
+	require.True(t, cs.Equal(restoredCS))
+	var restoredChal Chal
+	roundTrip(t, &cs[1], &restoredChal)
+	require.True(t, cs[1].Equal(restoredChal))
+	encodedTag, err := MarshalTagBinary(tags[0])
+	require.NoError(t, err)
+	require.Equal(t, sizeOfBinaryHeader+math.EllipticPointSize+sizeOfBinaryChecksum, len(encodedTag))
+	require.Equal(t, binaryMagic, string(encodedTag[:len(binaryMagic)]))
+	require.Equal(t, []byte{binaryVersion, binTypeTag}, encodedTag[len(binaryMagic):sizeOfBinaryHeader])
+	tagBody := encodedTag[:len(encodedTag)-sizeOfBinaryChecksum]
+	require.Equal(t, crc32.ChecksumIEEE(tagBody), binary.BigEndian.Uint32(encodedTag[len(tagBody):]))
+	restoredTag, err := ParseTagBinary(encodedTag)
+	require.NoError(t, err)
+	require.Equal(t, tags[0].Marshal(), restoredTag.Marshal())
+	encodedTag[sizeOfBinaryHeader] ^= 1
+	_, err = ParseTagBinary(encodedTag)
+	require.Error(t, err)
+	_, err = ParseTagBinary(tagBody[sizeOfBinaryHeader:])
+	require.Error(t, err)
+
+	proof, err := ProveFileSectors(pp, meta, restoredCS, tags, bytes.NewReader(data))
+	require.NoError(t, err)
+	var restoredProof SectorProof
+	roundTrip(t, &proof, &restoredProof)
+	require.True(t, VerifySectorProof(&restoredPP, cs, restoredProof))
+
+	id := NewBlockID([]byte("binary-file"), 42)
+	var restoredID BlockID
+	roundTrip(t, id, &restoredID)
+	require.True(t, id.Equal(restoredID))
+
+	secret := make([]byte, 32)
+	_, err = rand.Read(secret)
+	require.NoError(t, err)
+	sk, err := GenerateSignPrivKeyFromSecret(secret)
+	require.NoError(t, err)
+	var restoredSK SignPrivKey
+	roundTrip(t, sk, &restoredSK)
+	var restoredPK SignPubKey
+	roundTrip(t, sk.Pk, &restoredPK)
+	hash := [32]byte{1}
+	require.True(t, VerifySignature(restoredSK.Sign(hash), hash, restoredPK))
+}
+
+func TestBinaryCorruption(t *testing.T) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	require.NoError(t, err)
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	pp := sp.GeneratePublicParams(u)
+
+	data := getRandFile(fileTestBlockSize)
+	id := NewBlockID([]byte("corrupted-file"), 0)
+	tag, err := GenBlockTag(sp, pp, id, bytes.NewReader(data))
+	require.NoError(t, err)
+	chal, err := GenBlockChal(id)
+	require.NoError(t, err)
+	proof, err := Prove(pp, chal, tag, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	encoded, err := proof.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, sizeOfBinaryHeader+math.GaloisElemSize+math.EllipticPointSize+
+		math.QuadraticElemSize+sizeOfBinaryChecksum, len(encoded))
+	require.Equal(t, binaryMagic, string(encoded[:len(binaryMagic)]))
+	var res Proof
+	require.NoError(t, res.UnmarshalBinary(encoded))
+	require.True(t, VerifyProof(pp, chal, res))
+
+	// any bit flip is caught by the checksum, if not by the header checks
+	for i := range encoded {
+		corrupted := append([]byte{}, encoded...)
+		corrupted[i] ^= 1
+		require.Error(t, res.UnmarshalBinary(corrupted))
+	}
+	require.Error(t, res.UnmarshalBinary(encoded[:len(encoded)-1]))
+	require.Error(t, res.UnmarshalBinary(nil))
+
+	// the encoding of another type
+	var c Chal
+	require.Error(t, c.UnmarshalBinary(encoded))
+
+	// the fields are checked as the string parsers do, even with a valid
+	// checksum, & nothing is allowed after them
+	w := newBinWriter(binTypeProof)
+	w.galois(proof.miu)
+	// the compressed (0, 0), which is a point of order 2
+	lowPt := make([]byte, math.EllipticPointSize)
+	lowPt[0] = 0x02
+	w.buf = append(w.buf, lowPt...)
+	w.quadratic(proof.r)
+	lowProof, err := w.seal()
+	require.NoError(t, err)
+	require.Error(t, res.UnmarshalBinary(lowProof))
+
+	w = newBinWriter(binTypeProof)
+	w.galois(proof.miu)
+	w.point(proof.sigma)
+	w.quadratic(proof.r)
+	w.int64(0)
+	trailing, err := w.seal()
+	require.NoError(t, err)
+	require.Error(t, res.UnmarshalBinary(trailing))
+
+	// the list lengths are checked before any allocation
+	w = newBinWriter(binTypeChalSet)
+	w.uint32(1 << 30)
+	huge, err := w.seal()
+	require.NoError(t, err)
+	var cs ChalSet
+	require.Error(t, cs.UnmarshalBinary(huge))
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// constant
+const (
+	errParseBlockIDFmt = "Failed to restore BlockID: %s"
+
+	// the domain separation prefix of an encoded BlockID, which also tells
+	// it apart from the legacy decimal string indices
+	blockIDDomain = "proofDP/BlockID/v1"
+
+	sizeOfFileIDLen = 4
+	sizeOfBlockNo   = 8
+)
+
+// BlockID identifies a block by the file it belongs to & its position in
+// the file. It is the (Fid||index) parameter in PDP paper, which is encoded
+// as: domain || len(Fid) || Fid || index, all the integers in big-endian.
+// Unlike the bare int64 index, blocks of different files never share the
+// same BlockID, so a tag of one file cannot answer the challenges of another.
+type BlockID struct {
+	fileID []byte
+	index  int64
+}
+
+// NewBlockID creates a BlockID instance for the 'index'-th block of the
+// file identified by 'fileID', which can be a hash or an UUID of the file
+func NewBlockID(fileID []byte, index int64) BlockID {
+	return BlockID{
+		fileID: append([]byte{}, fileID...),
+		index:  index,
+	}
+}
+
+// FileID returns the identifier of the file the block belongs to
+func (id BlockID) FileID() []byte {
+	return id.fileID
+}
+
+// Index returns the position of the block in its file
+func (id BlockID) Index() int64 {
+	return id.index
+}
+
+// Equal works
+func (id BlockID) Equal(a BlockID) bool {
+	return bytes.Equal(id.fileID, a.fileID) && id.index == a.index
+}
+
+// String returns a human-readable form of the BlockID
+func (id BlockID) String() string {
+	return hex.EncodeToString(id.fileID) + "/" + strconv.FormatInt(id.index, intStrRadix)
+}
+
+// Bytes returns the unambiguous encoding of the BlockID
+func (id BlockID) Bytes() []byte {
+	res := make([]byte, 0, len(blockIDDomain)+sizeOfFileIDLen+len(id.fileID)+sizeOfBlockNo)
+	res = append(res, blockIDDomain...)
+	res = append(res, make([]byte, sizeOfFileIDLen)...)
+	binary.BigEndian.PutUint32(res[len(blockIDDomain):], uint32(len(id.fileID)))
+	res = append(res, id.fileID...)
+	res = append(res, make([]byte, sizeOfBlockNo)...)
+	binary.BigEndian.PutUint64(res[len(res)-sizeOfBlockNo:], uint64(id.index))
+	return res
+}
+
+// ParseBlockID trys to restore a BlockID instance from its encoding
+func ParseBlockID(b []byte) (BlockID, error) {
+	if !bytes.HasPrefix(b, []byte(blockIDDomain)) {
+		return BlockID{}, fmt.Errorf(errParseBlockIDFmt, "unknown domain")
+	}
+	b = b[len(blockIDDomain):]
+
+	if len(b) < sizeOfFileIDLen {
+		return BlockID{}, fmt.Errorf(errParseBlockIDFmt, "unexpected length")
+	}
+	fileIDLen := binary.BigEndian.Uint32(b)
+	b = b[sizeOfFileIDLen:]
+	if uint64(len(b)) != uint64(fileIDLen)+sizeOfBlockNo {
+		return BlockID{}, fmt.Errorf(errParseBlockIDFmt, "unexpected length")
+	}
+
+	return NewBlockID(b[:fileIDLen], int64(binary.BigEndian.Uint64(b[fileIDLen:]))), nil
+}
+
+// BlockID restores the BlockID a Chal instance is created against. An error
+// is returned for the Chal instances created with a legacy int64 index.
+func (c *Chal) BlockID() (BlockID, error) {
+	return ParseBlockID(c.idx)
+}
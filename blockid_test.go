@@ -0,0 +1,100 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/LambdaIM/proofDP/math"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockIDEncoding(t *testing.T) {
+	ids := []BlockID{
+		NewBlockID(nil, 0),
+		NewBlockID([]byte("file"), 1),
+		NewBlockID([]byte("file1"), 0),
+		NewBlockID(getRandSecret(), 1<<40),
+	}
+
+	for i, id := range ids {
+		restored, err := ParseBlockID(id.Bytes())
+		require.NoError(t, err)
+		require.True(t, id.Equal(restored))
+
+		for j := i + 1; j < len(ids); j++ {
+			require.False(t, bytes.Equal(id.Bytes(), ids[j].Bytes()))
+		}
+	}
+
+	// ("file", 1) & ("file1", 0) must not be confused
+	require.False(t, ids[1].Equal(ids[2]))
+
+	_, err := ParseBlockID([]byte("19"))
+	require.Error(t, err)
+	_, err = ParseBlockID(ids[1].Bytes()[:len(ids[1].Bytes())-1])
+	require.Error(t, err)
+
+	legacy, err := GenChal(19)
+	require.NoError(t, err)
+	_, err = legacy.BlockID()
+	require.Error(t, err)
+
+	c, err := GenBlockChal(ids[3])
+	require.NoError(t, err)
+	id, err := c.BlockID()
+	require.NoError(t, err)
+	require.True(t, id.Equal(ids[3]))
+}
+
+func TestBlockTagAcrossFiles(t *testing.T) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	require.NoError(t, err)
+
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	pp := sp.GeneratePublicParams(u)
+
+	data := getRandFile(fileTestBlockSize)
+	idA := NewBlockID([]byte("file-a"), 7)
+	idB := NewBlockID([]byte("file-b"), 7)
+
+	tag, err := GenBlockTag(sp, pp, idA, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	chalA, err := GenBlockChal(idA)
+	require.NoError(t, err)
+	proof, err := Prove(pp, chalA, tag, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.True(t, VerifyProof(pp, chalA, proof))
+
+	// the tag of file-a cannot answer the challenge against file-b
+	chalB, err := GenBlockChal(idB)
+	require.NoError(t, err)
+	proof, err = Prove(pp, chalB, tag, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.False(t, VerifyProof(pp, chalB, proof))
+
+	// neither can it answer the legacy challenge with the same block number
+	legacy, err := GenChal(7)
+	require.NoError(t, err)
+	proof, err = Prove(pp, legacy, tag, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.False(t, VerifyProof(pp, legacy, proof))
+}
@@ -0,0 +1,533 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/LambdaIM/proofDP/math"
+)
+
+// constant
+const (
+	errRunDKGFmt          = "Failed to run DKG as participant %d: %s"
+	errParseDKGMessageFmt = "Failed to restore DKGMessage: %s"
+	errGenPartialTagFmt   = "Failed to generate partial tag for given data (index:%s): %s"
+	errCombineTagsFmt     = "Failed to combine partial tags: %s"
+	errParsePartialTagFmt = "Failed to restore PartialTag: %s"
+	errNotEnoughQualified = "not enough qualified dealers"
+	errNoJustificationFmt = "no justification received from dealer %d"
+	errMissingShareFmt    = "missing share of qualified dealer %d"
+
+	// separator between the fields of a marshaled DKGMessage
+	dkgMessageSep = ";"
+	// separator between the complaining participant & the revealed share
+	dkgRevealSep = ":"
+)
+
+// the kinds of the DKG messages, which are also the steps of the DKG
+const (
+	dkgCommitments = iota + 1
+	dkgShare
+	dkgComplaints
+	dkgJustification
+)
+
+// DKGMessage is a message sent from one participant of the DKG to others
+type DKGMessage struct {
+	from    int
+	kind    int
+	payload string
+}
+
+// From returns the index of the sender
+func (m *DKGMessage) From() int {
+	return m.from
+}
+
+// Marshal works as a serialization routine
+func (m *DKGMessage) Marshal() string {
+	return strings.Join([]string{
+		strconv.Itoa(m.from), strconv.Itoa(m.kind), m.payload,
+	}, dkgMessageSep)
+}
+
+// ParseDKGMessage trys to restore a DKGMessage instance
+func ParseDKGMessage(s string) (DKGMessage, error) {
+	parts := strings.Split(s, dkgMessageSep)
+	if len(parts) != 3 {
+		return DKGMessage{}, fmt.Errorf(errParseDKGMessageFmt, "unmatched parts num")
+	}
+
+	from, err := parseShareIndex(parts[0])
+	if err != nil {
+		return DKGMessage{}, fmt.Errorf(errParseDKGMessageFmt, err.Error())
+	}
+
+	kind, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return DKGMessage{}, fmt.Errorf(errParseDKGMessageFmt, err.Error())
+	}
+	if kind < dkgCommitments || kind > dkgJustification {
+		return DKGMessage{}, fmt.Errorf(errParseDKGMessageFmt, "unknown message kind")
+	}
+
+	return DKGMessage{
+		from:    from,
+		kind:    kind,
+		payload: parts[2],
+	}, nil
+}
+
+// DKGTransport delivers the DKG messages among the participants, e.g. over
+// the network. The DKG relies on the broadcast to deliver the same message
+// to all the participants, & on Send to keep the message from the others.
+// The sender of a DKGMessage is taken from its From() as is, thus the
+// transport must authenticate the channels, e.g. by TLS with the keys of the
+// participants, & Receive must drop the messages whose From() does not match
+// the authenticated sender. Otherwise a participant could deal, complain or
+// justify on behalf of the others.
+type DKGTransport interface {
+	// Broadcast sends 'msg' to all the other participants. It must be a
+	// reliable broadcast, i.e. either all the honest participants receive
+	// the same 'msg' or none of them does, otherwise they may end with the
+	// different qualified dealers & thus the different PublicParams.
+	Broadcast(msg DKGMessage) error
+	// Send sends 'msg' to the participant of index 'to' only
+	Send(to int, msg DKGMessage) error
+	// Receive returns the next message sent to the participant. An error is
+	// returned once no message arrives in time, which ends the current step
+	// of the DKG & the participants not heard from are taken as faulty.
+	Receive() (DKGMessage, error)
+}
+
+// PrivateParamsShare is the share of the PrivateParams held by a participant
+// of the DKG
+type PrivateParamsShare struct {
+	index       int
+	sp          PrivateParams
+	commitments ShareCommitments
+}
+
+// Index returns the index of the participant holding the share
+func (s *PrivateParamsShare) Index() int {
+	return s.index
+}
+
+// Commitments returns the joint commitments of the DKG, which verify the
+// partial tags of all the participants
+func (s *PrivateParamsShare) Commitments() ShareCommitments {
+	return s.commitments
+}
+
+// GeneratePublicParams returns the PublicParams instance of the shared
+// PrivateParams with the given 'u', which is the same for all the
+// participants of the DKG
+func (s *PrivateParamsShare) GeneratePublicParams(u math.EllipticPoint) *PublicParams {
+	return newPublicParams(s.commitments[0], u)
+}
+
+// GenPartialTag calculates the partial tag of the participant for the given
+// 'data' of the block identified by 'id', see GenBlockTag
+func (s *PrivateParamsShare) GenPartialTag(pp *PublicParams, id BlockID, data io.Reader) (PartialTag, error) {
+	t, err := genTag(&s.sp, pp, id.Bytes(), data)
+	if err != nil {
+		return PartialTag{}, fmt.Errorf(errGenPartialTagFmt, id.String(), err.Error())
+	}
+	return PartialTag{
+		index: s.index,
+		tag:   t,
+	}, nil
+}
+
+// PartialTag is the product of PrivateParamsShare.GenPartialTag
+type PartialTag struct {
+	index int
+	tag   Tag
+}
+
+// Index returns the index of the participant generating the partial tag
+func (t *PartialTag) Index() int {
+	return t.index
+}
+
+// Marshal works as a serialization routine
+func (t *PartialTag) Marshal() string {
+	return fmt.Sprintf("%d,%s", t.index, t.tag.Marshal())
+}
+
+// ParsePartialTag trys to restore a PartialTag instance
+func ParsePartialTag(s string) (PartialTag, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return PartialTag{}, fmt.Errorf(errParsePartialTagFmt, "unmatched parts num")
+	}
+
+	index, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return PartialTag{}, fmt.Errorf(errParsePartialTagFmt, err.Error())
+	}
+	return parsePartialTagFields(index, parts[1])
+}
+
+// parsePartialTagFields restores a PartialTag instance from the 'index' &
+// the encoded tag
+func parsePartialTagFields(index int, tagStr string) (PartialTag, error) {
+	if err := checkShareIndex(index); err != nil {
+		return PartialTag{}, fmt.Errorf(errParsePartialTagFmt, err.Error())
+	}
+
+	tag, err := ParseTag(tagStr)
+	if err != nil {
+		return PartialTag{}, fmt.Errorf(errParsePartialTagFmt, err.Error())
+	}
+
+	return PartialTag{
+		index: index,
+		tag:   tag,
+	}, nil
+}
+
+// VerifyPartialTag validates if 't' is the partial tag of the participant
+// committed by 'c' for the given 'data' of the block identified by 'id',
+// i.e. e(t, g) == e(H(id) * u^m, v_j), which tells the bad partial tags
+// apart before they are combined
+func VerifyPartialTag(pp *PublicParams, c ShareCommitments, id BlockID, data io.Reader, t PartialTag) bool {
+	if t.index < 1 || t.index > maxShareNum || len(c) == 0 {
+		return false
+	}
+	base, err := tagBase(pp, id.Bytes(), data)
+	if err != nil {
+		return false
+	}
+	pk := c.SharePubKey(t.index)
+	return pairingCheck(math.QuadraticIdentity(),
+		[]*math.PreparedPoint{math.GetPreparedGenerator(), pk.prepared()},
+		[]math.EllipticPoint{t.tag, math.EllipticNeg(base)})
+}
+
+// CombineTags combines the partial tags of distinct participants into the
+// tag of the shared PrivateParams. At least the threshold num of valid
+// partial tags are required, or the result fails the proofs.
+func CombineTags(parts []PartialTag) (Tag, error) {
+	indices := make([]int, len(parts))
+	tags := make([]math.EllipticPoint, len(parts))
+	for i := range parts {
+		indices[i], tags[i] = parts[i].index, parts[i].tag
+	}
+	res, err := combineInExponent(indices, tags)
+	if err != nil {
+		return Tag{}, fmt.Errorf(errCombineTagsFmt, err.Error())
+	}
+	return res, nil
+}
+
+// dkgParticipant holds the state of a participant during the DKG
+type dkgParticipant struct {
+	index, t, n int
+	tr          DKGTransport
+
+	// the messages received ahead of their steps
+	pending []DKGMessage
+	// the commitments & the shares of the dealers, by their indices
+	commitments map[int]ShareCommitments
+	shares      map[int]math.GaloisElem
+	// the complaints against the dealers, by the indices of the dealers
+	complaints   map[int][]int
+	disqualified map[int]bool
+}
+
+// RunDKG runs the Joint-Feldman DKG of the PrivateParams as the participant
+// of 'index' among 'n' participants indexed from 1 to n, any 't' of whom are
+// able to tag the blocks together, while no one ever holds the whole secret.
+// Every participant deals a random secret by the Feldman VSS, complains
+// about the invalid shares it receives, and reveals the shares it sent to
+// the complaining participants. The dealers with the invalid commitments or
+// the complaints left unjustified are disqualified, and the secret is the
+// sum of the secrets of the qualified dealers.
+// All the honest participants end with the same commitments & thus the same
+// PublicParams, as long as no more than n - t participants are faulty & the
+// broadcast of the transport is reliable. An error is returned if the
+// justification of an accused dealer never arrives, since the participant
+// could not tell if the others received it. Note that the Joint-Feldman DKG
+// allows the adversary to bias the secret slightly, which does not affect
+// the soundness of the tags.
+func RunDKG(index, t, n int, tr DKGTransport) (*PrivateParamsShare, error) {
+	if t < 1 || t > n || n > maxShareNum || index < 1 || index > n {
+		return nil, fmt.Errorf(errRunDKGFmt, index, errInvalidThreshold)
+	}
+
+	p := &dkgParticipant{
+		index:        index,
+		t:            t,
+		n:            n,
+		tr:           tr,
+		commitments:  make(map[int]ShareCommitments),
+		shares:       make(map[int]math.GaloisElem),
+		complaints:   make(map[int][]int),
+		disqualified: make(map[int]bool),
+	}
+	res, err := p.run()
+	if err != nil {
+		return nil, fmt.Errorf(errRunDKGFmt, index, err.Error())
+	}
+	return res, nil
+}
+
+func (p *dkgParticipant) run() (*PrivateParamsShare, error) {
+	// step 1: deal
+	secret, err := math.RandGaloisElem()
+	if err != nil {
+		return nil, err
+	}
+	coefs, commitments, err := newSharingPolynomial(secret, p.t)
+	if err != nil {
+		return nil, err
+	}
+	p.commitments[p.index] = commitments
+	p.shares[p.index] = evalPolynomial(coefs, p.index)
+	if err := p.tr.Broadcast(p.message(dkgCommitments, commitments.Marshal())); err != nil {
+		return nil, err
+	}
+	for j := 1; j <= p.n; j++ {
+		if j == p.index {
+			continue
+		}
+		share := evalPolynomial(coefs, j)
+		if err := p.tr.Send(j, p.message(dkgShare, share.Marshal())); err != nil {
+			return nil, err
+		}
+	}
+	p.receiveDeals()
+
+	// step 2: complain
+	var accused []string
+	for i := 1; i <= p.n; i++ {
+		if _, ok := p.shares[i]; !ok && !p.disqualified[i] {
+			accused = append(accused, strconv.Itoa(i))
+		}
+	}
+	if err := p.tr.Broadcast(p.message(dkgComplaints, strings.Join(accused, ","))); err != nil {
+		return nil, err
+	}
+	p.receiveComplaints()
+
+	// step 3: justify
+	var revealed []string
+	for _, j := range p.complaints[p.index] {
+		share := evalPolynomial(coefs, j)
+		revealed = append(revealed, strconv.Itoa(j)+dkgRevealSep+share.Marshal())
+	}
+	if err := p.tr.Broadcast(p.message(dkgJustification, strings.Join(revealed, ","))); err != nil {
+		return nil, err
+	}
+	if err := p.receiveJustifications(); err != nil {
+		return nil, err
+	}
+
+	return p.share()
+}
+
+func (p *dkgParticipant) message(kind int, payload string) DKGMessage {
+	return DKGMessage{
+		from:    p.index,
+		kind:    kind,
+		payload: payload,
+	}
+}
+
+// receive collects the messages of 'kind' from all the other participants,
+// at most one from each, until Receive fails
+func (p *dkgParticipant) receive(kind int) map[int]DKGMessage {
+	res := make(map[int]DKGMessage)
+	accept := func(msg DKGMessage) {
+		if msg.from < 1 || msg.from > p.n || msg.from == p.index {
+			return
+		}
+		if _, ok := res[msg.from]; !ok {
+			res[msg.from] = msg
+		}
+	}
+
+	// the messages received ahead of this step
+	pending := p.pending[:0]
+	for _, msg := range p.pending {
+		if msg.kind == kind {
+			accept(msg)
+		} else if msg.kind > kind {
+			pending = append(pending, msg)
+		}
+	}
+	p.pending = pending
+
+	for len(res) < p.n-1 {
+		msg, err := p.tr.Receive()
+		if err != nil {
+			break
+		}
+		switch {
+		case msg.kind == kind:
+			accept(msg)
+		case msg.kind > kind:
+			p.pending = append(p.pending, msg)
+		}
+	}
+	return res
+}
+
+// receiveDeals collects the commitments & the shares of the other dealers.
+// The dealers with no valid commitments are disqualified, while the invalid
+// shares are left missing for the complaints.
+func (p *dkgParticipant) receiveDeals() {
+	for i, msg := range p.receive(dkgCommitments) {
+		c, err := ParseShareCommitments(msg.payload)
+		if err != nil || len(c) != p.t {
+			continue
+		}
+		p.commitments[i] = c
+	}
+	for i := 1; i <= p.n; i++ {
+		if _, ok := p.commitments[i]; !ok {
+			p.disqualified[i] = true
+		}
+	}
+
+	for i, msg := range p.receive(dkgShare) {
+		share, err := math.ParseGaloisElem(msg.payload)
+		if err != nil || p.disqualified[i] || !p.commitments[i].verifyShare(p.index, share) {
+			continue
+		}
+		p.shares[i] = share
+	}
+}
+
+// receiveComplaints collects the complaints of all the participants, which
+// are the same for all the honest participants as they are broadcast
+func (p *dkgParticipant) receiveComplaints() {
+	for i := 1; i <= p.n; i++ {
+		if _, ok := p.shares[i]; !ok && !p.disqualified[i] {
+			p.complaints[i] = append(p.complaints[i], p.index)
+		}
+	}
+
+	for j, msg := range p.receive(dkgComplaints) {
+		if len(msg.payload) == 0 {
+			continue
+		}
+		seen := make(map[int]bool)
+		for _, part := range strings.Split(msg.payload, ",") {
+			i, err := parseShareIndex(part)
+			if err != nil || i > p.n || i == j || seen[i] {
+				continue
+			}
+			seen[i] = true
+			p.complaints[i] = append(p.complaints[i], j)
+		}
+	}
+	for i := range p.complaints {
+		sort.Ints(p.complaints[i])
+	}
+}
+
+// receiveJustifications collects the shares revealed by the accused dealers,
+// the dealers failing to justify all the complaints are disqualified. An
+// error is returned if no justification of an accused dealer arrives in time,
+// as the other participants may have received it.
+func (p *dkgParticipant) receiveJustifications() error {
+	msgs := p.receive(dkgJustification)
+	for i := 1; i <= p.n; i++ {
+		if _, ok := msgs[i]; !ok && len(p.complaints[i]) > 0 && !p.disqualified[i] && i != p.index {
+			return fmt.Errorf(errNoJustificationFmt, i)
+		}
+	}
+
+	for i, complainers := range p.complaints {
+		if p.disqualified[i] || i == p.index {
+			continue
+		}
+		revealed := make(map[int]math.GaloisElem)
+		if msg := msgs[i]; len(msg.payload) > 0 {
+			for _, part := range strings.Split(msg.payload, ",") {
+				kv := strings.Split(part, dkgRevealSep)
+				if len(kv) != 2 {
+					continue
+				}
+				j, err := parseShareIndex(kv[0])
+				if err != nil {
+					continue
+				}
+				share, err := math.ParseGaloisElem(kv[1])
+				if err != nil {
+					continue
+				}
+				revealed[j] = share
+			}
+		}
+
+		for _, j := range complainers {
+			share, ok := revealed[j]
+			if !ok || !p.commitments[i].verifyShare(j, share) {
+				p.disqualified[i] = true
+				break
+			}
+			if j == p.index {
+				p.shares[i] = share
+			}
+		}
+	}
+	return nil
+}
+
+// share sums up the shares & the commitments of the qualified dealers
+func (p *dkgParticipant) share() (*PrivateParamsShare, error) {
+	var qualified []int
+	for i := 1; i <= p.n; i++ {
+		if !p.disqualified[i] {
+			qualified = append(qualified, i)
+		}
+	}
+	if len(qualified) < p.t {
+		return nil, errors.New(errNotEnoughQualified)
+	}
+
+	for _, i := range qualified {
+		if _, ok := p.shares[i]; !ok {
+			return nil, fmt.Errorf(errMissingShareFmt, i)
+		}
+	}
+
+	x := p.shares[qualified[0]]
+	commitments := append(ShareCommitments{}, p.commitments[qualified[0]]...)
+	for _, i := range qualified[1:] {
+		x = math.GaloisAdd(x, p.shares[i])
+		for k := range commitments {
+			commitments[k] = math.EllipticMul(commitments[k], p.commitments[i][k])
+		}
+	}
+
+	return &PrivateParamsShare{
+		index:       p.index,
+		sp:          PrivateParams{x: x},
+		commitments: commitments,
+	}, nil
+}
@@ -0,0 +1,364 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/LambdaIM/proofDP/math"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	dkgTestT = 3
+	dkgTestN = 5
+)
+
+// dkgNetwork is an in-process simulated network among the DKG participants,
+// where the messages go through their string forms. Instead of a real clock,
+// Receive times out once all the running participants are waiting for the
+// messages, i.e. no message is ever going to arrive.
+type dkgNetwork struct {
+	n       int
+	mu      sync.Mutex
+	cond    *sync.Cond
+	inboxes map[int][]string
+	// the num of the participants running, & the ones waiting in Receive
+	running int
+	waiting map[int]bool
+	// increased on every timeout
+	epoch int
+}
+
+func newDKGNetwork(n int) *dkgNetwork {
+	net := &dkgNetwork{
+		n:       n,
+		inboxes: make(map[int][]string),
+		waiting: make(map[int]bool),
+	}
+	net.cond = sync.NewCond(&net.mu)
+	for i := 1; i <= n; i++ {
+		net.inboxes[i] = nil
+	}
+	return net
+}
+
+// join & leave mark the start & the end of a participant
+func (net *dkgNetwork) join() {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	net.running++
+}
+
+func (net *dkgNetwork) leave() {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	net.running--
+	net.checkTimeout()
+}
+
+// checkTimeout wakes up all the waiting participants to time out, if none
+// of the running participants is able to go on
+func (net *dkgNetwork) checkTimeout() bool {
+	if len(net.waiting) < net.running {
+		return false
+	}
+	for i := range net.waiting {
+		if len(net.inboxes[i]) > 0 {
+			return false
+		}
+	}
+	net.waiting = make(map[int]bool)
+	net.epoch++
+	net.cond.Broadcast()
+	return true
+}
+
+// dkgEndpoint is the DKGTransport of a participant on a dkgNetwork
+type dkgEndpoint struct {
+	net   *dkgNetwork
+	index int
+	// tamper rewrites the outgoing messages of a faulty participant, which
+	// drops the message by returning false
+	tamper func(to int, msg *DKGMessage) bool
+}
+
+func (e *dkgEndpoint) deliver(to int, msg DKGMessage) error {
+	if e.tamper != nil && !e.tamper(to, &msg) {
+		return nil
+	}
+	e.net.mu.Lock()
+	defer e.net.mu.Unlock()
+	if _, ok := e.net.inboxes[to]; !ok {
+		return errors.New("unknown participant")
+	}
+	e.net.inboxes[to] = append(e.net.inboxes[to], msg.Marshal())
+	e.net.cond.Broadcast()
+	return nil
+}
+
+func (e *dkgEndpoint) Broadcast(msg DKGMessage) error {
+	for to := 1; to <= e.net.n; to++ {
+		if to == e.index {
+			continue
+		}
+		if err := e.deliver(to, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *dkgEndpoint) Send(to int, msg DKGMessage) error {
+	return e.deliver(to, msg)
+}
+
+func (e *dkgEndpoint) Receive() (DKGMessage, error) {
+	net := e.net
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	for len(net.inboxes[e.index]) == 0 {
+		net.waiting[e.index] = true
+		if net.checkTimeout() {
+			return DKGMessage{}, errors.New("timeout")
+		}
+		epoch := net.epoch
+		net.cond.Wait()
+		if net.epoch != epoch {
+			// the messages sent after the timeout are left to the next step
+			return DKGMessage{}, errors.New("timeout")
+		}
+		delete(net.waiting, e.index)
+	}
+	s := net.inboxes[e.index][0]
+	net.inboxes[e.index] = net.inboxes[e.index][1:]
+	return ParseDKGMessage(s)
+}
+
+// runDKG runs the DKG among all the participants concurrently, with the
+// given faulty behaviors by the indices of the participants, where all the
+// honest participants should succeed
+func runDKG(t *testing.T, tampers map[int]func(to int, msg *DKGMessage) bool) []*PrivateParamsShare {
+	res, errs := runDKGWithErrors(tampers)
+	for i := 1; i <= dkgTestN; i++ {
+		if tampers[i] == nil {
+			require.NoError(t, errs[i])
+		}
+	}
+	return res
+}
+
+// runDKGWithErrors works in the same way that runDKG does, returning the
+// errors of all the participants by their indices
+func runDKGWithErrors(tampers map[int]func(to int, msg *DKGMessage) bool) ([]*PrivateParamsShare, []error) {
+	net := newDKGNetwork(dkgTestN)
+	res := make([]*PrivateParamsShare, dkgTestN+1)
+	errs := make([]error, dkgTestN+1)
+	var wg sync.WaitGroup
+	for i := 1; i <= dkgTestN; i++ {
+		wg.Add(1)
+		net.join()
+	}
+	for i := 1; i <= dkgTestN; i++ {
+		go func(i int) {
+			defer wg.Done()
+			defer net.leave()
+			tr := &dkgEndpoint{net: net, index: i, tamper: tampers[i]}
+			res[i], errs[i] = RunDKG(i, dkgTestT, dkgTestN, tr)
+		}(i)
+	}
+	wg.Wait()
+	return res, errs
+}
+
+// requireDKGTags validates if the partial tags of the given participants
+// are combined into a sound tag
+func requireDKGTags(t *testing.T, pp *PublicParams, shares []*PrivateParamsShare, valid bool) {
+	data := getRandFile(fileTestBlockSize)
+	id := NewBlockID([]byte("dkg-file"), 0)
+	parts := make([]PartialTag, len(shares))
+	for i, s := range shares {
+		part, err := s.GenPartialTag(pp, id, bytes.NewReader(data))
+		require.NoError(t, err)
+		require.Equal(t, s.Index(), part.Index())
+		require.True(t, VerifyPartialTag(pp, s.Commitments(), id, bytes.NewReader(data), part))
+		restored, err := ParsePartialTag(part.Marshal())
+		require.NoError(t, err)
+		parts[i] = restored
+	}
+	tag, err := CombineTags(parts)
+	require.NoError(t, err)
+
+	chal, err := GenBlockChal(id)
+	require.NoError(t, err)
+	proof, err := Prove(pp, chal, tag, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, valid, VerifyProof(pp, chal, proof))
+	require.Equal(t, valid, VerifyBlock(pp, id, tag, bytes.NewReader(data)))
+}
+
+func TestDKG(t *testing.T) {
+	shares := runDKG(t, nil)
+
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	pp := shares[1].GeneratePublicParams(u)
+	for _, s := range shares[1:] {
+		require.Equal(t, shares[1].Commitments().Marshal(), s.Commitments().Marshal())
+		require.Equal(t, pp.Marshal(), s.GeneratePublicParams(u).Marshal())
+	}
+
+	// any t of the participants tag the blocks together, but not t - 1
+	requireDKGTags(t, pp, shares[1:1+dkgTestT], true)
+	requireDKGTags(t, pp, []*PrivateParamsShare{shares[2], shares[4], shares[5]}, true)
+	requireDKGTags(t, pp, shares[1:], true)
+	requireDKGTags(t, pp, shares[3:3+dkgTestT-1], false)
+
+	// a partial tag of another block, or by another participant
+	data := getRandFile(fileTestBlockSize)
+	id := NewBlockID([]byte("dkg-file"), 1)
+	part, err := shares[1].GenPartialTag(pp, id, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.False(t, VerifyPartialTag(pp, shares[1].Commitments(), NewBlockID([]byte("dkg-file"), 2), bytes.NewReader(data), part))
+	part.index = 2
+	require.False(t, VerifyPartialTag(pp, shares[1].Commitments(), id, bytes.NewReader(data), part))
+	_, err = CombineTags([]PartialTag{part, part})
+	require.Error(t, err)
+}
+
+func TestDKGFaulty(t *testing.T) {
+	randShare := func() string {
+		e, err := math.RandGaloisElem()
+		require.NoError(t, err)
+		return e.Marshal()
+	}
+
+	shares := runDKG(t, map[int]func(to int, msg *DKGMessage) bool{
+		// a bad share to 3, which is justified on the complaint
+		2: func(to int, msg *DKGMessage) bool {
+			if msg.kind == dkgShare && to == 3 {
+				msg.payload = randShare()
+			}
+			return true
+		},
+		// a bad share to 1, which fails the justification as well
+		4: func(to int, msg *DKGMessage) bool {
+			switch {
+			case msg.kind == dkgShare && to == 1:
+				msg.payload = randShare()
+			case msg.kind == dkgJustification:
+				msg.payload = "1" + dkgRevealSep + randShare()
+			}
+			return true
+		},
+		// silent all the time
+		5: func(to int, msg *DKGMessage) bool {
+			return false
+		},
+	})
+
+	// the honest participants agree on the result without 4 & 5
+	honest := []*PrivateParamsShare{shares[1], shares[2], shares[3]}
+	for _, s := range honest {
+		require.Equal(t, honest[0].Commitments().Marshal(), s.Commitments().Marshal())
+	}
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	requireDKGTags(t, honest[0].GeneratePublicParams(u), honest, true)
+}
+
+func TestDKGMissedJustification(t *testing.T) {
+	// 2 sends a bad share to 3 & justifies it to all but 1, which could not
+	// tell if 2 is qualified for the others
+	_, errs := runDKGWithErrors(map[int]func(to int, msg *DKGMessage) bool{
+		2: func(to int, msg *DKGMessage) bool {
+			switch {
+			case msg.kind == dkgShare && to == 3:
+				e, err := math.RandGaloisElem()
+				require.NoError(t, err)
+				msg.payload = e.Marshal()
+			case msg.kind == dkgJustification && to == 1:
+				return false
+			}
+			return true
+		},
+	})
+	require.Error(t, errs[1])
+	for _, err := range errs[3:] {
+		require.NoError(t, err)
+	}
+}
+
+func TestDKGEdgeCases(t *testing.T) {
+	net := newDKGNetwork(1)
+	for _, c := range [][3]int{{0, 1, 1}, {1, 0, 1}, {1, 2, 1}, {2, 1, 1}} {
+		_, err := RunDKG(c[0], c[1], c[2], &dkgEndpoint{net: net, index: c[0]})
+		require.Error(t, err)
+	}
+
+	// a single participant is a dealer on its own
+	s, err := RunDKG(1, 1, 1, &dkgEndpoint{net: net, index: 1})
+	require.NoError(t, err)
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	requireDKGTags(t, s.GeneratePublicParams(u), []*PrivateParamsShare{s}, true)
+
+	// not enough participants to qualify
+	net = newDKGNetwork(2)
+	_, err = RunDKG(1, 2, 2, &dkgEndpoint{net: net, index: 1})
+	require.Error(t, err)
+
+	msg := DKGMessage{from: 3, kind: dkgComplaints, payload: "1,2"}
+	restored, err := ParseDKGMessage(msg.Marshal())
+	require.NoError(t, err)
+	require.Equal(t, msg, restored)
+	require.Equal(t, 3, restored.From())
+	for _, s := range []string{"", "1;2", "0;1;", "1;0;", "1;5;", "x;1;"} {
+		_, err = ParseDKGMessage(s)
+		require.Error(t, err, s)
+	}
+
+	// a qualified dealer whose share is missing fails the DKG
+	sk, err := GenerateSignPrivKeyFromSecret(getRandSecret())
+	require.NoError(t, err)
+	shares, c, err := sk.Split(1, 2)
+	require.NoError(t, err)
+	p := &dkgParticipant{
+		index:        1,
+		t:            1,
+		n:            2,
+		commitments:  map[int]ShareCommitments{1: c, 2: c},
+		shares:       map[int]math.GaloisElem{1: shares[0].key},
+		disqualified: make(map[int]bool),
+	}
+	_, err = p.share()
+	require.Error(t, err)
+	p.disqualified[2] = true
+	_, err = p.share()
+	require.NoError(t, err)
+
+	// the errors of ParsePartialTag are of its own
+	for _, s := range []string{"", "1", "0,AA==", "1,AA==,AA==", "1,x"} {
+		_, err = ParsePartialTag(s)
+		require.Error(t, err, s)
+		require.NotContains(t, err.Error(), "PartialSignature", s)
+	}
+}
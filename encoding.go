@@ -0,0 +1,883 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/LambdaIM/proofDP/math"
+)
+
+// The JSON forms of the types, where "point", "gt" & "scalar" are the
+// Base64 encoded strings of the elements just as the Marshal of the math
+// package gives, i.e. the compressed points & the elements of GT, and the
+// fixed-length scalars of gFR. "bytes" are the Base64 encoded strings as
+// encoding/json gives for []byte.
+//
+//	PublicParams     {"v": point, "u": point, "e": gt, "us": [point]}
+//	PrivateParams    {"x": scalar}
+//	Chal             {"idx": bytes, "nu": scalar}
+//	Proof            {"miu": scalar, "sigma": point, "r": gt}
+//	SectorProof      {"mius": [scalar], "sigma": point, "r": gt}
+//	FileMeta         {"fileId": bytes, "blockSize": int, "size": int,
+//	                  "dataBlocks": int, "parityBlocks": int, "origSize": int}
+//	BlockID          {"fileId": bytes, "index": int}
+//	SignPubKey       {"key": point}
+//	SignPrivKey      {"key": scalar}
+//	SignKeyShare     {"index": int, "key": scalar}
+//	PartialSignature {"index": int, "sig": point}
+//	PartialTag       {"index": int, "tag": point}
+//	ExtendedPrivKey  {"depth": int, "index": int, "chainCode": bytes, "key": scalar}
+//	ExtendedPubKey   {"depth": int, "index": int, "chainCode": bytes, "key": point}
+//	KeyDerivationParams
+//	                 {"kdf": string, "salt": bytes, "context": string,
+//	                  "costs": [int, int, int]}
+//
+// "us" holds the sector generators u_2..u_s, which is omitted for the
+// PublicParams working on block digests only. The coding parameters of
+// FileMeta are omitted for the files not encoded by EncodeFile, & the costs
+// of KeyDerivationParams are all 0 for HKDF. The points & the elements of GT
+// are checked to be in their groups, as the untrusted parsers do.
+type (
+	publicParamsJSON struct {
+		V  string   `json:"v"`
+		U  string   `json:"u"`
+		E  string   `json:"e"`
+		Us []string `json:"us,omitempty"`
+	}
+
+	privateParamsJSON struct {
+		X string `json:"x"`
+	}
+
+	chalJSON struct {
+		Idx []byte `json:"idx"`
+		Nu  string `json:"nu"`
+	}
+
+	proofJSON struct {
+		Miu   string `json:"miu"`
+		Sigma string `json:"sigma"`
+		R     string `json:"r"`
+	}
+
+	sectorProofJSON struct {
+		Mius  []string `json:"mius"`
+		Sigma string   `json:"sigma"`
+		R     string   `json:"r"`
+	}
+
+	fileMetaJSON struct {
+		FileID       []byte `json:"fileId"`
+		BlockSize    int64  `json:"blockSize"`
+		Size         int64  `json:"size"`
+		DataBlocks   int64  `json:"dataBlocks,omitempty"`
+		ParityBlocks int64  `json:"parityBlocks,omitempty"`
+		OrigSize     int64  `json:"origSize,omitempty"`
+	}
+
+	blockIDJSON struct {
+		FileID []byte `json:"fileId"`
+		Index  int64  `json:"index"`
+	}
+
+	// signKeyJSON is shared by SignPubKey & SignPrivKey
+	signKeyJSON struct {
+		Key string `json:"key"`
+	}
+
+	signKeyShareJSON struct {
+		Index int    `json:"index"`
+		Key   string `json:"key"`
+	}
+
+	partialSignatureJSON struct {
+		Index int    `json:"index"`
+		Sig   string `json:"sig"`
+	}
+
+	partialTagJSON struct {
+		Index int    `json:"index"`
+		Tag   string `json:"tag"`
+	}
+
+	// extendedKeyJSON is shared by ExtendedPrivKey & ExtendedPubKey
+	extendedKeyJSON struct {
+		Depth     int    `json:"depth"`
+		Index     uint32 `json:"index"`
+		ChainCode []byte `json:"chainCode"`
+		Key       string `json:"key"`
+	}
+
+	keyDerivationParamsJSON struct {
+		KDF     string    `json:"kdf"`
+		Salt    []byte    `json:"salt"`
+		Context string    `json:"context"`
+		Costs   [3]uint64 `json:"costs"`
+	}
+)
+
+// MarshalText implements encoding.TextMarshaler, the result is Marshal().
+// Like the other encoders, it takes the value receiver, so that the values
+// embedded in the other structs are encoded as well.
+func (pp PublicParams) MarshalText() ([]byte, error) {
+	return []byte(pp.Marshal()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see ParsePublicParams
+func (pp *PublicParams) UnmarshalText(text []byte) error {
+	res, err := ParsePublicParams(string(text))
+	if err != nil {
+		return err
+	}
+	*pp = *res
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (pp PublicParams) MarshalJSON() ([]byte, error) {
+	res := publicParamsJSON{
+		V: pp.v.Marshal(),
+		U: pp.u.Marshal(),
+		E: pp.e.Marshal(),
+	}
+	for i := 1; i < len(pp.us); i++ {
+		res.Us = append(res.Us, pp.us[i].Marshal())
+	}
+	return json.Marshal(res)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (pp *PublicParams) UnmarshalJSON(data []byte) error {
+	var raw publicParamsJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf(errParsePublicParamsFmt, err.Error())
+	}
+
+	res, err := parsePublicParamsFields(raw.V, raw.U, raw.E, raw.Us, untrustedParsers)
+	if err != nil {
+		return err
+	}
+	*pp = *res
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, the result is MarshalBinary()
+func (pp PublicParams) GobEncode() ([]byte, error) {
+	return pp.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder
+func (pp *PublicParams) GobDecode(data []byte) error {
+	return pp.UnmarshalBinary(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, the result is Marshal()
+func (sp PrivateParams) MarshalText() ([]byte, error) {
+	return []byte(sp.Marshal()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (sp *PrivateParams) UnmarshalText(text []byte) error {
+	x, err := math.ParseGaloisElem(string(text))
+	if err != nil {
+		return fmt.Errorf(errParsePrivateParamFmt, err.Error())
+	}
+	sp.x = x
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (sp PrivateParams) MarshalJSON() ([]byte, error) {
+	return json.Marshal(privateParamsJSON{X: sp.x.Marshal()})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (sp *PrivateParams) UnmarshalJSON(data []byte) error {
+	var res privateParamsJSON
+	if err := json.Unmarshal(data, &res); err != nil {
+		return fmt.Errorf(errParsePrivateParamFmt, err.Error())
+	}
+	return sp.UnmarshalText([]byte(res.X))
+}
+
+// GobEncode implements gob.GobEncoder, the result is MarshalBinary()
+func (sp PrivateParams) GobEncode() ([]byte, error) {
+	return sp.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder
+func (sp *PrivateParams) GobDecode(data []byte) error {
+	return sp.UnmarshalBinary(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, the result is Marshal()
+func (c Chal) MarshalText() ([]byte, error) {
+	return []byte(c.Marshal()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see ParseChal
+func (c *Chal) UnmarshalText(text []byte) error {
+	res, err := ParseChal(string(text))
+	if err != nil {
+		return err
+	}
+	*c = res
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (c Chal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(chalJSON{
+		Idx: c.idx,
+		Nu:  c.nu.Marshal(),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (c *Chal) UnmarshalJSON(data []byte) error {
+	var raw chalJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf(errParseChalFmt, err.Error())
+	}
+
+	res, err := parseChalFields(raw.Idx, raw.Nu)
+	if err != nil {
+		return err
+	}
+	*c = res
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, the result is MarshalBinary()
+func (c Chal) GobEncode() ([]byte, error) {
+	return c.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder
+func (c *Chal) GobDecode(data []byte) error {
+	return c.UnmarshalBinary(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, the result is Marshal()
+func (p Proof) MarshalText() ([]byte, error) {
+	return []byte(p.Marshal()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see ParseProof
+func (p *Proof) UnmarshalText(text []byte) error {
+	res, err := ParseProof(string(text))
+	if err != nil {
+		return err
+	}
+	*p = res
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (p Proof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(proofJSON{
+		Miu:   p.miu.Marshal(),
+		Sigma: p.sigma.Marshal(),
+		R:     p.r.Marshal(),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (p *Proof) UnmarshalJSON(data []byte) error {
+	var raw proofJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf(errParseProofFmt, err.Error())
+	}
+
+	res, err := parseProofFields(raw.Miu, raw.Sigma, raw.R, untrustedParsers)
+	if err != nil {
+		return err
+	}
+	*p = res
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, the result is MarshalBinary()
+func (p Proof) GobEncode() ([]byte, error) {
+	return p.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder
+func (p *Proof) GobDecode(data []byte) error {
+	return p.UnmarshalBinary(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, the result is Marshal()
+func (p SectorProof) MarshalText() ([]byte, error) {
+	return []byte(p.Marshal()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see ParseSectorProof
+func (p *SectorProof) UnmarshalText(text []byte) error {
+	res, err := ParseSectorProof(string(text))
+	if err != nil {
+		return err
+	}
+	*p = res
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (p SectorProof) MarshalJSON() ([]byte, error) {
+	res := sectorProofJSON{
+		Mius:  make([]string, len(p.mius)),
+		Sigma: p.sigma.Marshal(),
+		R:     p.r.Marshal(),
+	}
+	for i := range p.mius {
+		res.Mius[i] = p.mius[i].Marshal()
+	}
+	return json.Marshal(res)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (p *SectorProof) UnmarshalJSON(data []byte) error {
+	var raw sectorProofJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf(errParseSectorProofFmt, err.Error())
+	}
+
+	res, err := parseSectorProofFields(raw.Sigma, raw.R, raw.Mius, untrustedParsers)
+	if err != nil {
+		return err
+	}
+	*p = res
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, the result is MarshalBinary()
+func (p SectorProof) GobEncode() ([]byte, error) {
+	return p.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder
+func (p *SectorProof) GobDecode(data []byte) error {
+	return p.UnmarshalBinary(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, the result is Marshal()
+func (m FileMeta) MarshalText() ([]byte, error) {
+	return []byte(m.Marshal()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see ParseFileMeta
+func (m *FileMeta) UnmarshalText(text []byte) error {
+	res, err := ParseFileMeta(string(text))
+	if err != nil {
+		return err
+	}
+	*m = *res
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (m FileMeta) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fileMetaJSON{
+		FileID:       m.fileID,
+		BlockSize:    m.blockSize,
+		Size:         m.size,
+		DataBlocks:   m.dataBlocks,
+		ParityBlocks: m.parityBlocks,
+		OrigSize:     m.origSize,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (m *FileMeta) UnmarshalJSON(data []byte) error {
+	var raw fileMetaJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf(errParseFileMetaFmt, err.Error())
+	}
+
+	res := FileMeta{
+		fileID:       raw.FileID,
+		blockSize:    raw.BlockSize,
+		size:         raw.Size,
+		dataBlocks:   raw.DataBlocks,
+		parityBlocks: raw.ParityBlocks,
+		origSize:     raw.OrigSize,
+	}
+	if err := res.validate(); err != nil {
+		return fmt.Errorf(errParseFileMetaFmt, err.Error())
+	}
+	*m = res
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, the result is MarshalBinary()
+func (m FileMeta) GobEncode() ([]byte, error) {
+	return m.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder
+func (m *FileMeta) GobDecode(data []byte) error {
+	return m.UnmarshalBinary(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, the result is the base64
+// encoded Bytes()
+func (id BlockID) MarshalText() ([]byte, error) {
+	return []byte(base64.StdEncoding.EncodeToString(id.Bytes())), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see ParseBlockID
+func (id *BlockID) UnmarshalText(text []byte) error {
+	b, err := base64.StdEncoding.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf(errParseBlockIDFmt, err.Error())
+	}
+	res, err := ParseBlockID(b)
+	if err != nil {
+		return err
+	}
+	*id = res
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (id BlockID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(blockIDJSON{
+		FileID: id.fileID,
+		Index:  id.index,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (id *BlockID) UnmarshalJSON(data []byte) error {
+	var raw blockIDJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf(errParseBlockIDFmt, err.Error())
+	}
+	*id = NewBlockID(raw.FileID, raw.Index)
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, the result is MarshalBinary()
+func (id BlockID) GobEncode() ([]byte, error) {
+	return id.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder
+func (id *BlockID) GobDecode(data []byte) error {
+	return id.UnmarshalBinary(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, the result is the base64
+// encoded key
+func (pk SignPubKey) MarshalText() ([]byte, error) {
+	return []byte(pk.key.Marshal()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see ParseSignPubKey
+func (pk *SignPubKey) UnmarshalText(text []byte) error {
+	res, err := ParseSignPubKey(string(text))
+	if err != nil {
+		return err
+	}
+	*pk = res
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (pk SignPubKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(signKeyJSON{Key: pk.key.Marshal()})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (pk *SignPubKey) UnmarshalJSON(data []byte) error {
+	var res signKeyJSON
+	if err := json.Unmarshal(data, &res); err != nil {
+		return fmt.Errorf(errParseSignPubKeyFmt, err.Error())
+	}
+	return pk.UnmarshalText([]byte(res.Key))
+}
+
+// GobEncode implements gob.GobEncoder, the result is MarshalBinary()
+func (pk SignPubKey) GobEncode() ([]byte, error) {
+	return pk.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder
+func (pk *SignPubKey) GobDecode(data []byte) error {
+	return pk.UnmarshalBinary(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, the result is the base64
+// encoded private key only, as the public key is derived from it
+func (sk SignPrivKey) MarshalText() ([]byte, error) {
+	return []byte(sk.key.Marshal()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (sk *SignPrivKey) UnmarshalText(text []byte) error {
+	key, err := math.ParseGaloisElem(string(text))
+	if err != nil {
+		return fmt.Errorf(errParseSignPrivKeyFmt, err.Error())
+	}
+	*sk = *newSignPrivKey(key)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (sk SignPrivKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(signKeyJSON{Key: sk.key.Marshal()})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (sk *SignPrivKey) UnmarshalJSON(data []byte) error {
+	var res signKeyJSON
+	if err := json.Unmarshal(data, &res); err != nil {
+		return fmt.Errorf(errParseSignPrivKeyFmt, err.Error())
+	}
+	return sk.UnmarshalText([]byte(res.Key))
+}
+
+// GobEncode implements gob.GobEncoder, the result is MarshalBinary()
+func (sk SignPrivKey) GobEncode() ([]byte, error) {
+	return sk.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder
+func (sk *SignPrivKey) GobDecode(data []byte) error {
+	return sk.UnmarshalBinary(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, the result is Marshal()
+func (s SignKeyShare) MarshalText() ([]byte, error) {
+	return []byte(s.Marshal()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see ParseSignKeyShare
+func (s *SignKeyShare) UnmarshalText(text []byte) error {
+	res, err := ParseSignKeyShare(string(text))
+	if err != nil {
+		return err
+	}
+	*s = res
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (s SignKeyShare) MarshalJSON() ([]byte, error) {
+	return json.Marshal(signKeyShareJSON{
+		Index: s.index,
+		Key:   s.key.Marshal(),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *SignKeyShare) UnmarshalJSON(data []byte) error {
+	var raw signKeyShareJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf(errParseSignKeyShareFmt, err.Error())
+	}
+
+	res, err := parseSignKeyShareFields(raw.Index, raw.Key)
+	if err != nil {
+		return err
+	}
+	*s = res
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, the result is MarshalText() as there
+// is no binary encoding of SignKeyShare
+func (s SignKeyShare) GobEncode() ([]byte, error) {
+	return s.MarshalText()
+}
+
+// GobDecode implements gob.GobDecoder
+func (s *SignKeyShare) GobDecode(data []byte) error {
+	return s.UnmarshalText(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, the result is Marshal()
+func (p PartialSignature) MarshalText() ([]byte, error) {
+	return []byte(p.Marshal()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see
+// ParsePartialSignature
+func (p *PartialSignature) UnmarshalText(text []byte) error {
+	res, err := ParsePartialSignature(string(text))
+	if err != nil {
+		return err
+	}
+	*p = res
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (p PartialSignature) MarshalJSON() ([]byte, error) {
+	return json.Marshal(partialSignatureJSON{
+		Index: p.index,
+		Sig:   p.sig.Marshal(),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (p *PartialSignature) UnmarshalJSON(data []byte) error {
+	var raw partialSignatureJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf(errParsePartialSigFmt, err.Error())
+	}
+
+	res, err := parsePartialSignatureFields(raw.Index, raw.Sig)
+	if err != nil {
+		return err
+	}
+	*p = res
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, the result is MarshalText()
+func (p PartialSignature) GobEncode() ([]byte, error) {
+	return p.MarshalText()
+}
+
+// GobDecode implements gob.GobDecoder
+func (p *PartialSignature) GobDecode(data []byte) error {
+	return p.UnmarshalText(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, the result is Marshal()
+func (t PartialTag) MarshalText() ([]byte, error) {
+	return []byte(t.Marshal()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see ParsePartialTag
+func (t *PartialTag) UnmarshalText(text []byte) error {
+	res, err := ParsePartialTag(string(text))
+	if err != nil {
+		return err
+	}
+	*t = res
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (t PartialTag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(partialTagJSON{
+		Index: t.index,
+		Tag:   t.tag.Marshal(),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *PartialTag) UnmarshalJSON(data []byte) error {
+	var raw partialTagJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf(errParsePartialTagFmt, err.Error())
+	}
+
+	res, err := parsePartialTagFields(raw.Index, raw.Tag)
+	if err != nil {
+		return err
+	}
+	*t = res
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, the result is MarshalText()
+func (t PartialTag) GobEncode() ([]byte, error) {
+	return t.MarshalText()
+}
+
+// GobDecode implements gob.GobDecoder
+func (t *PartialTag) GobDecode(data []byte) error {
+	return t.UnmarshalText(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, the result is Marshal()
+func (k ExtendedPrivKey) MarshalText() ([]byte, error) {
+	return []byte(k.Marshal()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see
+// ParseExtendedPrivKey
+func (k *ExtendedPrivKey) UnmarshalText(text []byte) error {
+	res, err := ParseExtendedPrivKey(string(text))
+	if err != nil {
+		return err
+	}
+	*k = *res
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (k ExtendedPrivKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(extendedKeyJSON{
+		Depth:     k.depth,
+		Index:     k.index,
+		ChainCode: k.chainCode[:],
+		Key:       k.key.Marshal(),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (k *ExtendedPrivKey) UnmarshalJSON(data []byte) error {
+	var raw extendedKeyJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf(errParseExtendedPrivKeyFmt, err.Error())
+	}
+
+	ek, err := newExtendedKey(raw.Depth, raw.Index, raw.ChainCode)
+	if err != nil {
+		return fmt.Errorf(errParseExtendedPrivKeyFmt, err.Error())
+	}
+	res, err := parseExtendedPrivKeyFields(ek, raw.Key)
+	if err != nil {
+		return err
+	}
+	*k = *res
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, the result is MarshalText()
+func (k ExtendedPrivKey) GobEncode() ([]byte, error) {
+	return k.MarshalText()
+}
+
+// GobDecode implements gob.GobDecoder
+func (k *ExtendedPrivKey) GobDecode(data []byte) error {
+	return k.UnmarshalText(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, the result is Marshal()
+func (pk ExtendedPubKey) MarshalText() ([]byte, error) {
+	return []byte(pk.Marshal()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see ParseExtendedPubKey
+func (pk *ExtendedPubKey) UnmarshalText(text []byte) error {
+	res, err := ParseExtendedPubKey(string(text))
+	if err != nil {
+		return err
+	}
+	*pk = *res
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (pk ExtendedPubKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(extendedKeyJSON{
+		Depth:     pk.depth,
+		Index:     pk.index,
+		ChainCode: pk.chainCode[:],
+		Key:       pk.key.Marshal(),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (pk *ExtendedPubKey) UnmarshalJSON(data []byte) error {
+	var raw extendedKeyJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf(errParseExtendedPubKeyFmt, err.Error())
+	}
+
+	ek, err := newExtendedKey(raw.Depth, raw.Index, raw.ChainCode)
+	if err != nil {
+		return fmt.Errorf(errParseExtendedPubKeyFmt, err.Error())
+	}
+	res, err := parseExtendedPubKeyFields(ek, raw.Key)
+	if err != nil {
+		return err
+	}
+	*pk = *res
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, the result is MarshalText()
+func (pk ExtendedPubKey) GobEncode() ([]byte, error) {
+	return pk.MarshalText()
+}
+
+// GobDecode implements gob.GobDecoder
+func (pk *ExtendedPubKey) GobDecode(data []byte) error {
+	return pk.UnmarshalText(data)
+}
+
+// MarshalText implements encoding.TextMarshaler, the result is Marshal()
+func (kp KeyDerivationParams) MarshalText() ([]byte, error) {
+	return []byte(kp.Marshal()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see
+// ParseKeyDerivationParams
+func (kp *KeyDerivationParams) UnmarshalText(text []byte) error {
+	res, err := ParseKeyDerivationParams(string(text))
+	if err != nil {
+		return err
+	}
+	*kp = *res
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, where the costs are all 0 for HKDF
+func (kp KeyDerivationParams) MarshalJSON() ([]byte, error) {
+	return json.Marshal(keyDerivationParamsJSON{
+		KDF:     kp.kdf.String(),
+		Salt:    kp.salt,
+		Context: kp.context,
+		Costs:   kp.costs(),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (kp *KeyDerivationParams) UnmarshalJSON(data []byte) error {
+	var res keyDerivationParamsJSON
+	if err := json.Unmarshal(data, &res); err != nil {
+		return fmt.Errorf(errParseKeyDerivationParamsFmt, err.Error())
+	}
+
+	kdf, err := parseKDF(res.KDF)
+	if err != nil {
+		return fmt.Errorf(errParseKeyDerivationParamsFmt, err.Error())
+	}
+	params := KeyDerivationParams{
+		kdf:     kdf,
+		salt:    res.Salt,
+		context: res.Context,
+	}
+	if err := params.setCosts(res.Costs); err != nil {
+		return fmt.Errorf(errParseKeyDerivationParamsFmt, err.Error())
+	}
+	if err := params.validate(); err != nil {
+		return fmt.Errorf(errParseKeyDerivationParamsFmt, err.Error())
+	}
+	*kp = params
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, the result is MarshalBinary()
+func (kp KeyDerivationParams) GobEncode() ([]byte, error) {
+	return kp.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder
+func (kp *KeyDerivationParams) GobDecode(data []byte) error {
+	return kp.UnmarshalBinary(data)
+}
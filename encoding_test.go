@@ -0,0 +1,314 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// auditRecord is a sample record that embeds the public types by value
+type auditRecord struct {
+	PP    PublicParams
+	SP    *PrivateParams
+	Chal  Chal
+	Chals ChalSet
+	Proof Proof
+	Pk    SignPubKey
+	Sk    SignPrivKey
+}
+
+func getAuditRecord(t *testing.T) auditRecord {
+	sp, pp := getSectorParams(t)
+
+	data := getRandFile(fileTestBlockSize)
+	id := NewBlockID([]byte("record-file"), 0)
+	tag, err := GenBlockTag(sp, pp, id, bytes.NewReader(data))
+	require.NoError(t, err)
+	chal, err := GenBlockChal(id)
+	require.NoError(t, err)
+	proof, err := Prove(pp, chal, tag, bytes.NewReader(data))
+	require.NoError(t, err)
+	sk, err := GenerateSignPrivKeyFromSecret(getRandSecret())
+	require.NoError(t, err)
+
+	return auditRecord{
+		PP:    *pp,
+		SP:    sp,
+		Chal:  chal,
+		Chals: ChalSet{chal, chal},
+		Proof: proof,
+		Pk:    sk.Pk,
+		Sk:    *sk,
+	}
+}
+
+// requireRecordEqual validates if 'res' is restored from 'r'
+func requireRecordEqual(t *testing.T, r, res auditRecord) {
+	require.Equal(t, r.PP.Marshal(), res.PP.Marshal())
+	require.Equal(t, r.PP.Sectors(), res.PP.Sectors())
+	require.Equal(t, r.SP.Marshal(), res.SP.Marshal())
+	require.True(t, r.Chal.Equal(res.Chal))
+	require.True(t, r.Chals.Equal(res.Chals))
+	require.Equal(t, r.Proof.Marshal(), res.Proof.Marshal())
+	require.True(t, VerifyProof(&res.PP, res.Chal, res.Proof))
+
+	hash := [32]byte{2}
+	require.True(t, VerifySignature(res.Sk.Sign(hash), hash, res.Pk))
+	require.True(t, VerifySignature(r.Sk.Sign(hash), hash, res.Sk.Pk))
+}
+
+func TestJSONEncoding(t *testing.T) {
+	r := getAuditRecord(t)
+
+	data, err := json.Marshal(r)
+	require.NoError(t, err)
+	var res auditRecord
+	require.NoError(t, json.Unmarshal(data, &res))
+	requireRecordEqual(t, r, res)
+
+	// the documented schema
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &fields))
+	schema := make(map[string]map[string]interface{})
+	for name, keys := range map[string][]string{
+		"PP":    {"v", "u", "e", "us"},
+		"SP":    {"x"},
+		"Chal":  {"idx", "nu"},
+		"Proof": {"miu", "sigma", "r"},
+		"Pk":    {"key"},
+		"Sk":    {"key"},
+	} {
+		var obj map[string]interface{}
+		require.NoError(t, json.Unmarshal(fields[name], &obj), name)
+		schema[name] = obj
+		require.Len(t, obj, len(keys), name)
+		for _, key := range keys {
+			require.Contains(t, obj, key, name)
+		}
+	}
+	require.Equal(t, r.Proof.sigma.Marshal(), schema["Proof"]["sigma"])
+	require.Len(t, schema["PP"]["us"], sectorTestNum-1)
+
+	bare := PublicParams{v: r.PP.v, u: r.PP.u, e: r.PP.e}
+	data, err = json.Marshal(bare)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), `"us"`)
+
+	// the elements are checked just as the untrusted parsers do
+	var proof Proof
+	for _, s := range []string{
+		`{"miu": "", "sigma": "", "r": ""}`,
+		`{"sigma": "` + r.Proof.sigma.Marshal() + `", "r": "` + r.Proof.r.Marshal() + `"}`,
+		`{"miu": "` + r.Proof.miu.Marshal() + `", "sigma": "AAAA", "r": "` + r.Proof.r.Marshal() + `"}`,
+		`["miu"]`,
+	} {
+		require.Error(t, json.Unmarshal([]byte(s), &proof), s)
+	}
+	var pp PublicParams
+	require.Error(t, json.Unmarshal([]byte(`{"v": "", "u": "", "e": ""}`), &pp))
+	var pk SignPubKey
+	require.Error(t, json.Unmarshal([]byte(`{"key": "AAAA"}`), &pk))
+}
+
+func TestTextEncoding(t *testing.T) {
+	r := getAuditRecord(t)
+
+	// the text encodings are the strings of Marshal
+	text, err := r.Proof.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, r.Proof.Marshal(), string(text))
+
+	res := auditRecord{SP: &PrivateParams{}}
+	for _, c := range []struct {
+		m interface{ MarshalText() ([]byte, error) }
+		u interface{ UnmarshalText([]byte) error }
+	}{
+		{r.PP, &res.PP},
+		{r.SP, res.SP},
+		{r.Chal, &res.Chal},
+		{r.Proof, &res.Proof},
+		{r.Pk, &res.Pk},
+		{r.Sk, &res.Sk},
+	} {
+		text, err := c.m.MarshalText()
+		require.NoError(t, err)
+		require.NoError(t, c.u.UnmarshalText(text))
+		require.Error(t, c.u.UnmarshalText([]byte("AAAA,")))
+	}
+	res.Chals = r.Chals
+	requireRecordEqual(t, r, res)
+}
+
+func TestGobEncoding(t *testing.T) {
+	r := getAuditRecord(t)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, gob.NewEncoder(buf).Encode(r))
+	var res auditRecord
+	require.NoError(t, gob.NewDecoder(buf).Decode(&res))
+	requireRecordEqual(t, r, res)
+
+	// a corrupted value fails the checksum
+	encoded, err := r.Proof.GobEncode()
+	require.NoError(t, err)
+	buf.Reset()
+	require.NoError(t, gob.NewEncoder(buf).Encode(r.Proof))
+	idx := bytes.Index(buf.Bytes(), encoded)
+	require.True(t, idx > 0)
+	buf.Bytes()[idx+len(encoded)/2] ^= 1
+	var proof Proof
+	require.Error(t, gob.NewDecoder(buf).Decode(&proof))
+}
+
+// fileRecord is a sample record of the file, threshold & HD key types
+type fileRecord struct {
+	Meta    *FileMeta
+	ID      BlockID
+	Proof   SectorProof
+	Share   SignKeyShare
+	PartSig PartialSignature
+	PartTag PartialTag
+	Priv    *ExtendedPrivKey
+	Pub     *ExtendedPubKey
+}
+
+func getFileRecord(t *testing.T) fileRecord {
+	sp, pp := getSectorParams(t)
+
+	data := getRandFile(2*pp.SectorBlockSize() + 7)
+	tags, meta, err := TagFileSectors(sp, pp, []byte("record-file"), bytes.NewReader(data))
+	require.NoError(t, err)
+	cs, err := GenFileChalSet(meta, []int64{0, 2})
+	require.NoError(t, err)
+	proof, err := ProveFileSectors(pp, meta, cs, tags, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	sk, err := GenerateSignPrivKeyFromSecret(getRandSecret())
+	require.NoError(t, err)
+	shares, _, err := sk.Split(2, 3)
+	require.NoError(t, err)
+
+	master, err := NewMasterKey(getRandSecret())
+	require.NoError(t, err)
+	priv, err := master.Derive("m/0'/1")
+	require.NoError(t, err)
+
+	return fileRecord{
+		Meta:    meta,
+		ID:      NewBlockID([]byte("record-file"), 2),
+		Proof:   proof,
+		Share:   shares[1],
+		PartSig: shares[1].Sign([32]byte{3}),
+		PartTag: PartialTag{index: 2, tag: tags[0]},
+		Priv:    priv,
+		Pub:     priv.Public(),
+	}
+}
+
+// requireFileRecordEqual validates if 'res' is restored from 'r'
+func requireFileRecordEqual(t *testing.T, r, res fileRecord) {
+	require.Equal(t, r.Meta, res.Meta)
+	require.True(t, r.ID.Equal(res.ID))
+	require.Equal(t, r.Proof.Marshal(), res.Proof.Marshal())
+	require.Equal(t, r.Share.Marshal(), res.Share.Marshal())
+	require.Equal(t, r.PartSig.Marshal(), res.PartSig.Marshal())
+	require.Equal(t, r.PartTag.Marshal(), res.PartTag.Marshal())
+	require.Equal(t, r.Priv.Marshal(), res.Priv.Marshal())
+	require.Equal(t, r.Pub.Marshal(), res.Pub.Marshal())
+}
+
+func TestFileRecordEncoding(t *testing.T) {
+	r := getFileRecord(t)
+
+	data, err := json.Marshal(r)
+	require.NoError(t, err)
+	var res fileRecord
+	require.NoError(t, json.Unmarshal(data, &res))
+	requireFileRecordEqual(t, r, res)
+
+	// the documented schema
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &fields))
+	for name, keys := range map[string][]string{
+		"Meta":    {"fileId", "blockSize", "size"},
+		"ID":      {"fileId", "index"},
+		"Proof":   {"mius", "sigma", "r"},
+		"Share":   {"index", "key"},
+		"PartSig": {"index", "sig"},
+		"PartTag": {"index", "tag"},
+		"Priv":    {"depth", "index", "chainCode", "key"},
+		"Pub":     {"depth", "index", "chainCode", "key"},
+	} {
+		var obj map[string]interface{}
+		require.NoError(t, json.Unmarshal(fields[name], &obj), name)
+		require.Len(t, obj, len(keys), name)
+		for _, key := range keys {
+			require.Contains(t, obj, key, name)
+		}
+	}
+
+	text := fileRecord{Meta: &FileMeta{}, Priv: &ExtendedPrivKey{}, Pub: &ExtendedPubKey{}}
+	for _, c := range []struct {
+		m interface{ MarshalText() ([]byte, error) }
+		u interface{ UnmarshalText([]byte) error }
+	}{
+		{r.Meta, text.Meta},
+		{r.ID, &text.ID},
+		{r.Proof, &text.Proof},
+		{r.Share, &text.Share},
+		{r.PartSig, &text.PartSig},
+		{r.PartTag, &text.PartTag},
+		{r.Priv, text.Priv},
+		{r.Pub, text.Pub},
+	} {
+		data, err := c.m.MarshalText()
+		require.NoError(t, err)
+		require.NoError(t, c.u.UnmarshalText(data))
+		require.Error(t, c.u.UnmarshalText([]byte("AAAA,")))
+	}
+	requireFileRecordEqual(t, r, text)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, gob.NewEncoder(buf).Encode(r))
+	var decoded fileRecord
+	require.NoError(t, gob.NewDecoder(buf).Decode(&decoded))
+	requireFileRecordEqual(t, r, decoded)
+
+	// the fields are checked just as the parsers do
+	for _, c := range []struct {
+		s string
+		u interface{}
+	}{
+		{`{"fileId": "", "blockSize": 0, "size": 1}`, &FileMeta{}},
+		{`{"fileId": "", "blockSize": 1, "size": 1, "parityBlocks": 1}`, &FileMeta{}},
+		{`{"mius": [], "sigma": "` + r.Proof.sigma.Marshal() + `", "r": "` + r.Proof.r.Marshal() + `"}`, &SectorProof{}},
+		{`{"index": 0, "key": "` + r.Share.key.Marshal() + `"}`, &SignKeyShare{}},
+		{`{"index": 1, "sig": "AAAA"}`, &PartialSignature{}},
+		{`{"index": 1, "tag": "AAAA"}`, &PartialTag{}},
+		{`{"depth": 256, "index": 0, "chainCode": "", "key": ""}`, &ExtendedPrivKey{}},
+		{`{"depth": 0, "index": 1, "chainCode": "", "key": ""}`, &ExtendedPubKey{}},
+		{`{"idx": "", "nu": "AAAA"}`, &Chal{}},
+	} {
+		require.Error(t, json.Unmarshal([]byte(c.s), c.u), c.s)
+	}
+}
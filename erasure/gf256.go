@@ -0,0 +1,97 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package erasure
+
+// implement the arithmetic of GF(2^8) with the primitive polynomial
+// x^8 + x^4 + x^3 + x^2 + 1, using the log/exp tables of generator 2
+const (
+	fieldSize = 256
+	primPoly  = 0x11d
+)
+
+// psuedo-constant
+var (
+	expTable [2 * fieldSize]byte
+	logTable [fieldSize]byte
+)
+
+// package level init(): build the log/exp tables
+func init() {
+	x := 1
+	for i := 0; i < fieldSize-1; i++ {
+		expTable[i] = byte(x)
+		logTable[x] = byte(i)
+		x <<= 1
+		if x&fieldSize != 0 {
+			x ^= primPoly
+		}
+	}
+	// duplicate the table to skip the mod operation in gfMul
+	for i := fieldSize - 1; i < len(expTable); i++ {
+		expTable[i] = expTable[i-(fieldSize-1)]
+	}
+}
+
+func gfAdd(a, b byte) byte {
+	return a ^ b
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+int(logTable[b])]
+}
+
+// gfDiv panics on a zero divisor
+func gfDiv(a, b byte) byte {
+	if b == 0 {
+		panic(errDivByZero)
+	}
+	if a == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+(fieldSize-1)-int(logTable[b])]
+}
+
+func gfInv(a byte) byte {
+	return gfDiv(1, a)
+}
+
+func gfPow(a byte, n int) byte {
+	if n == 0 {
+		return 1
+	}
+	if a == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])*n)%(fieldSize-1)]
+}
+
+// gfMulSliceAdd sets dst[i] ^= c * src[i]
+func gfMulSliceAdd(c byte, src, dst []byte) {
+	if c == 0 {
+		return
+	}
+	logC := int(logTable[c])
+	for i, s := range src {
+		if s != 0 {
+			dst[i] ^= expTable[logC+int(logTable[s])]
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package erasure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGF256Arith(t *testing.T) {
+	for a := 0; a < fieldSize; a++ {
+		for b := 1; b < fieldSize; b++ {
+			prd := gfMul(byte(a), byte(b))
+			assert.Equal(t, byte(a), gfDiv(prd, byte(b)))
+			assert.Equal(t, prd, gfMul(byte(b), byte(a)))
+		}
+		if a != 0 {
+			assert.Equal(t, byte(1), gfMul(byte(a), gfInv(byte(a))))
+		}
+	}
+
+	// the generator walks through all the non-zero elements
+	seen := map[byte]bool{}
+	for i := 0; i < fieldSize-1; i++ {
+		seen[gfPow(2, i)] = true
+	}
+	assert.Len(t, seen, fieldSize-1)
+	assert.Panics(t, func() { gfDiv(1, 0) })
+}
+
+func TestMatrixInvert(t *testing.T) {
+	v := vandermonde(7, 7)
+	inv, err := v.invert()
+	assert.NoError(t, err)
+
+	id := v.mul(inv)
+	for r := range id {
+		for c := range id[r] {
+			if r == c {
+				assert.Equal(t, byte(1), id[r][c])
+			} else {
+				assert.Equal(t, byte(0), id[r][c])
+			}
+		}
+	}
+
+	singular := newMatrix(2, 2)
+	singular[0][0], singular[0][1] = 1, 2
+	singular[1][0], singular[1][1] = 1, 2
+	_, err = singular.invert()
+	assert.Error(t, err)
+}
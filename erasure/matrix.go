@@ -0,0 +1,113 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package erasure
+
+import (
+	"errors"
+)
+
+// matrix is a row-major matrix over GF(2^8)
+type matrix [][]byte
+
+func newMatrix(rows, cols int) matrix {
+	m := make(matrix, rows)
+	for i := range m {
+		m[i] = make([]byte, cols)
+	}
+	return m
+}
+
+// vandermonde returns the matrix where m[r][c] = r^c
+func vandermonde(rows, cols int) matrix {
+	m := newMatrix(rows, cols)
+	for r := range m {
+		for c := range m[r] {
+			m[r][c] = gfPow(byte(r), c)
+		}
+	}
+	return m
+}
+
+func (m matrix) subMatrix(rowStart, rowEnd int) matrix {
+	res := newMatrix(rowEnd-rowStart, len(m[0]))
+	for r := range res {
+		copy(res[r], m[rowStart+r])
+	}
+	return res
+}
+
+func (m matrix) mul(a matrix) matrix {
+	res := newMatrix(len(m), len(a[0]))
+	for r := range res {
+		for c := range res[r] {
+			var v byte
+			for k := range a {
+				v = gfAdd(v, gfMul(m[r][k], a[k][c]))
+			}
+			res[r][c] = v
+		}
+	}
+	return res
+}
+
+// invert returns the inverse of a square matrix by Gauss-Jordan elimination
+func (m matrix) invert() (matrix, error) {
+	size := len(m)
+	// work on [m | I]
+	work := newMatrix(size, 2*size)
+	for r := range m {
+		copy(work[r], m[r])
+		work[r][size+r] = 1
+	}
+
+	for r := 0; r < size; r++ {
+		// find a non-zero pivot
+		if work[r][r] == 0 {
+			for below := r + 1; below < size; below++ {
+				if work[below][r] != 0 {
+					work[r], work[below] = work[below], work[r]
+					break
+				}
+			}
+		}
+		if work[r][r] == 0 {
+			return nil, errors.New(errSingularMatrix)
+		}
+
+		// scale the pivot row to 1
+		if scale := work[r][r]; scale != 1 {
+			inv := gfInv(scale)
+			for c := range work[r] {
+				work[r][c] = gfMul(work[r][c], inv)
+			}
+		}
+
+		// eliminate the column in other rows
+		for other := 0; other < size; other++ {
+			if other != r && work[other][r] != 0 {
+				gfMulSliceAdd(work[other][r], work[r], work[other])
+			}
+		}
+	}
+
+	res := newMatrix(size, size)
+	for r := range res {
+		copy(res[r], work[r][size:])
+	}
+	return res, nil
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package erasure
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	errDivByZero          = "Division by zero in GF(2^8)"
+	errInvalidShardNumFmt = "Invalid shard num: %d data + %d parity"
+	errUnmatchedShards    = "Unmatched shard num"
+	errUnmatchedShardSize = "Shards of different sizes"
+	errTooFewShards       = "Too few shards to reconstruct"
+	errSingularMatrix     = "Singular matrix"
+)
+
+// ReedSolomon is a systematic Reed-Solomon coder over GF(2^8). The first
+// dataShards shards are kept as is, followed by parityShards parity shards.
+// Any dataShards of the dataShards+parityShards shards are enough to
+// restore all the others.
+type ReedSolomon struct {
+	dataShards   int
+	parityShards int
+	// the (dataShards + parityShards) x dataShards encoding matrix,
+	// whose top square part is an identity matrix
+	matrix matrix
+}
+
+// New creates a ReedSolomon instance with the given shard num. The sum of
+// 'dataShards' & 'parityShards' is limited to 256.
+func New(dataShards, parityShards int) (*ReedSolomon, error) {
+	if dataShards <= 0 || parityShards < 0 || dataShards+parityShards > fieldSize {
+		return nil, fmt.Errorf(errInvalidShardNumFmt, dataShards, parityShards)
+	}
+
+	// any dataShards rows of a Vandermonde matrix are linear independent,
+	// which still holds after multiplied by the inverse of its top part
+	v := vandermonde(dataShards+parityShards, dataShards)
+	top, err := v.subMatrix(0, dataShards).invert()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReedSolomon{
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		matrix:       v.mul(top),
+	}, nil
+}
+
+// DataShards returns the num of the data shards
+func (rs *ReedSolomon) DataShards() int {
+	return rs.dataShards
+}
+
+// ParityShards returns the num of the parity shards
+func (rs *ReedSolomon) ParityShards() int {
+	return rs.parityShards
+}
+
+// TotalShards returns the num of all the shards
+func (rs *ReedSolomon) TotalShards() int {
+	return rs.dataShards + rs.parityShards
+}
+
+// shardSize checks the given shards & returns their size, the nil ones
+// are skipped
+func (rs *ReedSolomon) shardSize(shards [][]byte) (int, error) {
+	if len(shards) != rs.TotalShards() {
+		return 0, errors.New(errUnmatchedShards)
+	}
+
+	size := -1
+	for _, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		if size >= 0 && len(shard) != size {
+			return 0, errors.New(errUnmatchedShardSize)
+		}
+		size = len(shard)
+	}
+	return size, nil
+}
+
+// Encode calculates the parity shards from the data shards. All the
+// shards should be allocated in the same size.
+func (rs *ReedSolomon) Encode(shards [][]byte) error {
+	if len(shards) != rs.TotalShards() {
+		return errors.New(errUnmatchedShards)
+	}
+	for _, shard := range shards {
+		if shard == nil {
+			return errors.New(errUnmatchedShards)
+		}
+	}
+	if _, err := rs.shardSize(shards); err != nil {
+		return err
+	}
+
+	rs.encodeRows(rs.matrix[rs.dataShards:], shards[:rs.dataShards], shards[rs.dataShards:])
+	return nil
+}
+
+// encodeRows sets outputs[i] = Sum(rows[i][j] * inputs[j])
+func (rs *ReedSolomon) encodeRows(rows matrix, inputs, outputs [][]byte) {
+	for i, row := range rows {
+		out := outputs[i]
+		for k := range out {
+			out[k] = 0
+		}
+		for j, in := range inputs {
+			gfMulSliceAdd(row[j], in, out)
+		}
+	}
+}
+
+// Reconstruct restores the missing shards, which are given as nil, in place.
+// At least DataShards() shards are required.
+func (rs *ReedSolomon) Reconstruct(shards [][]byte) error {
+	size, err := rs.shardSize(shards)
+	if err != nil {
+		return err
+	}
+
+	// pick the first dataShards present shards & the coresponding rows
+	present := make([][]byte, 0, rs.dataShards)
+	rows := make(matrix, 0, rs.dataShards)
+	for i, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		present = append(present, shard)
+		rows = append(rows, rs.matrix[i])
+		if len(present) == rs.dataShards {
+			break
+		}
+	}
+	if len(present) < rs.dataShards {
+		return errors.New(errTooFewShards)
+	}
+
+	// the data shards are restored by the inverse of the picked rows
+	decoder, err := rows.invert()
+	if err != nil {
+		return err
+	}
+	data := make([][]byte, rs.dataShards)
+	for i := range data {
+		if shards[i] != nil {
+			data[i] = shards[i]
+			continue
+		}
+		data[i] = make([]byte, size)
+		rs.encodeRows(decoder[i:i+1], present, data[i:i+1])
+	}
+
+	// then the missing parity shards are encoded again
+	for i := rs.dataShards; i < len(shards); i++ {
+		if shards[i] != nil {
+			continue
+		}
+		shards[i] = make([]byte, size)
+		rs.encodeRows(rs.matrix[i:i+1], data, shards[i:i+1])
+	}
+	copy(shards, data)
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package erasure
+
+import (
+	"bytes"
+	"crypto/rand"
+	mrand "math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	rsTestRound     = 64
+	rsTestShardSize = 333
+)
+
+func getRandShards(rs *ReedSolomon) [][]byte {
+	shards := make([][]byte, rs.TotalShards())
+	for i := range shards {
+		shards[i] = make([]byte, rsTestShardSize)
+		if i < rs.DataShards() {
+			if _, err := rand.Read(shards[i]); err != nil {
+				panic(err)
+			}
+		}
+	}
+	return shards
+}
+
+func TestReedSolomonReconstruct(t *testing.T) {
+	mrand.Seed(time.Now().UnixNano())
+	for i := 0; i < rsTestRound; i++ {
+		dataShards := 1 + mrand.Intn(16)
+		parityShards := mrand.Intn(8)
+		rs, err := New(dataShards, parityShards)
+		require.NoError(t, err)
+
+		shards := getRandShards(rs)
+		require.NoError(t, rs.Encode(shards))
+		origin := make([][]byte, len(shards))
+		for j := range shards {
+			origin[j] = append([]byte{}, shards[j]...)
+		}
+
+		// lose up to 'parityShards' random shards
+		lost := mrand.Perm(rs.TotalShards())[:mrand.Intn(parityShards+1)]
+		for _, j := range lost {
+			shards[j] = nil
+		}
+		require.NoError(t, rs.Reconstruct(shards))
+		for j := range shards {
+			assert.True(t, bytes.Equal(origin[j], shards[j]))
+		}
+	}
+}
+
+func TestReedSolomonEdgeCases(t *testing.T) {
+	_, err := New(0, 1)
+	assert.Error(t, err)
+	_, err = New(200, 57)
+	assert.Error(t, err)
+
+	rs, err := New(4, 2)
+	require.NoError(t, err)
+
+	shards := getRandShards(rs)
+	assert.Error(t, rs.Encode(shards[1:]))
+	shards[5] = shards[5][1:]
+	assert.Error(t, rs.Encode(shards))
+
+	shards = getRandShards(rs)
+	require.NoError(t, rs.Encode(shards))
+	shards[0], shards[2], shards[5] = nil, nil, nil
+	assert.Error(t, rs.Reconstruct(shards))
+}
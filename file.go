@@ -0,0 +1,311 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// constant
+const (
+	errTagFileFmt       = "Failed to generate tags for file: %s"
+	errParseFileMetaFmt = "Failed to restore FileMeta: %s"
+	errFileBlockFmt     = "Failed to locate block %d of file: %s"
+	errProveFileFmt     = "Failed to prove against challenge set on file: %s"
+	errInvalidBlockSize = "invalid block size"
+	errInvalidFileSize  = "invalid file size"
+	errBlockOutOfRange  = "block number out of range"
+	errBlockNotInFile   = "index not belonging to the file"
+	errUnmatchedTagsNum = "unmatched tags num"
+
+	// the num of blocks from which TagFile builds the fixed-base tables
+	precomputeBlockNum = 16
+)
+
+// FileMeta holds the metadata of a file tagged by TagFile. A file is cut
+// into fixed-size blocks, the last one of which may be shorter.
+type FileMeta struct {
+	fileID    []byte
+	blockSize int64
+	size      int64
+
+	// the Reed-Solomon coding parameters of a file encoded by EncodeFile,
+	// all zero for the files not encoded
+	dataBlocks   int64
+	parityBlocks int64
+	origSize     int64
+}
+
+// FileID returns the identifier of the file
+func (m *FileMeta) FileID() []byte {
+	return m.fileID
+}
+
+// BlockSize returns the size of the blocks in bytes
+func (m *FileMeta) BlockSize() int64 {
+	return m.blockSize
+}
+
+// Size returns the size of the whole file in bytes
+func (m *FileMeta) Size() int64 {
+	return m.size
+}
+
+// BlockNum returns the number of blocks the file is cut into, which is 0 for
+// a FileMeta with no block size, e.g. the zero value
+func (m *FileMeta) BlockNum() int64 {
+	if m.blockSize <= 0 {
+		return 0
+	}
+	// (size + blockSize - 1) / blockSize may overflow for a huge blockSize
+	n := m.size / m.blockSize
+	if m.size%m.blockSize != 0 {
+		n++
+	}
+	return n
+}
+
+// Marshal works as a serialization routine. The coding parameters are
+// appended for the files encoded by EncodeFile.
+func (m *FileMeta) Marshal() string {
+	res := fmt.Sprintf("%s,%d,%d", base64.StdEncoding.EncodeToString(m.fileID), m.blockSize, m.size)
+	if m.Encoded() {
+		res += fmt.Sprintf(",%d,%d,%d", m.dataBlocks, m.parityBlocks, m.origSize)
+	}
+	return res
+}
+
+// ParseFileMeta trys to restore a FileMeta instance
+func ParseFileMeta(s string) (*FileMeta, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 && len(parts) != 6 {
+		return nil, fmt.Errorf(errParseFileMetaFmt, "unmatched parts num")
+	}
+
+	fileID, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf(errParseFileMetaFmt, err.Error())
+	}
+
+	blockSize, err := strconv.ParseInt(parts[1], intStrRadix, 64)
+	if err != nil {
+		return nil, fmt.Errorf(errParseFileMetaFmt, err.Error())
+	}
+	if blockSize <= 0 {
+		return nil, fmt.Errorf(errParseFileMetaFmt, errInvalidBlockSize)
+	}
+
+	size, err := strconv.ParseInt(parts[2], intStrRadix, 64)
+	if err != nil {
+		return nil, fmt.Errorf(errParseFileMetaFmt, err.Error())
+	}
+	if size < 0 {
+		return nil, fmt.Errorf(errParseFileMetaFmt, errInvalidFileSize)
+	}
+
+	meta := &FileMeta{
+		fileID:    fileID,
+		blockSize: blockSize,
+		size:      size,
+	}
+	if len(parts) == 3 {
+		return meta, nil
+	}
+
+	coding := make([]int64, 3)
+	for i, part := range parts[3:] {
+		coding[i], err = strconv.ParseInt(part, intStrRadix, 64)
+		if err != nil {
+			return nil, fmt.Errorf(errParseFileMetaFmt, err.Error())
+		}
+	}
+	meta.dataBlocks, meta.parityBlocks, meta.origSize = coding[0], coding[1], coding[2]
+	if err := meta.validateCoding(); err != nil {
+		return nil, fmt.Errorf(errParseFileMetaFmt, err.Error())
+	}
+	return meta, nil
+}
+
+// validate checks the fields of a FileMeta instance restored from the
+// untrusted data, the coding parameters included
+func (m *FileMeta) validate() error {
+	if m.blockSize <= 0 {
+		return errors.New(errInvalidBlockSize)
+	}
+	if m.size < 0 {
+		return errors.New(errInvalidFileSize)
+	}
+	if m.Encoded() {
+		return m.validateCoding()
+	}
+	if m.parityBlocks != 0 || m.origSize != 0 {
+		return errors.New(errInvalidCoding)
+	}
+	return nil
+}
+
+// blockID returns the BlockID of the 'blockNo'-th block of the file, which
+// is used in both the tag & the challenge of the block
+func (m *FileMeta) blockID(blockNo int64) BlockID {
+	return NewBlockID(m.fileID, blockNo)
+}
+
+// blockNo restores the block number from an index created by blockID
+func (m *FileMeta) blockNo(idx []byte) (int64, error) {
+	id, err := ParseBlockID(idx)
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(id.FileID(), m.fileID) {
+		return 0, errors.New(errBlockNotInFile)
+	}
+	if id.Index() < 0 || id.Index() >= m.BlockNum() {
+		return 0, errors.New(errBlockOutOfRange)
+	}
+	return id.Index(), nil
+}
+
+// BlockReader returns a reader of the 'blockNo'-th block's content in the
+// given file storage 'r'. Only the required block will be read.
+func (m *FileMeta) BlockReader(r io.ReaderAt, blockNo int64) (io.Reader, error) {
+	if blockNo < 0 || blockNo >= m.BlockNum() {
+		return nil, fmt.Errorf(errFileBlockFmt, blockNo, errBlockOutOfRange)
+	}
+
+	// offset + length may overflow for the huge sizes
+	offset := blockNo * m.blockSize
+	if offset > m.size {
+		return nil, fmt.Errorf(errFileBlockFmt, blockNo, errBlockOutOfRange)
+	}
+	length := m.blockSize
+	if length > m.size-offset {
+		length = m.size - offset
+	}
+	return io.NewSectionReader(r, offset, length), nil
+}
+
+// TagFile cuts the content read from 'r' into blocks of 'blockSize' bytes
+// and tags each of them. The tags are returned in the order of the blocks,
+// together with the metadata required to challenge & prove the file later.
+func TagFile(sp *PrivateParams, pp *PublicParams, fileID []byte, r io.Reader, blockSize int) ([]Tag, *FileMeta, error) {
+	return tagFile(sp, pp, fileID, r, blockSize, GenBlockTag)
+}
+
+// blockTagger is the routine tagging a single block of a file
+type blockTagger func(sp *PrivateParams, pp *PublicParams, id BlockID, data io.Reader) (Tag, error)
+
+func tagFile(sp *PrivateParams, pp *PublicParams, fileID []byte, r io.Reader, blockSize int, tagger blockTagger) ([]Tag, *FileMeta, error) {
+	if blockSize <= 0 {
+		return nil, nil, fmt.Errorf(errTagFileFmt, errInvalidBlockSize)
+	}
+
+	meta := &FileMeta{
+		fileID:    append([]byte{}, fileID...),
+		blockSize: int64(blockSize),
+	}
+	tags := []Tag{}
+	buf := make([]byte, blockSize)
+	for {
+		// the tables pay off only for the files with enough blocks
+		if len(tags) == precomputeBlockNum {
+			pp.Precompute()
+		}
+
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return nil, nil, fmt.Errorf(errTagFileFmt, err.Error())
+		}
+
+		blockNo := int64(len(tags))
+		t, tErr := tagger(sp, pp, meta.blockID(blockNo), bytes.NewReader(buf[:n]))
+		if tErr != nil {
+			return nil, nil, fmt.Errorf(errTagFileFmt, tErr.Error())
+		}
+		tags = append(tags, t)
+		meta.size += int64(n)
+
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
+	}
+
+	return tags, meta, nil
+}
+
+// GenFileChal creates a challenge against the 'blockNo'-th block of the file
+func GenFileChal(meta *FileMeta, blockNo int64) (Chal, error) {
+	if blockNo < 0 || blockNo >= meta.BlockNum() {
+		return Chal{}, fmt.Errorf(errFileBlockFmt, blockNo, errBlockOutOfRange)
+	}
+
+	return GenBlockChal(meta.blockID(blockNo))
+}
+
+// GenFileChalSet creates a challenge set against all the given blocks of the file
+func GenFileChalSet(meta *FileMeta, blockNos []int64) (ChalSet, error) {
+	ids := make([]BlockID, len(blockNos))
+	for i, blockNo := range blockNos {
+		if blockNo < 0 || blockNo >= meta.BlockNum() {
+			return nil, fmt.Errorf(errFileBlockFmt, blockNo, errBlockOutOfRange)
+		}
+		ids[i] = meta.blockID(blockNo)
+	}
+	return GenBlockChalSet(ids)
+}
+
+// ProveFile creates *ONE* aggregated Proof against the challenge set 'cs' on
+// the file stored in 'r'. 'tags' is the full tag list returned by TagFile and
+// only the challenged blocks will be read from 'r'.
+func ProveFile(pp *PublicParams, meta *FileMeta, cs ChalSet, tags []Tag, r io.ReaderAt) (Proof, error) {
+	chalTags, data, err := meta.challengedBlocks(cs, tags, r)
+	if err != nil {
+		return Proof{}, fmt.Errorf(errProveFileFmt, err.Error())
+	}
+	return ProveSet(pp, cs, chalTags, data)
+}
+
+// challengedBlocks picks the tags & the block readers challenged by 'cs'
+func (m *FileMeta) challengedBlocks(cs ChalSet, tags []Tag, r io.ReaderAt) ([]Tag, []io.Reader, error) {
+	if int64(len(tags)) != m.BlockNum() {
+		return nil, nil, errors.New(errUnmatchedTagsNum)
+	}
+
+	chalTags := make([]Tag, len(cs))
+	data := make([]io.Reader, len(cs))
+	for i, c := range cs {
+		blockNo, err := m.blockNo(c.idx)
+		if err != nil {
+			return nil, nil, err
+		}
+		chalTags[i] = tags[blockNo]
+		data[i], err = m.BlockReader(r, blockNo)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return chalTags, data, nil
+}
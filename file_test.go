@@ -0,0 +1,136 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/LambdaIM/proofDP/math"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fileTestBlockSize = 1024
+	fileTestSize      = 10*fileTestBlockSize + 100
+)
+
+func getRandFile(size int) []byte {
+	res := make([]byte, size)
+	_, err := rand.Read(res)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+func TestTagFile(t *testing.T) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	require.NoError(t, err)
+
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	pp := sp.GeneratePublicParams(u)
+
+	data := getRandFile(fileTestSize)
+	tags, meta, err := TagFile(sp, pp, []byte("sample-file"), bytes.NewReader(data), fileTestBlockSize)
+	require.NoError(t, err)
+	require.Equal(t, int64(11), meta.BlockNum())
+	require.Equal(t, int64(fileTestSize), meta.Size())
+	require.Len(t, tags, 11)
+
+	restored, err := ParseFileMeta(meta.Marshal())
+	require.NoError(t, err)
+	require.Equal(t, meta, restored)
+
+	// challenge some blocks including the short tail one
+	cs, err := GenFileChalSet(meta, []int64{0, 4, 10})
+	require.NoError(t, err)
+
+	proof, err := ProveFile(pp, meta, cs, tags, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.True(t, VerifyProofSet(pp, cs, proof))
+
+	// a change in an unchallenged block does not matter
+	data[fileTestBlockSize*2] ^= byte(1 << 7)
+	proof, err = ProveFile(pp, meta, cs, tags, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.True(t, VerifyProofSet(pp, cs, proof))
+
+	// a change in a challenged block fails the proof
+	data[fileTestBlockSize*4+1] ^= byte(1 << 7)
+	proof, err = ProveFile(pp, meta, cs, tags, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.False(t, VerifyProofSet(pp, cs, proof))
+
+	// challenges out of the file
+	_, err = GenFileChal(meta, meta.BlockNum())
+	require.Error(t, err)
+	otherMeta := &FileMeta{fileID: []byte("other-file"), blockSize: meta.blockSize, size: meta.size}
+	otherCs, err := GenFileChalSet(otherMeta, []int64{1})
+	require.NoError(t, err)
+	_, err = ProveFile(pp, meta, otherCs, tags, bytes.NewReader(data))
+	require.Error(t, err)
+}
+
+func TestTagFileEdgeCases(t *testing.T) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	require.NoError(t, err)
+
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	pp := sp.GeneratePublicParams(u)
+
+	tags, meta, err := TagFile(sp, pp, []byte("empty-file"), bytes.NewReader(nil), fileTestBlockSize)
+	require.NoError(t, err)
+	require.Empty(t, tags)
+	require.Equal(t, int64(0), meta.BlockNum())
+
+	tags, meta, err = TagFile(sp, pp, []byte("aligned-file"), bytes.NewReader(getRandFile(2*fileTestBlockSize)), fileTestBlockSize)
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	require.Equal(t, int64(2), meta.BlockNum())
+
+	_, _, err = TagFile(sp, pp, []byte("bad-file"), bytes.NewReader(nil), 0)
+	require.Error(t, err)
+
+	_, err = ParseFileMeta("AAAA,0,1")
+	require.Error(t, err)
+	_, err = ParseFileMeta("AAAA,1")
+	require.Error(t, err)
+
+	// the block num must not overflow for a huge block size
+	meta, err = ParseFileMeta("AAAA,9223372036854775807,1")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), meta.BlockNum())
+
+	// nor does the last block overflow its length
+	meta, err = ParseFileMeta("AAAA,4611686018427387904,9223372036854775807")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), meta.BlockNum())
+	r, err := meta.BlockReader(bytes.NewReader(nil), 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1<<62-1), r.(*io.SectionReader).Size())
+	_, err = meta.BlockReader(bytes.NewReader(nil), 2)
+	require.Error(t, err)
+
+	// the zero FileMeta holds no block
+	require.Equal(t, int64(0), (&FileMeta{}).BlockNum())
+}
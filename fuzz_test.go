@@ -0,0 +1,229 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build go1.18
+// +build go1.18
+
+package proofDP
+
+import (
+	"bytes"
+	"encoding"
+	"io"
+	"testing"
+
+	"github.com/LambdaIM/proofDP/math"
+)
+
+// the fuzz tests make sure that the parsers never panic on untrusted input
+
+// fuzzSeeds returns the marshaled PublicParams, ChalSet & Proof of a valid
+// proof, as the seeds of the fuzz tests
+func fuzzSeeds(f *testing.F) (string, string, string) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	if err != nil {
+		f.Fatal(err)
+	}
+	u, err := math.RandEllipticPt()
+	if err != nil {
+		f.Fatal(err)
+	}
+	pp := sp.GeneratePublicParams(u)
+
+	data := []byte("fuzz")
+	id := NewBlockID([]byte("fuzz-file"), 1)
+	tag, err := GenBlockTag(sp, pp, id, bytes.NewReader(data))
+	if err != nil {
+		f.Fatal(err)
+	}
+	cs, err := GenBlockChalSet([]BlockID{id})
+	if err != nil {
+		f.Fatal(err)
+	}
+	p, err := ProveSet(pp, cs, []Tag{tag}, []io.Reader{bytes.NewReader(data)})
+	if err != nil {
+		f.Fatal(err)
+	}
+	return pp.Marshal(), cs.Marshal(), p.Marshal()
+}
+
+func FuzzParsePublicParams(f *testing.F) {
+	pp, _, _ := fuzzSeeds(f)
+	f.Add(pp)
+	f.Add(pp + "," + pp)
+	f.Add(",,")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		pp, err := ParsePublicParams(s)
+		if err != nil {
+			return
+		}
+		if _, err := ParsePublicParams(pp.Marshal()); err != nil {
+			t.Fatalf("failed to restore %q", s)
+		}
+	})
+}
+
+func FuzzParseChalSet(f *testing.F) {
+	_, cs, _ := fuzzSeeds(f)
+	f.Add(cs)
+	f.Add(cs + chalSetSep + cs)
+	f.Add(chalSetSep)
+
+	f.Fuzz(func(t *testing.T, s string) {
+		cs, err := ParseChalSet(s)
+		if err != nil {
+			return
+		}
+		res, err := ParseChalSet(cs.Marshal())
+		if err != nil || !res.Equal(cs) {
+			t.Fatalf("failed to restore %q", s)
+		}
+	})
+}
+
+func FuzzParseProof(f *testing.F) {
+	_, _, p := fuzzSeeds(f)
+	f.Add(p)
+	f.Add(",,")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		p, err := ParseProof(s)
+		if err != nil {
+			return
+		}
+		if _, err := ParseProof(p.Marshal()); err != nil {
+			t.Fatalf("failed to restore %q", s)
+		}
+	})
+}
+
+func FuzzParseSectorProof(f *testing.F) {
+	_, _, p := fuzzSeeds(f)
+	f.Add(p)
+	f.Add(",,,")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		p, err := ParseSectorProof(s)
+		if err != nil {
+			return
+		}
+		if _, err := ParseSectorProof(p.Marshal()); err != nil {
+			t.Fatalf("failed to restore %q", s)
+		}
+	})
+}
+
+func FuzzParseTag(f *testing.F) {
+	g := math.GetGenerator()
+	f.Add(g.Marshal())
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		ParseTag(s)
+		ParsePrivateParams(s)
+	})
+}
+
+func FuzzParseFileMeta(f *testing.F) {
+	f.Add("ZmlsZQ==,1024,10340")
+	f.Add("ZmlsZQ==,1024,12288,4,2,10340")
+	f.Add(",,,,,")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		meta, err := ParseFileMeta(s)
+		if err != nil {
+			return
+		}
+		meta.BlockNum()
+		if _, err := ParseFileMeta(meta.Marshal()); err != nil {
+			t.Fatalf("failed to restore %q", s)
+		}
+	})
+}
+
+func FuzzParseBlockID(f *testing.F) {
+	f.Add(NewBlockID([]byte("fuzz-file"), 1).Bytes())
+	f.Add([]byte(blockIDDomain))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		id, err := ParseBlockID(b)
+		if err != nil {
+			return
+		}
+		if !bytes.Equal(id.Bytes(), b) {
+			t.Fatalf("failed to restore %x", b)
+		}
+	})
+}
+
+func FuzzUnmarshalBinary(f *testing.F) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	if err != nil {
+		f.Fatal(err)
+	}
+	ppStr, csStr, pStr := fuzzSeeds(f)
+	pp, err := ParsePublicParams(ppStr)
+	if err != nil {
+		f.Fatal(err)
+	}
+	cs, err := ParseChalSet(csStr)
+	if err != nil {
+		f.Fatal(err)
+	}
+	p, err := ParseProof(pStr)
+	if err != nil {
+		f.Fatal(err)
+	}
+	meta, err := ParseFileMeta("ZmlsZQ==,1024,12288,4,2,8000")
+	if err != nil {
+		f.Fatal(err)
+	}
+	kp, err := NewArgon2idParams([]byte("fuzz salt"), "fuzz", 1, 64, 1)
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, m := range []encoding.BinaryMarshaler{pp, sp, &cs[0], cs, &p, meta, NewBlockID([]byte("fuzz-file"), 1), kp} {
+		data, err := m.MarshalBinary()
+		if err != nil {
+			f.Fatal(err)
+		}
+		// the checksum is left to the fuzz function, so that the fuzzer
+		// mutates the fields rather than fails on the checksum
+		f.Add(data[len(binaryMagic)+1], data[sizeOfBinaryHeader:len(data)-sizeOfBinaryChecksum])
+	}
+
+	f.Fuzz(func(t *testing.T, typ byte, body []byte) {
+		w := newBinWriter(typ)
+		w.buf = append(w.buf, body...)
+		data, _ := w.seal()
+
+		for _, v := range []interface {
+			encoding.BinaryMarshaler
+			encoding.BinaryUnmarshaler
+		}{&PublicParams{}, &PrivateParams{}, &Chal{}, &ChalSet{}, &Proof{}, &SectorProof{}, &FileMeta{}, &BlockID{}, &SignPubKey{},
+			&KeyDerivationParams{}} {
+			if err := v.UnmarshalBinary(data); err != nil {
+				continue
+			}
+			res, err := v.MarshalBinary()
+			if err != nil || !bytes.Equal(res, data) {
+				t.Fatalf("failed to restore %x", data)
+			}
+		}
+	})
+}
@@ -0,0 +1,451 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/LambdaIM/proofDP/math"
+)
+
+// constant
+const (
+	errNewMasterKeyFmt          = "Failed to create master key: %s"
+	errDeriveChildKeyFmt        = "Failed to derive child key (index:%d): %s"
+	errParseDerivationPathFmt   = "Failed to parse derivation path %q: %s"
+	errParseExtendedPrivKeyFmt  = "Failed to restore ExtendedPrivKey: %s"
+	errParseExtendedPubKeyFmt   = "Failed to restore ExtendedPubKey: %s"
+	errInvalidSeedSize          = "seed size out of range"
+	errInvalidChildKey          = "invalid child key, try the next index"
+	errZeroKey                  = "zero key"
+	errHardenedFromPubKey       = "hardened child of public key"
+	errMaxDepthExceeded         = "max depth exceeded"
+	errInvalidPathComponent     = "invalid path component"
+	errInvalidChainCode         = "invalid chain code"
+	errInvalidDepth             = "invalid depth"
+	errUnmatchedExtendedKeyPart = "unmatched parts num"
+
+	// HardenedKeyStart is the first index of the hardened children
+	HardenedKeyStart uint32 = 1 << 31
+
+	hdSeedKey = "proofDP HD seed"
+	// the domain separation prefix of the names mapped to the indices
+	hdNameDomain = "proofDP HD name"
+	// the sizes of the seeds in bytes, as BIP32 recommends
+	hdMinSeedSize = 16
+	hdMaxSeedSize = 64
+	// the size of the chain codes in bytes
+	hdChainCodeSize = 32
+	// the depth of the master keys is 0
+	hdMaxDepth = 255
+)
+
+// extendedKey holds the parts shared by the extended private & public keys
+type extendedKey struct {
+	chainCode [hdChainCodeSize]byte
+	depth     int
+	index     uint32
+}
+
+// ChainCode returns the chain code of the key
+func (k *extendedKey) ChainCode() []byte {
+	return append([]byte{}, k.chainCode[:]...)
+}
+
+// Depth returns the depth of the key in the hierarchy, which is 0 for the
+// master key
+func (k *extendedKey) Depth() int {
+	return k.depth
+}
+
+// Index returns the index of the key among its siblings, which is 0 for
+// the master key
+func (k *extendedKey) Index() uint32 {
+	return k.index
+}
+
+// child derives the tweak & the extended key of the child 'index' from the
+// HMAC-SHA512 of 'data' || 'index'
+func (k *extendedKey) child(data []byte, index uint32) (math.GaloisElem, extendedKey, error) {
+	if k.depth >= hdMaxDepth {
+		return math.GaloisElem{}, extendedKey{}, errors.New(errMaxDepthExceeded)
+	}
+
+	mac := hmac.New(sha512.New, k.chainCode[:])
+	mac.Write(data)
+	var i [4]byte
+	binary.BigEndian.PutUint32(i[:], index)
+	mac.Write(i[:])
+	sum := mac.Sum(nil)
+
+	res := extendedKey{
+		depth: k.depth + 1,
+		index: index,
+	}
+	copy(res.chainCode[:], sum[hdChainCodeSize:])
+	return math.HashToGaloisElem(sum[:hdChainCodeSize]), res, nil
+}
+
+// marshal joins the parts with the key
+func (k *extendedKey) marshal(key []byte) string {
+	return fmt.Sprintf("%d,%d,%s,%s", k.depth, k.index,
+		base64.StdEncoding.EncodeToString(k.chainCode[:]), base64.StdEncoding.EncodeToString(key))
+}
+
+// parseExtendedKey restores the parts along with the encoded key
+func parseExtendedKey(s string) (extendedKey, string, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return extendedKey{}, "", errors.New(errUnmatchedExtendedKeyPart)
+	}
+
+	depth, err := strconv.ParseUint(parts[0], intStrRadix, 8)
+	if err != nil {
+		return extendedKey{}, "", err
+	}
+	index, err := strconv.ParseUint(parts[1], intStrRadix, 32)
+	if err != nil {
+		return extendedKey{}, "", err
+	}
+	chainCode, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return extendedKey{}, "", err
+	}
+
+	res, err := newExtendedKey(int(depth), uint32(index), chainCode)
+	if err != nil {
+		return extendedKey{}, "", err
+	}
+	return res, parts[3], nil
+}
+
+// newExtendedKey restores the parts shared by the extended keys, which are
+// checked against each other
+func newExtendedKey(depth int, index uint32, chainCode []byte) (extendedKey, error) {
+	if depth < 0 || depth > hdMaxDepth {
+		return extendedKey{}, errors.New(errInvalidDepth)
+	}
+	if depth == 0 && index != 0 {
+		return extendedKey{}, errors.New(errInvalidPathComponent)
+	}
+	if len(chainCode) != hdChainCodeSize {
+		return extendedKey{}, errors.New(errInvalidChainCode)
+	}
+
+	res := extendedKey{
+		depth: depth,
+		index: index,
+	}
+	copy(res.chainCode[:], chainCode)
+	return res, nil
+}
+
+// ExtendedPrivKey is a private key in the hierarchy along with its chain
+// code, which derives the child private keys in the spirit of BIP32, over
+// the gFR scalars & the points g^x. The child of index i is derived by
+//
+//	I = HMAC-SHA512(c, 0x00 || x || i)   for the hardened i >= 2^31
+//	I = HMAC-SHA512(c, g^x || i)         for the normal i
+//	x_i = x + H(I_L), c_i = I_R
+//
+// where c is the chain code, i is in big-endian, x & g^x are in their
+// fixed-length binary forms & H maps to gFR just as HashToGaloisElem does.
+// The hardened children keep the parent private key safe from a leaked child
+// private key, thus the levels that the public keys are shared below should
+// be hardened, e.g. "m/0'/<tenant>/<bucket>" for the PrivateParams &
+// "m/1'/<tenant>/<bucket>" for the SignPrivKeys, see NameToIndex. A child key
+// should never serve both usages.
+type ExtendedPrivKey struct {
+	extendedKey
+	key math.GaloisElem
+}
+
+// NewMasterKey returns the master key derived from the seed of 16 to 64
+// bytes, which should be random, e.g. the bytes read from crypto/rand. With
+// I = HMAC-SHA512("proofDP HD seed", seed), the private key is H(I_L) & the
+// chain code is I_R.
+func NewMasterKey(seed []byte) (*ExtendedPrivKey, error) {
+	if len(seed) < hdMinSeedSize || len(seed) > hdMaxSeedSize {
+		return nil, fmt.Errorf(errNewMasterKeyFmt, errInvalidSeedSize)
+	}
+
+	mac := hmac.New(sha512.New, []byte(hdSeedKey))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	k := &ExtendedPrivKey{
+		key: math.HashToGaloisElem(sum[:hdChainCodeSize]),
+	}
+	if k.key.Equal(math.NewGaloisElem(0)) {
+		return nil, fmt.Errorf(errNewMasterKeyFmt, errZeroKey)
+	}
+	copy(k.chainCode[:], sum[hdChainCodeSize:])
+	return k, nil
+}
+
+// Child derives the child private key of 'index', where the indices from
+// HardenedKeyStart on are the hardened children. An error is returned for
+// the negligible chance of the zero key, where the next index should be used.
+func (k *ExtendedPrivKey) Child(index uint32) (*ExtendedPrivKey, error) {
+	var data []byte
+	if index >= HardenedKeyStart {
+		data = append([]byte{0}, k.key.Bytes()...)
+	} else {
+		pub := k.publicKey()
+		data = pub.CompressedBytes()
+	}
+
+	tweak, ek, err := k.child(data, index)
+	if err != nil {
+		return nil, fmt.Errorf(errDeriveChildKeyFmt, index, err.Error())
+	}
+	res := &ExtendedPrivKey{
+		extendedKey: ek,
+		key:         math.GaloisAdd(k.key, tweak),
+	}
+	if res.key.Equal(math.NewGaloisElem(0)) {
+		return nil, fmt.Errorf(errDeriveChildKeyFmt, index, errInvalidChildKey)
+	}
+	return res, nil
+}
+
+// Derive derives the descendant private key along 'path' from 'k', see
+// ParseDerivationPath
+func (k *ExtendedPrivKey) Derive(path string) (*ExtendedPrivKey, error) {
+	indices, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	res := k
+	for _, i := range indices {
+		if res, err = res.Child(i); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// publicKey returns g^x
+func (k *ExtendedPrivKey) publicKey() math.EllipticPoint {
+	return math.EllipticPowSecret(math.GetGenerator(), k.key)
+}
+
+// Public returns the extended public key of 'k', which derives the public
+// keys of the normal children
+func (k *ExtendedPrivKey) Public() *ExtendedPubKey {
+	return &ExtendedPubKey{
+		extendedKey: k.extendedKey,
+		key:         k.publicKey(),
+	}
+}
+
+// PrivateParams returns the PrivateParams instance of the key, whose 'v' of
+// the PublicParams is the key of Public()
+func (k *ExtendedPrivKey) PrivateParams() *PrivateParams {
+	return &PrivateParams{
+		x: k.key,
+	}
+}
+
+// SignPrivKey returns the SignPrivKey instance of the key, whose public key
+// is the key of Public()
+func (k *ExtendedPrivKey) SignPrivKey() *SignPrivKey {
+	return newSignPrivKey(k.key)
+}
+
+// Marshal works as the serialization routine, i.e. the depth, the index,
+// the chain code & the private key joined by commas
+func (k *ExtendedPrivKey) Marshal() string {
+	return k.marshal(k.key.Bytes())
+}
+
+// ParseExtendedPrivKey trys to restore an ExtendedPrivKey instance
+func ParseExtendedPrivKey(s string) (*ExtendedPrivKey, error) {
+	ek, keyStr, err := parseExtendedKey(s)
+	if err != nil {
+		return nil, fmt.Errorf(errParseExtendedPrivKeyFmt, err.Error())
+	}
+	return parseExtendedPrivKeyFields(ek, keyStr)
+}
+
+// parseExtendedPrivKeyFields restores an ExtendedPrivKey instance from the
+// shared parts & the encoded private key
+func parseExtendedPrivKeyFields(ek extendedKey, keyStr string) (*ExtendedPrivKey, error) {
+	key, err := math.ParseGaloisElem(keyStr)
+	if err != nil {
+		return nil, fmt.Errorf(errParseExtendedPrivKeyFmt, err.Error())
+	}
+	if key.Equal(math.NewGaloisElem(0)) {
+		return nil, fmt.Errorf(errParseExtendedPrivKeyFmt, errZeroKey)
+	}
+
+	return &ExtendedPrivKey{
+		extendedKey: ek,
+		key:         key,
+	}, nil
+}
+
+// ExtendedPubKey is a public key in the hierarchy along with its chain
+// code, which derives the public keys of the normal children only, as
+// g^(x_i) == g^x * g^H(I_L), e.g. the 'v' of the PublicParams
+type ExtendedPubKey struct {
+	extendedKey
+	key math.EllipticPoint
+}
+
+// Child derives the public key of the normal child 'index', which is the
+// public key of the child private key of the same index. An error is
+// returned for the hardened children.
+func (pk *ExtendedPubKey) Child(index uint32) (*ExtendedPubKey, error) {
+	if index >= HardenedKeyStart {
+		return nil, fmt.Errorf(errDeriveChildKeyFmt, index, errHardenedFromPubKey)
+	}
+
+	tweak, ek, err := pk.child(pk.key.CompressedBytes(), index)
+	if err != nil {
+		return nil, fmt.Errorf(errDeriveChildKeyFmt, index, err.Error())
+	}
+	res := &ExtendedPubKey{
+		extendedKey: ek,
+		key:         math.EllipticMul(pk.key, math.EllipticPow(math.GetGenerator(), tweak)),
+	}
+	if res.key.IsInfinity() {
+		return nil, fmt.Errorf(errDeriveChildKeyFmt, index, errInvalidChildKey)
+	}
+	return res, nil
+}
+
+// Derive derives the descendant public key along 'path' from 'pk', where
+// all the indices should be of the normal children, see ParseDerivationPath
+func (pk *ExtendedPubKey) Derive(path string) (*ExtendedPubKey, error) {
+	indices, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	res := pk
+	for _, i := range indices {
+		if res, err = res.Child(i); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// GeneratePublicParams returns the PublicParams instance of the key with
+// the given point 'u', which is the same as the one that the PrivateParams
+// of the private key generates
+func (pk *ExtendedPubKey) GeneratePublicParams(u math.EllipticPoint) *PublicParams {
+	return newPublicParams(pk.key, u)
+}
+
+// SignPubKey returns the SignPubKey instance of the key
+func (pk *ExtendedPubKey) SignPubKey() SignPubKey {
+	return newSignPubKey(pk.key)
+}
+
+// Marshal works as the serialization routine, i.e. the depth, the index,
+// the chain code & the compressed public key joined by commas
+func (pk *ExtendedPubKey) Marshal() string {
+	return pk.marshal(pk.key.CompressedBytes())
+}
+
+// ParseExtendedPubKey trys to restore an ExtendedPubKey instance, an error
+// is returned for the key outside the subgroup or of the infinity point
+func ParseExtendedPubKey(s string) (*ExtendedPubKey, error) {
+	ek, keyStr, err := parseExtendedKey(s)
+	if err != nil {
+		return nil, fmt.Errorf(errParseExtendedPubKeyFmt, err.Error())
+	}
+	return parseExtendedPubKeyFields(ek, keyStr)
+}
+
+// parseExtendedPubKeyFields restores an ExtendedPubKey instance from the
+// shared parts & the encoded public key
+func parseExtendedPubKeyFields(ek extendedKey, keyStr string) (*ExtendedPubKey, error) {
+	key, err := math.ParseEllipticPt(keyStr)
+	if err != nil {
+		return nil, fmt.Errorf(errParseExtendedPubKeyFmt, err.Error())
+	}
+	if key.IsInfinity() {
+		return nil, fmt.Errorf(errParseExtendedPubKeyFmt, errZeroKey)
+	}
+
+	return &ExtendedPubKey{
+		extendedKey: ek,
+		key:         key,
+	}, nil
+}
+
+// ParseDerivationPath parses the path of the form "m/0'/1/2h", where "m"
+// stands for the key that the path is derived from & the indices marked by
+// "'" or "h" are the hardened ones, i.e. i + HardenedKeyStart. A leading
+// "M" is accepted as well for the public keys. The components are the plain
+// decimal indices only, the names such as the tenants & the buckets are
+// mapped to the indices by NameToIndex first.
+func ParseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" && parts[0] != "M" {
+		return nil, fmt.Errorf(errParseDerivationPathFmt, path, errInvalidPathComponent)
+	}
+
+	res := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		var offset uint32
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") {
+			offset = HardenedKeyStart
+			part = part[:len(part)-1]
+		}
+		// the plain decimals only, e.g. no sign
+		if len(part) == 0 || strings.TrimLeft(part, "0123456789") != "" {
+			return nil, fmt.Errorf(errParseDerivationPathFmt, path, errInvalidPathComponent)
+		}
+		i, err := strconv.ParseUint(part, intStrRadix, 31)
+		if err != nil {
+			return nil, fmt.Errorf(errParseDerivationPathFmt, path, errInvalidPathComponent)
+		}
+		res = append(res, uint32(i)+offset)
+	}
+	if len(res) > hdMaxDepth {
+		return nil, fmt.Errorf(errParseDerivationPathFmt, path, errMaxDepthExceeded)
+	}
+	return res, nil
+}
+
+// NameToIndex maps a name, e.g. of a tenant or a bucket, to the index of a
+// normal child, i.e. the first 31 bits of SHA256("proofDP HD name" || name),
+// so that the path of a bucket is built as
+//
+//	fmt.Sprintf("m/0'/%d/%d", NameToIndex(tenant), NameToIndex(bucket))
+//
+// Add HardenedKeyStart for the hardened child. The indices of 2 names may
+// collide with a chance of about n^2 / 2^32 among n names of the same
+// parent, where a registry of the indices should be kept instead.
+func NameToIndex(name string) uint32 {
+	h := sha256.Sum256(append([]byte(hdNameDomain), name...))
+	return binary.BigEndian.Uint32(h[:4]) &^ HardenedKeyStart
+}
@@ -0,0 +1,295 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/LambdaIM/proofDP/math"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHDKeyVectors(t *testing.T) {
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	require.NoError(t, err)
+	master, err := NewMasterKey(seed)
+	require.NoError(t, err)
+
+	// the vectors are produced by this implementation itself, which pin the
+	// derivation against the regressions only, see TestHDKeyIndependent
+	vectors := []struct {
+		path, priv, pub string
+	}{
+		{
+			"m",
+			"0,0,2YKsNHjhFnPjJQ8/835E/zOQzVBenX/XC5xJ5zApsy8=,QdvG4kO/shIZP3/JRaO7wQhSWkg=",
+			"0,0,2YKsNHjhFnPjJQ8/835E/zOQzVBenX/XC5xJ5zApsy8=,A2hW1LynoZj6qNUqOp7oPTg9PD6B9IIRHTVKD5dhwbxuYK9gL+HHV444Z3CFmh++x8wPEfGpfuzvG6egBvMltPY=",
+		},
+		{
+			"m/0'",
+			"1,2147483648,jdw9SR4H0wu4AIzjWtewH2wNSCdf7Ej9bewujtLCHz4=,M7HLab0SjL65SpBFi4Ql9IvNM6s=",
+			"1,2147483648,jdw9SR4H0wu4AIzjWtewH2wNSCdf7Ej9bewujtLCHz4=,AxyF87iDQ39L/63rK3ECEocICXsowiKxB372ic83gsMOmAtdUmwzap59Io3N4HBt8OGBmPmnhh5U9Jyl07mHL0E=",
+		},
+		{
+			"m/0'/1",
+			"2,1,1rLYJOaDAw5UDl5gdYCFu3hiMsa0/W9Q8dRhCcxRHyc=,c1I4cWg2kWUIzRAByLxz/t+keJ0=",
+			"2,1,1rLYJOaDAw5UDl5gdYCFu3hiMsa0/W9Q8dRhCcxRHyc=,A4lt4sPxGuFMN81OMjAmPmuInc5d/XkJmP1hXHYibEISUvUOmB69Ohoo2CaNyKbKW9QajiaCYKiHY0SPkcqXBfY=",
+		},
+		{
+			"m/0'/1/2'",
+			"3,2147483650,dt314Q1ysPXewMVLiL10v4UkHkR9GZJaNbRkNB1K/P8=,aLD4yevRprPl4ijn5KPZRs9SDlw=",
+			"3,2147483650,dt314Q1ysPXewMVLiL10v4UkHkR9GZJaNbRkNB1K/P8=,AxB6YwQEtSB0n23G6t72oHCE6dK1ckcF3jSZBcUdLhTKO18oJukodxT0aPKi98f0BFuuB34rbuqgcp7itSJpHGg=",
+		},
+		{
+			"m/0'/1/2'/2",
+			"4,2,07L8cyUzbcVLtNtPkMIrE1vAmtT1AFXe1/VtrQB5ycw=,SihITo/erh6wvocUQV5RXDJeqVM=",
+			"4,2,07L8cyUzbcVLtNtPkMIrE1vAmtT1AFXe1/VtrQB5ycw=,AgVhXFe4Fpq54pPel3nVl2JXXdvOc2BFGiiApi+ha046sZOv9fVP3nycEZ/lw4VDd1h94KJjNRCw6uMvsU6kwY4=",
+		},
+		{
+			"m/0'/1/2'/2/1000000000",
+			"5,1000000000,epEFpaM+U5McFm9gy51NTjdQ93uL4imJOrXOyeWSYbE=,Ny25XYfjk+aNu65Zb6LOVjBzBVI=",
+			"5,1000000000,epEFpaM+U5McFm9gy51NTjdQ93uL4imJOrXOyeWSYbE=,AhwkO/tvYF/vuygLtgL9TCMLKu0lZL7pt0EcvDbFfE7fPddypTL0vzQZpghNXdy4C+ywx010GVsitvgmrEByIXU=",
+		},
+	}
+
+	for _, v := range vectors {
+		k, err := master.Derive(v.path)
+		require.NoError(t, err, v.path)
+		require.Equal(t, v.priv, k.Marshal(), v.path)
+		require.Equal(t, v.pub, k.Public().Marshal(), v.path)
+
+		restored, err := ParseExtendedPrivKey(v.priv)
+		require.NoError(t, err)
+		require.Equal(t, k, restored)
+		restoredPub, err := ParseExtendedPubKey(v.pub)
+		require.NoError(t, err)
+		require.Equal(t, v.pub, restoredPub.Marshal())
+	}
+
+	// the normal children are derived from the public keys as well
+	parent, err := ParseExtendedPubKey(vectors[3].pub)
+	require.NoError(t, err)
+	child, err := parent.Derive("M/2/1000000000")
+	require.NoError(t, err)
+	require.Equal(t, vectors[5].pub, child.Marshal())
+}
+
+// TestHDKeyIndependent recomputes the first vectors of TestHDKeyVectors by
+// HMAC-SHA512 directly, following the derivation described in the docs
+func TestHDKeyIndependent(t *testing.T) {
+	hmacSHA512 := func(key []byte, data ...[]byte) ([]byte, []byte) {
+		mac := hmac.New(sha512.New, key)
+		for _, d := range data {
+			mac.Write(d)
+		}
+		sum := mac.Sum(nil)
+		return sum[:32], sum[32:]
+	}
+	index := func(i uint32) []byte {
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], i)
+		return b[:]
+	}
+	fields := func(s string) (chainCode, key []byte) {
+		parts := strings.Split(s, ",")
+		require.Len(t, parts, 4)
+		chainCode, err := base64.StdEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		key, err = base64.StdEncoding.DecodeString(parts[3])
+		require.NoError(t, err)
+		return chainCode, key
+	}
+
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	require.NoError(t, err)
+	master, err := NewMasterKey(seed)
+	require.NoError(t, err)
+
+	// m: I = HMAC-SHA512("proofDP HD seed", seed), x = H(I_L), c = I_R
+	il, ir := hmacSHA512([]byte("proofDP HD seed"), seed)
+	c, x := fields(master.Marshal())
+	require.Equal(t, ir, c)
+	xm := math.HashToGaloisElem(il)
+	require.Equal(t, xm.Bytes(), x)
+	require.Len(t, x, math.GaloisElemSize)
+
+	// m/0': I = HMAC-SHA512(c, 0x00 || x || 2^31), x' = x + H(I_L), c' = I_R
+	il, ir = hmacSHA512(c, []byte{0}, x, index(HardenedKeyStart))
+	k, err := master.Derive("m/0'")
+	require.NoError(t, err)
+	c, x = fields(k.Marshal())
+	require.Equal(t, ir, c)
+	xh := math.GaloisAdd(xm, math.HashToGaloisElem(il))
+	require.Equal(t, xh.Bytes(), x)
+
+	// m/0'/1: I = HMAC-SHA512(c', g^x' || 1), where g^x' is compressed
+	_, pub := fields(k.Public().Marshal())
+	il, ir = hmacSHA512(c, pub, index(1))
+	k, err = master.Derive("m/0'/1")
+	require.NoError(t, err)
+	c, x = fields(k.Marshal())
+	require.Equal(t, ir, c)
+	xn := math.GaloisAdd(xh, math.HashToGaloisElem(il))
+	require.Equal(t, xn.Bytes(), x)
+}
+
+func TestNameToIndex(t *testing.T) {
+	for _, name := range []string{"", "tenant-a", "bucket-1"} {
+		h := sha256.Sum256([]byte("proofDP HD name" + name))
+		expected := (uint32(h[0])<<24 | uint32(h[1])<<16 | uint32(h[2])<<8 | uint32(h[3])) & 0x7fffffff
+		require.Equal(t, expected, NameToIndex(name), name)
+		require.True(t, NameToIndex(name) < HardenedKeyStart, name)
+	}
+	require.NotEqual(t, NameToIndex("tenant-a"), NameToIndex("tenant-b"))
+
+	// the path of a bucket, derived from the shared public key as well
+	master, err := NewMasterKey(getRandSecret())
+	require.NoError(t, err)
+	pdpRoot, err := master.Derive("m/0'")
+	require.NoError(t, err)
+	path := fmt.Sprintf("m/%d/%d", NameToIndex("tenant-a"), NameToIndex("bucket-1"))
+	k, err := pdpRoot.Derive(path)
+	require.NoError(t, err)
+	require.Equal(t, NameToIndex("bucket-1"), k.Index())
+	pub, err := pdpRoot.Public().Derive(path)
+	require.NoError(t, err)
+	require.Equal(t, k.Public().Marshal(), pub.Marshal())
+}
+
+func TestHDKeyUsage(t *testing.T) {
+	master, err := NewMasterKey(getRandSecret())
+	require.NoError(t, err)
+	pdpRoot, err := master.Derive("m/0'")
+	require.NoError(t, err)
+	signRoot, err := master.Derive("m/1'")
+	require.NoError(t, err)
+
+	// the PublicParams of a bucket derived from the shared public key only
+	k, err := pdpRoot.Derive("m/7/42")
+	require.NoError(t, err)
+	require.Equal(t, 3, k.Depth())
+	require.Equal(t, uint32(42), k.Index())
+	pub, err := pdpRoot.Public().Derive("m/7/42")
+	require.NoError(t, err)
+	require.Equal(t, k.Public().Marshal(), pub.Marshal())
+	require.Equal(t, k.ChainCode(), pub.ChainCode())
+
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	pp := pub.GeneratePublicParams(u)
+	require.Equal(t, k.PrivateParams().GeneratePublicParams(u).Marshal(), pp.Marshal())
+	data := getRandFile(fileTestBlockSize)
+	id := NewBlockID([]byte("hd-file"), 0)
+	tag, err := GenBlockTag(k.PrivateParams(), pp, id, bytes.NewReader(data))
+	require.NoError(t, err)
+	chal, err := GenBlockChal(id)
+	require.NoError(t, err)
+	proof, err := Prove(pp, chal, tag, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.True(t, VerifyProof(pp, chal, proof))
+
+	// the keys of another bucket or usage fail the proof
+	for _, path := range []string{"m/7/43", "m/8/42"} {
+		other, err := pdpRoot.Derive(path)
+		require.NoError(t, err)
+		require.False(t, VerifyProof(other.Public().GeneratePublicParams(u), chal, proof), path)
+	}
+	other, err := signRoot.Derive("m/7/42")
+	require.NoError(t, err)
+	require.False(t, VerifyProof(other.Public().GeneratePublicParams(u), chal, proof))
+
+	// the SignPrivKey & SignPubKey
+	signPub, err := signRoot.Public().Derive("m/7/42")
+	require.NoError(t, err)
+	hash := sha256.Sum256([]byte("hd-message"))
+	require.True(t, VerifySignature(other.SignPrivKey().Sign(hash), hash, signPub.SignPubKey()))
+	require.False(t, VerifySignature(k.SignPrivKey().Sign(hash), hash, signPub.SignPubKey()))
+
+	// no hardened child from the public key
+	_, err = pdpRoot.Public().Derive("m/7'/42")
+	require.Error(t, err)
+	hardened, err := pdpRoot.Child(HardenedKeyStart + 7)
+	require.NoError(t, err)
+	require.NotEqual(t, hardened.Marshal(), k.Marshal())
+}
+
+func TestHDKeyEdgeCases(t *testing.T) {
+	for _, size := range []int{0, hdMinSeedSize - 1, hdMaxSeedSize + 1} {
+		_, err := NewMasterKey(make([]byte, size))
+		require.Error(t, err, size)
+	}
+	master, err := NewMasterKey(make([]byte, hdMaxSeedSize))
+	require.NoError(t, err)
+
+	for path, expected := range map[string][]uint32{
+		"m":               {},
+		"M":               {},
+		"m/0/1h/2'":       {0, HardenedKeyStart + 1, HardenedKeyStart + 2},
+		"m/2147483647'/0": {1<<32 - 1, 0},
+	} {
+		res, err := ParseDerivationPath(path)
+		require.NoError(t, err, path)
+		require.Equal(t, expected, res, path)
+	}
+	for _, path := range []string{"", "/1", "n/1", "m/", "m//1", "1/2", "m/-1", "m/+1", "m/1''", "m/1'h", "m/h", "m/2147483648", "m/1 "} {
+		_, err := ParseDerivationPath(path)
+		require.Error(t, err, path)
+	}
+
+	// the depth is limited
+	k := master
+	for i := 0; i < hdMaxDepth; i++ {
+		k, err = k.Child(0)
+		require.NoError(t, err)
+	}
+	_, err = k.Child(0)
+	require.Error(t, err)
+	_, err = k.Public().Child(0)
+	require.Error(t, err)
+
+	chainCode := "2YKsNHjhFnPjJQ8/835E/zOQzVBenX/XC5xJ5zApsy8="
+	for _, s := range []string{
+		"",
+		"0,0," + chainCode,
+		"0,1," + chainCode + ",QdvG4kO/shIZP3/JRaO7wQhSWkg=",
+		"256,1," + chainCode + ",QdvG4kO/shIZP3/JRaO7wQhSWkg=",
+		"1,-1," + chainCode + ",QdvG4kO/shIZP3/JRaO7wQhSWkg=",
+		"0,0,AAAA,QdvG4kO/shIZP3/JRaO7wQhSWkg=",
+		"0,0," + chainCode + ",AAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+		"0,0," + chainCode + ",QdvG",
+	} {
+		_, err := ParseExtendedPrivKey(s)
+		require.Error(t, err, s)
+	}
+	for _, s := range []string{
+		"0,0," + chainCode + ",AA==",
+		"0,0," + chainCode + ",QdvG4kO/shIZP3/JRaO7wQhSWkg=",
+	} {
+		_, err := ParseExtendedPubKey(s)
+		require.Error(t, err, s)
+	}
+}
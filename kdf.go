@@ -0,0 +1,402 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/LambdaIM/proofDP/math"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
+)
+
+// constant
+const (
+	errDerivePrivateParamFmt        = "Failed to derive PrivateParams: %s"
+	errDeriveSignPrivKeyFmt         = "Failed to derive SignPrivKey: %s"
+	errParseKeyDerivationParamsFmt  = "Failed to restore KeyDerivationParams: %s"
+	errNewKeyDerivationParamsFmt    = "Failed to create KeyDerivationParams: %s"
+	errInvalidKDF                   = "invalid KDF"
+	errInvalidSalt                  = "salt too short"
+	errInvalidScryptParams          = "invalid scrypt parameters"
+	errInvalidArgon2idParams        = "invalid Argon2id parameters"
+	errInvalidHKDFParams            = "invalid HKDF parameters"
+	errUnmatchedKeyDerivationParams = "unmatched parts num"
+
+	// the default cost params of scrypt
+	scryptN = 32768
+	scryptR = 8
+	scryptP = 1
+
+	// the max cost params accepted, which keep a stored record from taking
+	// too much memory or time to derive the key, i.e. 2 GiB at most for
+	// both scrypt, taking 128 * N * r bytes, & Argon2id, whose memory is in KiB
+	maxScryptNR     = 1 << 24
+	maxScryptP      = 16
+	maxArgon2idMem  = 2 << 20
+	maxArgon2idTime = 64
+
+	// the size of the random salts, & the least size of the salts of the
+	// password-based KDFs, i.e. scrypt & Argon2id
+	kdfSaltSize    = 16
+	kdfMinSaltSize = 8
+	// the size of the pseudorandom keys & the derived key materials
+	kdfKeySize = 32
+	maxInt     = int(^uint(0) >> 1)
+
+	// the usages of the derived keys, which are put ahead of the context
+	// strings in the info of HKDF-Expand
+	kdfUsagePrivateParams = "proofDP/PrivateParams/v1"
+	kdfUsageSignPrivKey   = "proofDP/SignPrivKey/v1"
+)
+
+// KDF identifies the key derivation function extracting the keys
+type KDF byte
+
+// the supported KDFs
+const (
+	// KDFScrypt is scrypt, with the cost params N, r & p
+	KDFScrypt KDF = iota + 1
+	// KDFArgon2id is Argon2id, with the cost params time, memory in KiB &
+	// threads
+	KDFArgon2id
+	// KDFHKDF is HKDF-Extract(SHA-256), which is for the secrets of high
+	// entropy only, e.g. the random master seeds, but never the passwords
+	KDFHKDF
+)
+
+var kdfNames = map[KDF]string{
+	KDFScrypt:   "scrypt",
+	KDFArgon2id: "argon2id",
+	KDFHKDF:     "hkdf",
+}
+
+// String returns the name of the KDF, e.g. "scrypt"
+func (k KDF) String() string {
+	if name, ok := kdfNames[k]; ok {
+		return name
+	}
+	return "KDF(" + strconv.Itoa(int(k)) + ")"
+}
+
+// parseKDF trys to restore a KDF from its name
+func parseKDF(s string) (KDF, error) {
+	for k, name := range kdfNames {
+		if name == s {
+			return k, nil
+		}
+	}
+	return 0, errors.New(errInvalidKDF)
+}
+
+// KeyDerivationParams holds the KDF, the salt & the context string, along
+// with the cost params of the KDF, that a key is derived by. The chosen KDF
+// extracts a pseudorandom key from the secret & the salt, which is then
+// expanded by HKDF-Expand(SHA-256) with the usage of the key & the context
+// string as the info, so the PrivateParams & the SignPrivKey derived by the
+// same params are independent. It holds no secret, thus is stored next to
+// the key to re-derive it later.
+type KeyDerivationParams struct {
+	kdf     KDF
+	salt    []byte
+	context string
+
+	// the cost params of scrypt
+	n, r, p int
+	// the cost params of Argon2id
+	time, memory uint32
+	threads      uint8
+}
+
+// NewKeyDerivationParams returns the KeyDerivationParams of scrypt with the
+// default cost params & a random salt, which is the way GeneratePrivateParams
+// & GenerateSignPrivKeyFromSecret derive the keys
+func NewKeyDerivationParams(context string) (*KeyDerivationParams, error) {
+	salt := make([]byte, kdfSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf(errNewKeyDerivationParamsFmt, err.Error())
+	}
+	return NewScryptParams(salt, context, scryptN, scryptR, scryptP)
+}
+
+// NewScryptParams returns the KeyDerivationParams of scrypt with the cost
+// params 'n', 'r' & 'p', where 'n' is a power of 2, n * r <= 2^24 & p <= 16.
+// The salt should be of at least 8 bytes.
+func NewScryptParams(salt []byte, context string, n, r, p int) (*KeyDerivationParams, error) {
+	kp := &KeyDerivationParams{
+		kdf:     KDFScrypt,
+		salt:    append([]byte{}, salt...),
+		context: context,
+		n:       n,
+		r:       r,
+		p:       p,
+	}
+	if err := kp.validate(); err != nil {
+		return nil, fmt.Errorf(errNewKeyDerivationParamsFmt, err.Error())
+	}
+	return kp, nil
+}
+
+// NewArgon2idParams returns the KeyDerivationParams of Argon2id with the
+// cost params 'time', 'memory' in KiB & 'threads', e.g. 1, 64*1024 & 4 as
+// RFC 9106 recommends, where 'time' <= 64 & 'memory' <= 2 GiB. The salt
+// should be of at least 8 bytes.
+func NewArgon2idParams(salt []byte, context string, time, memory uint32, threads uint8) (*KeyDerivationParams, error) {
+	kp := &KeyDerivationParams{
+		kdf:     KDFArgon2id,
+		salt:    append([]byte{}, salt...),
+		context: context,
+		time:    time,
+		memory:  memory,
+		threads: threads,
+	}
+	if err := kp.validate(); err != nil {
+		return nil, fmt.Errorf(errNewKeyDerivationParamsFmt, err.Error())
+	}
+	return kp, nil
+}
+
+// NewHKDFParams returns the KeyDerivationParams of HKDF, where the salt is
+// optional. The secret should be of high entropy, see KDFHKDF.
+func NewHKDFParams(salt []byte, context string) *KeyDerivationParams {
+	return &KeyDerivationParams{
+		kdf:     KDFHKDF,
+		salt:    append([]byte{}, salt...),
+		context: context,
+	}
+}
+
+// KDF returns the KDF of the params
+func (kp *KeyDerivationParams) KDF() KDF {
+	return kp.kdf
+}
+
+// Salt returns the salt of the params
+func (kp *KeyDerivationParams) Salt() []byte {
+	return append([]byte{}, kp.salt...)
+}
+
+// Context returns the context string of the params
+func (kp *KeyDerivationParams) Context() string {
+	return kp.context
+}
+
+// ScryptCost returns the cost params of scrypt, which are all 0 for the
+// other KDFs
+func (kp *KeyDerivationParams) ScryptCost() (n, r, p int) {
+	return kp.n, kp.r, kp.p
+}
+
+// Argon2idCost returns the cost params of Argon2id, which are all 0 for
+// the other KDFs
+func (kp *KeyDerivationParams) Argon2idCost() (time, memory uint32, threads uint8) {
+	return kp.time, kp.memory, kp.threads
+}
+
+func (kp *KeyDerivationParams) validate() error {
+	switch kp.kdf {
+	case KDFScrypt:
+		if len(kp.salt) < kdfMinSaltSize {
+			return errors.New(errInvalidSalt)
+		}
+		// the same limits that scrypt.Key checks, which are checked ahead
+		// of the derivation
+		n, r, p := kp.n, kp.r, kp.p
+		if n <= 1 || n&(n-1) != 0 || r <= 0 || p <= 0 {
+			return errors.New(errInvalidScryptParams)
+		}
+		if uint64(r)*uint64(p) >= 1<<30 || r > maxInt/128/p || r > maxInt/256 || n > maxInt/128/r {
+			return errors.New(errInvalidScryptParams)
+		}
+		if uint64(n)*uint64(r) > maxScryptNR || p > maxScryptP {
+			return errors.New(errInvalidScryptParams)
+		}
+	case KDFArgon2id:
+		if len(kp.salt) < kdfMinSaltSize {
+			return errors.New(errInvalidSalt)
+		}
+		// Argon2id takes at least 8 KiB per thread
+		if kp.time < 1 || kp.threads < 1 || kp.memory < 8*uint32(kp.threads) {
+			return errors.New(errInvalidArgon2idParams)
+		}
+		if kp.time > maxArgon2idTime || kp.memory > maxArgon2idMem {
+			return errors.New(errInvalidArgon2idParams)
+		}
+	case KDFHKDF:
+	default:
+		return errors.New(errInvalidKDF)
+	}
+	return nil
+}
+
+// deriveKey derives the key material of 'usage' from the secret
+func (kp *KeyDerivationParams) deriveKey(secret []byte, usage string) ([]byte, error) {
+	if err := kp.validate(); err != nil {
+		return nil, err
+	}
+
+	var prk []byte
+	switch kp.kdf {
+	case KDFScrypt:
+		var err error
+		prk, err = scrypt.Key(secret, kp.salt, kp.n, kp.r, kp.p, kdfKeySize)
+		if err != nil {
+			return nil, err
+		}
+	case KDFArgon2id:
+		prk = argon2.IDKey(secret, kp.salt, kp.time, kp.memory, kp.threads, kdfKeySize)
+	case KDFHKDF:
+		prk = hkdf.Extract(sha256.New, secret, kp.salt)
+	}
+
+	// the usages hold no zero byte, which keeps the info unambiguous
+	info := append([]byte(usage), 0)
+	info = append(info, kp.context...)
+	res := make([]byte, kdfKeySize)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, info), res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DerivePrivateParams returns the PrivateParams instance derived from the
+// secret by 'kp', which is always the same for the same secret & params
+func DerivePrivateParams(secret []byte, kp *KeyDerivationParams) (*PrivateParams, error) {
+	key, err := kp.deriveKey(secret, kdfUsagePrivateParams)
+	if err != nil {
+		return nil, fmt.Errorf(errDerivePrivateParamFmt, err.Error())
+	}
+
+	return &PrivateParams{
+		x: math.HashToGaloisElem(key),
+	}, nil
+}
+
+// DeriveSignPrivKey returns the SignPrivKey instance derived from the secret
+// by 'kp', which is always the same for the same secret & params
+func DeriveSignPrivKey(secret []byte, kp *KeyDerivationParams) (*SignPrivKey, error) {
+	key, err := kp.deriveKey(secret, kdfUsageSignPrivKey)
+	if err != nil {
+		return nil, fmt.Errorf(errDeriveSignPrivKeyFmt, err.Error())
+	}
+
+	return newSignPrivKey(math.HashToGaloisElem(key)), nil
+}
+
+// costs returns the cost params of the KDF, which are all 0 for HKDF
+func (kp *KeyDerivationParams) costs() [3]uint64 {
+	switch kp.kdf {
+	case KDFScrypt:
+		return [3]uint64{uint64(kp.n), uint64(kp.r), uint64(kp.p)}
+	case KDFArgon2id:
+		return [3]uint64{uint64(kp.time), uint64(kp.memory), uint64(kp.threads)}
+	}
+	return [3]uint64{}
+}
+
+// setCosts restores the cost params of the KDF, the values out of range are
+// left to validate
+func (kp *KeyDerivationParams) setCosts(costs [3]uint64) error {
+	switch kp.kdf {
+	case KDFScrypt:
+		for _, v := range costs {
+			if v > 1<<31-1 {
+				return errors.New(errInvalidScryptParams)
+			}
+		}
+		kp.n, kp.r, kp.p = int(costs[0]), int(costs[1]), int(costs[2])
+	case KDFArgon2id:
+		if costs[0] > 1<<32-1 || costs[1] > 1<<32-1 || costs[2] > 1<<8-1 {
+			return errors.New(errInvalidArgon2idParams)
+		}
+		kp.time, kp.memory, kp.threads = uint32(costs[0]), uint32(costs[1]), uint8(costs[2])
+	default:
+		if costs != [3]uint64{} {
+			return errors.New(errInvalidHKDFParams)
+		}
+	}
+	return nil
+}
+
+// Marshal works as the serialization routine, i.e. the KDF name, the base64
+// encoded salt & context string, followed by the cost params if any, e.g.
+// "scrypt,<salt>,<context>,32768,8,1"
+func (kp *KeyDerivationParams) Marshal() string {
+	res := fmt.Sprintf("%s,%s,%s", kp.kdf, base64.StdEncoding.EncodeToString(kp.salt),
+		base64.StdEncoding.EncodeToString([]byte(kp.context)))
+	if kp.kdf != KDFHKDF {
+		costs := kp.costs()
+		res += fmt.Sprintf(",%d,%d,%d", costs[0], costs[1], costs[2])
+	}
+	return res
+}
+
+// ParseKeyDerivationParams trys to restore a KeyDerivationParams instance,
+// an error is returned for the invalid params
+func ParseKeyDerivationParams(s string) (*KeyDerivationParams, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) < 3 {
+		return nil, fmt.Errorf(errParseKeyDerivationParamsFmt, errUnmatchedKeyDerivationParams)
+	}
+
+	kdf, err := parseKDF(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf(errParseKeyDerivationParamsFmt, err.Error())
+	}
+	if kdf == KDFHKDF && len(parts) != 3 || kdf != KDFHKDF && len(parts) != 6 {
+		return nil, fmt.Errorf(errParseKeyDerivationParamsFmt, errUnmatchedKeyDerivationParams)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf(errParseKeyDerivationParamsFmt, err.Error())
+	}
+	context, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf(errParseKeyDerivationParamsFmt, err.Error())
+	}
+
+	var costs [3]uint64
+	for i, part := range parts[3:] {
+		costs[i], err = strconv.ParseUint(part, intStrRadix, 32)
+		if err != nil {
+			return nil, fmt.Errorf(errParseKeyDerivationParamsFmt, err.Error())
+		}
+	}
+
+	kp := &KeyDerivationParams{
+		kdf:     kdf,
+		salt:    salt,
+		context: string(context),
+	}
+	if err := kp.setCosts(costs); err != nil {
+		return nil, fmt.Errorf(errParseKeyDerivationParamsFmt, err.Error())
+	}
+	if err := kp.validate(); err != nil {
+		return nil, fmt.Errorf(errParseKeyDerivationParamsFmt, err.Error())
+	}
+	return kp, nil
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// the low-cost params for test purpose
+func getTestKeyDerivationParams(t *testing.T, salt []byte, context string) []*KeyDerivationParams {
+	scryptParams, err := NewScryptParams(salt, context, 1024, 8, 1)
+	require.NoError(t, err)
+	argon2idParams, err := NewArgon2idParams(salt, context, 1, 64, 1)
+	require.NoError(t, err)
+	return []*KeyDerivationParams{scryptParams, argon2idParams, NewHKDFParams(salt, context)}
+}
+
+func TestKeyDerivation(t *testing.T) {
+	secret := getRandSecret()
+	salt := []byte("proofDP test salt")
+	others := append(getTestKeyDerivationParams(t, []byte("another test salt"), "tenant-1"),
+		getTestKeyDerivationParams(t, salt, "tenant-2")...)
+
+	for _, kp := range getTestKeyDerivationParams(t, salt, "tenant-1") {
+		sp, err := DerivePrivateParams(secret, kp)
+		require.NoError(t, err)
+		sk, err := DeriveSignPrivKey(secret, kp)
+		require.NoError(t, err)
+		require.NotEqual(t, sp.Marshal(), sk.key.Marshal(), kp.KDF())
+
+		// the same key re-derived from the stored params
+		restored, err := ParseKeyDerivationParams(kp.Marshal())
+		require.NoError(t, err)
+		require.Equal(t, kp, restored)
+		res, err := DerivePrivateParams(secret, restored)
+		require.NoError(t, err)
+		require.Equal(t, sp.Marshal(), res.Marshal(), kp.KDF())
+		resSK, err := DeriveSignPrivKey(secret, restored)
+		require.NoError(t, err)
+		require.Equal(t, sk.key.Marshal(), resSK.key.Marshal(), kp.KDF())
+
+		// but not from another secret, salt, context or KDF
+		res, err = DerivePrivateParams(getRandSecret(), kp)
+		require.NoError(t, err)
+		require.NotEqual(t, sp.Marshal(), res.Marshal(), kp.KDF())
+		for _, other := range others {
+			res, err = DerivePrivateParams(secret, other)
+			require.NoError(t, err)
+			require.NotEqual(t, sp.Marshal(), res.Marshal(), other.Marshal())
+		}
+	}
+
+	// the random salts differ every time
+	kp, err := NewKeyDerivationParams("tenant-1")
+	require.NoError(t, err)
+	another, err := NewKeyDerivationParams("tenant-1")
+	require.NoError(t, err)
+	require.Equal(t, KDFScrypt, kp.KDF())
+	require.Equal(t, "tenant-1", kp.Context())
+	require.Len(t, kp.Salt(), kdfSaltSize)
+	require.NotEqual(t, kp.Salt(), another.Salt())
+	n, r, p := kp.ScryptCost()
+	require.Equal(t, [3]int{scryptN, scryptR, scryptP}, [3]int{n, r, p})
+}
+
+func TestKeyDerivationVectors(t *testing.T) {
+	secret := []byte("proofDP test master seed")
+	expected := []struct {
+		params, x, key string
+	}{
+		{
+			"scrypt,cHJvb2ZEUCB0ZXN0IHNhbHQ=,dGVuYW50LTE=,1024,8,1",
+			"SGuSgZl0+fw2kHQzLozv2rzVaJs=",
+			"Rto/ZX6WCzl+SbiQ0jK2NA4/LLE=",
+		},
+		{
+			"argon2id,cHJvb2ZEUCB0ZXN0IHNhbHQ=,dGVuYW50LTE=,1,64,1",
+			"TS14apLzA5CoGDkxI2BVCs59SY8=",
+			"FFItAMCGNqEy5WoXtTbequrPvJE=",
+		},
+		{
+			"hkdf,cHJvb2ZEUCB0ZXN0IHNhbHQ=,dGVuYW50LTE=",
+			"bsSuS/CbsfS8nV5u5fIb7OV9H3A=",
+			"KN1AWZGe4if5nI8LLS4ksnuupe8=",
+		},
+	}
+
+	params := getTestKeyDerivationParams(t, []byte("proofDP test salt"), "tenant-1")
+	for i, e := range expected {
+		require.Equal(t, e.params, params[i].Marshal())
+		sp, err := DerivePrivateParams(secret, params[i])
+		require.NoError(t, err)
+		require.Equal(t, e.x, sp.Marshal(), e.params)
+		sk, err := DeriveSignPrivKey(secret, params[i])
+		require.NoError(t, err)
+		require.Equal(t, e.key, sk.key.Marshal(), e.params)
+	}
+}
+
+func TestKeyDerivationParamsEncoding(t *testing.T) {
+	for _, kp := range getTestKeyDerivationParams(t, []byte("proofDP test salt"), "tenant,1") {
+		var restored KeyDerivationParams
+		roundTrip(t, kp, &restored)
+		require.Equal(t, *kp, restored)
+
+		data, err := json.Marshal(kp)
+		require.NoError(t, err)
+		restored = KeyDerivationParams{}
+		require.NoError(t, json.Unmarshal(data, &restored))
+		require.Equal(t, *kp, restored)
+
+		text, err := kp.MarshalText()
+		require.NoError(t, err)
+		restored = KeyDerivationParams{}
+		require.NoError(t, restored.UnmarshalText(text))
+		require.Equal(t, *kp, restored)
+
+		var buf bytes.Buffer
+		require.NoError(t, gob.NewEncoder(&buf).Encode(kp))
+		restored = KeyDerivationParams{}
+		require.NoError(t, gob.NewDecoder(&buf).Decode(&restored))
+		require.Equal(t, *kp, restored)
+	}
+
+	// the costs of HKDF are all 0
+	var res KeyDerivationParams
+	require.Error(t, json.Unmarshal([]byte(`{"kdf":"hkdf","salt":"","context":"","costs":[1,0,0]}`), &res))
+	require.NoError(t, json.Unmarshal([]byte(`{"kdf":"hkdf","salt":"","context":"","costs":[0,0,0]}`), &res))
+	require.Error(t, json.Unmarshal([]byte(`{"kdf":"md5","salt":"","context":"","costs":[0,0,0]}`), &res))
+}
+
+func TestKeyDerivationParamsInvalid(t *testing.T) {
+	salt := []byte("proofDP test salt")
+	for _, c := range [][3]int{{0, 8, 1}, {1, 8, 1}, {1000, 8, 1}, {1024, 0, 1}, {1024, 8, 0}, {1024, 1 << 15, 1 << 15},
+		{1 << 22, 8, 1}, {1024, 8, maxScryptP + 1}} {
+		_, err := NewScryptParams(salt, "", c[0], c[1], c[2])
+		require.Error(t, err, c)
+	}
+	_, err := NewScryptParams(salt[:kdfMinSaltSize-1], "", 1024, 8, 1)
+	require.Error(t, err)
+
+	for _, c := range [][3]uint32{{0, 64, 1}, {1, 64, 0}, {1, 7, 1}, {1, 16, 4}, {1, maxArgon2idMem + 1, 1}, {maxArgon2idTime + 1, 64, 1}} {
+		_, err := NewArgon2idParams(salt, "", c[0], c[1], uint8(c[2]))
+		require.Error(t, err, c)
+	}
+	_, err = NewArgon2idParams(nil, "", 1, 64, 1)
+	require.Error(t, err)
+
+	// the invalid KeyDerivationParams never derives a key
+	kp := &KeyDerivationParams{kdf: KDFScrypt, salt: salt}
+	_, err = DerivePrivateParams(getRandSecret(), kp)
+	require.Error(t, err)
+	_, err = DeriveSignPrivKey(getRandSecret(), kp)
+	require.Error(t, err)
+
+	for _, s := range []string{
+		"",
+		"hkdf,",
+		"hkdf,,,0,0,0",
+		"md5,,",
+		"hkdf,!,",
+		"hkdf,,!",
+		"scrypt,cHJvb2ZEUCB0ZXN0IHNhbHQ=,",
+		"scrypt,cHJvb2ZEUCB0ZXN0IHNhbHQ=,,1024,8",
+		"scrypt,cHJvb2ZEUCB0ZXN0IHNhbHQ=,,1024,8,-1",
+		"scrypt,cHJvb2ZEUCB0ZXN0IHNhbHQ=,,1023,8,1",
+		"scrypt,,,1024,8,1",
+		"argon2id,cHJvb2ZEUCB0ZXN0IHNhbHQ=,,1,64,256",
+		"argon2id,cHJvb2ZEUCB0ZXN0IHNhbHQ=,,1,4294967296,1",
+		// the oversized records, e.g. a corrupted one, never get to derive
+		"scrypt,cHJvb2ZEUCB0ZXN0IHNhbHQ=,,1073741824,8,1",
+		"scrypt,cHJvb2ZEUCB0ZXN0IHNhbHQ=,,16777216,8388607,1",
+		"argon2id,cHJvb2ZEUCB0ZXN0IHNhbHQ=,,1,4294967295,1",
+		"argon2id,cHJvb2ZEUCB0ZXN0IHNhbHQ=,,4294967295,64,1",
+	} {
+		_, err := ParseKeyDerivationParams(s)
+		require.Error(t, err, s)
+	}
+	// an empty salt is fine for HKDF
+	_, err = ParseKeyDerivationParams("hkdf,,")
+	require.NoError(t, err)
+}
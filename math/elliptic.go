@@ -20,6 +20,7 @@ package math
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"math/big"
 )
@@ -33,12 +34,24 @@ const (
 	errInitCurveParamFmt   = "Failed to initalized the required elliptic curve parameters: %s"
 	errInitCurveProperties = "Failed to initalized the key parameters of the elliptic curve"
 	errInvalidCurvePoint   = "Failed to locate point on elliptic curve"
+
+	// parse errors
+	errInvalidPointLen    = "Invalid length of elliptic curve point data"
+	errInvalidPointFlag   = "Invalid flag byte of elliptic curve point data"
+	errPointNotInSubgroup = "Elliptic curve point not in the subgroup of order r"
+
+	// the flag bytes leading the encoded points
+	ptFlagInfinity     = 0x00
+	ptFlagCompressed   = 0x02 // | parity of y
+	ptFlagUncompressed = 0x04
 )
 
 // psuedo-constant
 var (
 	gen   *curP
 	coFac *big.Int
+	// the fixed-base table of 'gen'
+	genBase *fixedBase
 )
 
 // package level init(): initialize the co-factor constant
@@ -56,6 +69,7 @@ func initElliptic() {
 	if !done {
 		panic(fmt.Errorf(errInitCurveParamFmt, "Cannot load cofactor"))
 	}
+	genBase = newFixedBase(gen, gFR.ord.BitLen(), gFR.ord)
 }
 
 type curP struct {
@@ -151,7 +165,110 @@ func (p *curP) setBytes(data []byte) *curP {
 	return p
 }
 
-// for test purpose only
+// trySetBytes restores the point from the untrusted data, which is rejected
+// if of wrong length, out of range, not on the curve or, with 'checkSubgroup',
+// not in the subgroup of order r. 'p' is left unchanged on error.
+// The data is told apart by its length & leading flag byte:
+//
+//	0x00             : the infinity point
+//	0x02 | y%2, x    : the compressed point
+//	0x04, x, y       : the uncompressed point
+//	x, y             : the legacy uncompressed point, without the flag byte
+//	empty            : the legacy infinity point
+func (p *curP) trySetBytes(data []byte, checkSubgroup bool) error {
+	l := lenInByte(gFQ.ord)
+	a := newCurP()
+	switch {
+	case len(data) == 0:
+		a.inf = true
+	case len(data) == 1:
+		if data[0] != ptFlagInfinity {
+			return errors.New(errInvalidPointFlag)
+		}
+		a.inf = true
+	case len(data) == 1+l:
+		if data[0]&^1 != ptFlagCompressed {
+			return errors.New(errInvalidPointFlag)
+		}
+		if err := a.trySetX(data[1:], uint(data[0]&1)); err != nil {
+			return err
+		}
+	case len(data) == 1+2*l:
+		if data[0] != ptFlagUncompressed {
+			return errors.New(errInvalidPointFlag)
+		}
+		if err := a.trySetXY(data[1:]); err != nil {
+			return err
+		}
+	case len(data) == 2*l:
+		if err := a.trySetXY(data); err != nil {
+			return err
+		}
+	default:
+		return errors.New(errInvalidPointLen)
+	}
+
+	if checkSubgroup && !a.inSubgroup() {
+		return errors.New(errPointNotInSubgroup)
+	}
+	p.set(a)
+	return nil
+}
+
+// trySetXY restores the point from x || y
+func (p *curP) trySetXY(data []byte) error {
+	l := len(data) / 2
+	p.inf = false
+	if err := p.x.trySetBytes(data[:l]); err != nil {
+		return err
+	}
+	if err := p.y.trySetBytes(data[l:]); err != nil {
+		return err
+	}
+	if !validateCurP(p) {
+		return errors.New(errInvalidCurvePoint)
+	}
+	return nil
+}
+
+// trySetX restores the point from x & the parity of y, where y is the
+// square root of x^3 + x
+func (p *curP) trySetX(data []byte, parity uint) error {
+	p.inf = false
+	if err := p.x.trySetBytes(data); err != nil {
+		return err
+	}
+	rhs := newGalE(gFQ).powI(p.x, 3)
+	rhs.add(rhs, p.x)
+	if p.y.sqrt(rhs) == nil {
+		return errors.New(errInvalidCurvePoint)
+	}
+	if p.y.bigInt().Bit(0) != parity {
+		// y == 0 has no odd counterpart
+		if p.y.sign() == 0 {
+			return errors.New(errInvalidCurvePoint)
+		}
+		p.y.neg(p.y)
+	}
+	return nil
+}
+
+// compressedBytes encodes the point as 0x02 | y%2, x, or 0x00 for the
+// infinity point
+func (p *curP) compressedBytes() []byte {
+	if p.inf {
+		return []byte{ptFlagInfinity}
+	}
+	flag := byte(ptFlagCompressed) | byte(p.y.bigInt().Bit(0))
+	return append([]byte{flag}, p.x.bytes()...)
+}
+
+// inSubgroup validates if p^r is the infinity point
+func (p *curP) inSubgroup() bool {
+	return p.inf || powNJacobian(p, gFR.ord).isInf()
+}
+
+// bytes returns the legacy encoding x || y, empty for the infinity point
 func (p *curP) bytes() []byte {
 	if p.inf {
 		return []byte{}
@@ -182,7 +299,7 @@ func (p *curP) doubleNormal(a *curP) *curP {
 
 func (p *curP) double(a *curP) *curP {
 	// infinity | on-x-axis
-	if a.inf || a.y.val.Cmp(intZero) == 0 {
+	if a.inf || a.y.sign() == 0 {
 		p.inf = true
 		return p
 	}
@@ -205,7 +322,7 @@ func (p *curP) add(lhs, rhs *curP) *curP {
 		// lhs.y == rhs.y
 		if lhs.y.equal(rhs.y) {
 			// lhs.y == rhs.y == 0 -> the sum is infinity
-			if lhs.y.val.Cmp(intZero) == 0 {
+			if lhs.y.sign() == 0 {
 				p.inf = true
 				return p
 			}
@@ -245,8 +362,15 @@ func (p *curP) sqr(a *curP) *curP {
 	return p.double(a)
 }
 
-// TODO: optimize this
+// powN calculates a^n in Jacobian coordinates with the w-NAF recoding of
+// 'n', which takes only one field inversion at the end
 func (p *curP) powN(a *curP, n *big.Int) *curP {
+	return powNJacobian(a, n).toAffine(p)
+}
+
+// powNAffine is the affine double-and-add version of powN, which takes a
+// field inversion on every step. It is kept as the reference of powN.
+func (p *curP) powNAffine(a *curP, n *big.Int) *curP {
 	exp := new(big.Int).Set(n)
 	tmp := newCurP().set(a)
 	res := newCurIdentity()
@@ -117,9 +117,233 @@ func TestEllipticPowN(t *testing.T) {
 		b, err := randGalE(gFR)
 		assert.NoError(t, err)
 
-		gPowA := newCurP().powN(gen, a.val)
-		gPowB := newCurP().powN(gen, b.val)
+		gPowA := newCurP().powN(gen, a.bigInt())
+		gPowB := newCurP().powN(gen, b.bigInt())
 
-		assert.True(t, gPowA.powN(gPowA, b.val).equal(gPowB.powN(gPowB, a.val)))
+		assert.True(t, gPowA.powN(gPowA, b.bigInt()).equal(gPowB.powN(gPowB, a.bigInt())))
 	}
 }
+
+func TestEllipticNeg(t *testing.T) {
+	for i := 0; i < ellipticTestRound; i++ {
+		p, err := RandEllipticPt()
+		assert.NoError(t, err)
+
+		negP := EllipticNeg(p)
+		assert.True(t, validateCurP(negP.v))
+		assert.True(t, EllipticMul(p, negP).v.inf)
+		assert.True(t, EllipticNeg(negP).v.equal(p.v))
+
+		// e(p, q) * e(-p, q) == 1
+		q, err := RandEllipticPt()
+		assert.NoError(t, err)
+		prd := QuadraticMul(BiLinearMap(p, q), BiLinearMap(negP, q))
+		assert.True(t, QuadraticEqual(prd, QuadraticIdentity()))
+	}
+	assert.True(t, EllipticNeg(EllipticPoint{v: newCurIdentity()}).v.inf)
+}
+
+// curPointBytes encodes (x, y) without any check
+func curPointBytes(x, y *big.Int) []byte {
+	return append(newGalE(gFQ).setV(x).bytes(), newGalE(gFQ).setV(y).bytes()...)
+}
+
+// randCurPOffSubgroup returns a point of the curve outside the subgroup of
+// order r, i.e. without clearing the co-factor
+func randCurPOffSubgroup() *curP {
+	for {
+		x, err := randGalE(gFQ)
+		if err != nil {
+			continue
+		}
+		y := newGalE(gFQ).powI(x, 3)
+		y.add(y, x)
+		if y = newGalE(gFQ).sqrt(y); y != nil {
+			return &curP{x: x, y: y}
+		}
+	}
+}
+
+func TestParseEllipticPt(t *testing.T) {
+	p, err := RandEllipticPt()
+	assert.NoError(t, err)
+	res, err := ParseEllipticPt(p.Marshal())
+	assert.NoError(t, err)
+	assert.True(t, res.v.equal(p.v))
+
+	valid := p.v.bytes()
+	offSubgroup := randCurPOffSubgroup()
+	assert.False(t, offSubgroup.inSubgroup())
+	invalids := map[string][]byte{
+		"short":           valid[:len(valid)-1],
+		"long":            append(append([]byte{}, valid...), 0),
+		"x out of range":  append(gFQ.ord.Bytes(), valid[len(valid)/2:]...),
+		"y out of range":  append(append([]byte{}, valid[:len(valid)/2]...), gFQ.ord.Bytes()...),
+		"off curve":       curPointBytes(intOne, intOne),
+		"order 2":         curPointBytes(intZero, intZero),
+		"off subgroup":    offSubgroup.bytes(),
+		"off subgroup -y": newCurP().neg(offSubgroup).bytes(),
+	}
+	for name, data := range invalids {
+		_, err := ParseEllipticPt(toBase64Str(data))
+		assert.Error(t, err, name)
+	}
+	_, err = ParseEllipticPt("not base64")
+	assert.Error(t, err)
+}
+
+// lowOrderCurP returns a point whose order is a power of 2, by clearing the
+// odd part of the order of a random curve point
+func lowOrderCurP() *curP {
+	odd := new(big.Int).Set(coFac)
+	for odd.Bit(0) == 0 {
+		odd.Rsh(odd, 1)
+	}
+	odd.Mul(odd, gFR.ord)
+	for {
+		if p := newCurP().powN(randCurPOffSubgroup(), odd); !p.inf {
+			return p
+		}
+	}
+}
+
+func TestInSubgroup(t *testing.T) {
+	for i := 0; i < ellipticTestRound/8; i++ {
+		p, err := RandEllipticPt()
+		assert.NoError(t, err)
+		assert.True(t, p.InSubgroup())
+		h := HashToEllipticPt([]byte{byte(i)})
+		assert.True(t, h.InSubgroup())
+
+		for _, a := range []*curP{
+			randCurPOffSubgroup(),
+			lowOrderCurP(),
+			// a point of the subgroup shifted by a low order point
+			newCurP().add(p.v, lowOrderCurP()),
+			{x: newGalZero(gFQ), y: newGalZero(gFQ)},
+		} {
+			low := EllipticPoint{v: a}
+			assert.False(t, low.InSubgroup())
+			_, err := ParseEllipticPt(low.Marshal())
+			assert.Error(t, err)
+			// the trusted parsing skips the subgroup check only
+			res, err := ParseEllipticPtTrusted(low.Marshal())
+			assert.NoError(t, err)
+			assert.True(t, res.v.equal(a))
+		}
+	}
+	g := GetGenerator()
+	assert.True(t, g.InSubgroup())
+	inf := EllipticPoint{v: newCurIdentity()}
+	assert.True(t, inf.InSubgroup())
+
+	_, err := ParseEllipticPtTrusted(toBase64Str(curPointBytes(intOne, intOne)))
+	assert.Error(t, err)
+}
+
+func TestCompressedEllipticPt(t *testing.T) {
+	l := lenInByte(gFQ.ord)
+	for i := 0; i < ellipticTestRound; i++ {
+		p, err := RandEllipticPt()
+		assert.NoError(t, err)
+
+		assert.False(t, p.IsInfinity())
+		compressed := p.CompressedBytes()
+		assert.Equal(t, 1+l, len(compressed))
+		uncompressed := p.UncompressedBytes()
+		assert.Equal(t, 1+2*l, len(uncompressed))
+
+		// all the encodings are restored to the same point
+		// Bytes() keeps the legacy x || y encoding
+		assert.Equal(t, p.v.bytes(), p.Bytes())
+		for _, data := range [][]byte{compressed, uncompressed, p.Bytes()} {
+			res, err := ParseEllipticPt(toBase64Str(data))
+			assert.NoError(t, err)
+			assert.True(t, res.Equal(p))
+		}
+
+		// the flipped parity results in the negative point
+		compressed[0] ^= 1
+		res, err := ParseEllipticPt(toBase64Str(compressed))
+		assert.NoError(t, err)
+		assert.True(t, res.v.equal(newCurP().neg(p.v)))
+		assert.False(t, res.Equal(p))
+
+		for _, flag := range []byte{0x01, 0x04, 0x05, 0xff} {
+			compressed[0] = flag
+			_, err := ParseEllipticPt(toBase64Str(compressed))
+			assert.Error(t, err)
+		}
+		uncompressed[0] = ptFlagCompressed
+		_, err = ParseEllipticPt(toBase64Str(uncompressed))
+		assert.Error(t, err)
+	}
+
+	inf := EllipticPoint{v: newCurIdentity()}
+	assert.True(t, inf.IsInfinity())
+	assert.True(t, inf.Equal(EllipticPoint{v: newCurIdentity()}))
+	assert.False(t, inf.Equal(GetGenerator()))
+	for _, data := range [][]byte{inf.CompressedBytes(), inf.UncompressedBytes(), inf.Bytes()} {
+		res, err := ParseEllipticPt(toBase64Str(data))
+		assert.NoError(t, err)
+		assert.True(t, res.v.inf)
+	}
+	_, err := ParseEllipticPt(toBase64Str([]byte{0x01}))
+	assert.Error(t, err)
+
+	// x of no point on the curve, & the odd flag of y == 0
+	x := big.NewInt(1)
+	for {
+		rhs := newGalE(gFQ).setV(x)
+		rhs.mul(rhs, newGalE(gFQ).sqr(rhs))
+		rhs.add(rhs, newGalE(gFQ).setV(x))
+		if !rhs.isSqr() {
+			break
+		}
+		x.Add(x, intOne)
+	}
+	noPoint := append([]byte{ptFlagCompressed}, newGalE(gFQ).setV(x).bytes()...)
+	_, err = ParseEllipticPtTrusted(toBase64Str(noPoint))
+	assert.Error(t, err)
+	oddZero := append([]byte{ptFlagCompressed | 1}, make([]byte, l)...)
+	_, err = ParseEllipticPtTrusted(toBase64Str(oddZero))
+	assert.Error(t, err)
+	evenZero := append([]byte{ptFlagCompressed}, make([]byte, l)...)
+	res, err := ParseEllipticPtTrusted(toBase64Str(evenZero))
+	assert.NoError(t, err)
+	assert.True(t, res.v.equal(&curP{x: newGalZero(gFQ), y: newGalZero(gFQ)}))
+}
+
+func TestEllipticPtBinary(t *testing.T) {
+	assert.Equal(t, 1+lenInByte(gFQ.ord), EllipticPointSize)
+	for i := 0; i < ellipticTestRound; i++ {
+		p, err := RandEllipticPt()
+		assert.NoError(t, err)
+
+		data, err := p.MarshalBinary()
+		assert.NoError(t, err)
+		assert.Equal(t, EllipticPointSize, len(data))
+		var res EllipticPoint
+		assert.NoError(t, res.UnmarshalBinary(data))
+		assert.True(t, res.v.equal(p.v))
+
+		// only the fixed-length compressed encoding is accepted
+		assert.Error(t, res.UnmarshalBinary(p.UncompressedBytes()))
+		assert.Error(t, res.UnmarshalBinary(data[:EllipticPointSize-1]))
+	}
+
+	inf := EllipticPoint{v: newCurIdentity()}
+	data, err := inf.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Equal(t, EllipticPointSize, len(data))
+	var res EllipticPoint
+	assert.NoError(t, res.UnmarshalBinary(data))
+	assert.True(t, res.v.inf)
+	data[EllipticPointSize-1] = 1
+	assert.Error(t, res.UnmarshalBinary(data))
+
+	low := EllipticPoint{v: lowOrderCurP()}
+	data, err = low.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Error(t, res.UnmarshalBinary(data))
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package math
+
+import (
+	"math/big"
+)
+
+// the window width of the fixed-base tables
+const fixedBaseWidth = 4
+
+// fixedBase holds the precomputed multiples of a fixed point P for the
+// fixed-window exponentiation: table[i][j-1] = j * 2^(w*i) * P for every
+// window 'i' & 0 < j < 2^w. The points are kept in affine coordinates so
+// that the exponentiation takes mixed additions only, without any doubling.
+type fixedBase struct {
+	table [][]*curP
+	// the max bit length of the exponents covered by the table
+	bits int
+	// order of P if known, the larger exponents are reduced by it
+	ord *big.Int
+}
+
+// newFixedBase builds the table of 'a' for the exponents up to 'bits' bits
+func newFixedBase(a *curP, bits int, ord *big.Int) *fixedBase {
+	windows := (bits + fixedBaseWidth - 1) / fixedBaseWidth
+	entries := 1<<fixedBaseWidth - 1
+
+	// build all the multiples in Jacobian coordinates first
+	jacs := make([]*jacP, 0, windows*entries)
+	base := newJacP().setAffine(a)
+	for i := 0; i < windows; i++ {
+		cur := newJacP().set(base)
+		for j := 0; j < entries; j++ {
+			jacs = append(jacs, newJacP().set(cur))
+			cur.add(cur, base)
+		}
+		// cur == 2^w * base now
+		base.set(cur)
+	}
+
+	affines := batchToAffine(jacs)
+	table := make([][]*curP, windows)
+	for i := range table {
+		table[i] = affines[i*entries : (i+1)*entries]
+	}
+	return &fixedBase{
+		table: table,
+		bits:  windows * fixedBaseWidth,
+		ord:   ord,
+	}
+}
+
+// batchToAffine converts the points to affine coordinates with one single
+// field inversion, by Montgomery's trick
+func batchToAffine(ps []*jacP) []*curP {
+	res := make([]*curP, len(ps))
+	// prds[i] = Prod(Z_k), k <= i, skipping the infinity points
+	prds := make([]*galE, len(ps))
+	acc := newGalOne(gFQ)
+	for i, p := range ps {
+		if !p.isInf() {
+			acc.mul(acc, p.z)
+		}
+		prds[i] = newGalE(gFQ).set(acc)
+	}
+
+	inv := newGalE(gFQ).inv(acc)
+	zInv := newGalE(gFQ)
+	zInvSqr := newGalE(gFQ)
+	for i := len(ps) - 1; i >= 0; i-- {
+		res[i] = newCurP()
+		if ps[i].isInf() {
+			continue
+		}
+		// 1/Z_i = Prod(Z_k, k < i) / Prod(Z_k, k <= i)
+		if i > 0 {
+			zInv.mul(inv, prds[i-1])
+		} else {
+			zInv.set(inv)
+		}
+		inv.mul(inv, ps[i].z)
+
+		zInvSqr.sqr(zInv)
+		res[i].inf = false
+		res[i].x.mul(ps[i].x, zInvSqr)
+		zInvSqr.mul(zInvSqr, zInv)
+		res[i].y.mul(ps[i].y, zInvSqr)
+	}
+	return res
+}
+
+// pow calculates P^n using the table, the exponents beyond the table go
+// through the ordinary powN
+func (fb *fixedBase) pow(p *curP, n *big.Int) *curP {
+	if n.Sign() <= 0 {
+		p.inf = true
+		return p
+	}
+	if n.BitLen() > fb.bits && fb.ord != nil {
+		n = new(big.Int).Mod(n, fb.ord)
+	}
+	if n.BitLen() > fb.bits {
+		return p.powN(fb.table[0][0], n)
+	}
+
+	res := newJacP()
+	for i := range fb.table {
+		j := 0
+		for k := fixedBaseWidth - 1; k >= 0; k-- {
+			j = j<<1 | int(n.Bit(i*fixedBaseWidth+k))
+		}
+		if j != 0 {
+			res.addAffine(res, fb.table[i][j-1])
+		}
+	}
+	return res.toAffine(p)
+}
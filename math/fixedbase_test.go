@@ -0,0 +1,101 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package math
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixedBasePow(t *testing.T) {
+	a, err := RandEllipticPt()
+	assert.NoError(t, err)
+	fb := NewFixedBase(a)
+
+	for i := 0; i < ellipticTestRound; i++ {
+		x, err := RandGaloisElem()
+		assert.NoError(t, err)
+
+		assert.True(t, fb.Pow(x).v.equal(newCurP().powN(a.v, x.v.bigInt())))
+		assert.True(t, EllipticPow(GetGenerator(), x).v.equal(newCurP().powN(gen, x.v.bigInt())))
+	}
+
+	edges := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(15),
+		big.NewInt(16),
+		new(big.Int).Sub(gFR.ord, intOne),
+		new(big.Int).Lsh(intOne, uint(genBase.bits)),
+	}
+	for i := 0; i < ellipticTestRound/4; i++ {
+		n, err := rand.Int(rand.Reader, gFQ.ord)
+		assert.NoError(t, err)
+		edges = append(edges, n)
+	}
+	for _, n := range edges {
+		assert.True(t, fb.v.pow(newCurP(), n).equal(newCurP().powN(a.v, n)))
+		assert.True(t, genBase.pow(newCurP(), n).equal(newCurP().powN(gen, n)))
+	}
+
+	inf := newFixedBase(newCurIdentity(), 8, nil)
+	assert.True(t, inf.pow(newCurP(), big.NewInt(7)).inf)
+}
+
+func TestBatchToAffine(t *testing.T) {
+	ps := []*jacP{}
+	expected := []*curP{}
+	for i := 0; i < 8; i++ {
+		a, err := randCurP()
+		assert.NoError(t, err)
+		// a non-trivial Z
+		p := newJacP().double(newJacP().setAffine(a))
+		ps = append(ps, p, newJacP())
+		expected = append(expected, newCurP().double(a), newCurIdentity())
+	}
+
+	for i, p := range batchToAffine(ps) {
+		assert.True(t, p.equal(expected[i]))
+	}
+}
+
+func BenchmarkNewFixedBase(b *testing.B) {
+	a, err := RandEllipticPt()
+	assert.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewFixedBase(a)
+	}
+}
+
+func BenchmarkFixedBasePow(b *testing.B) {
+	a, err := RandEllipticPt()
+	assert.NoError(b, err)
+	x, err := RandGaloisElem()
+	assert.NoError(b, err)
+	fb := NewFixedBase(a)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fb.Pow(x)
+	}
+}
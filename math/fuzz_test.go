@@ -0,0 +1,96 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build go1.18
+// +build go1.18
+
+package math
+
+import (
+	"testing"
+)
+
+// the fuzz tests make sure that the parsers never panic on untrusted input,
+// & whatever they accept is restored by marshaling it again
+
+func FuzzParseGaloisElem(f *testing.F) {
+	x, err := RandGaloisElem()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(x.Bytes())
+	f.Add([]byte{})
+	f.Add(gFR.ord.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		e, err := ParseGaloisElem(toBase64Str(data))
+		if err != nil {
+			return
+		}
+		res, err := ParseGaloisElem(e.Marshal())
+		if err != nil || !res.Equal(e) {
+			t.Fatalf("failed to restore %x", data)
+		}
+	})
+}
+
+func FuzzParseEllipticPt(f *testing.F) {
+	p, err := RandEllipticPt()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(p.CompressedBytes())
+	f.Add(p.UncompressedBytes())
+	f.Add(p.Bytes())
+	f.Add([]byte{})
+	f.Add([]byte{ptFlagInfinity})
+	f.Add(randCurPOffSubgroup().bytes())
+	f.Add(curPointBytes(intZero, intZero))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p, err := ParseEllipticPt(toBase64Str(data))
+		if err != nil {
+			return
+		}
+		if !validateCurP(p.v) || !p.v.inSubgroup() {
+			t.Fatalf("invalid point accepted %x", data)
+		}
+		res, err := ParseEllipticPt(p.Marshal())
+		if err != nil || !res.v.equal(p.v) {
+			t.Fatalf("failed to restore %x", data)
+		}
+	})
+}
+
+func FuzzParseQuadraticElem(f *testing.F) {
+	e := BiLinearMap(GetGenerator(), GetGenerator())
+	f.Add(e.CompressedBytes())
+	f.Add(e.UncompressedBytes())
+	f.Add(e.Bytes())
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		e, err := ParseQuadraticElem(toBase64Str(data))
+		if err != nil {
+			return
+		}
+		res, err := ParseQuadraticElem(e.Marshal())
+		if err != nil || !QuadraticEqual(res, e) {
+			t.Fatalf("failed to restore %x", data)
+		}
+	})
+}
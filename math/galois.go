@@ -19,6 +19,7 @@ package math
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"math/big"
 )
@@ -35,6 +36,12 @@ const (
 
 	errInitFieldOrder       = "Failed to initialized the required Galois fields with order %s"
 	errOperandsInDiffFields = "Arthimetic operation on elements from different fields"
+	errInitMontField        = "Failed to initialize the Montgomery arithmetic of the field"
+	errInvalidElemLen       = "Invalid length of Galois field element data"
+	errElemOutOfRange       = "Galois field element out of range"
+	errElemNotInGT          = "Quadratic field element not in the group of order r"
+	errInvalidElemFlag      = "Invalid flag byte of quadratic field element data"
+	errElemNotOfNorm1       = "Quadratic field element not of norm 1"
 )
 
 // psuedo-constant, global
@@ -59,10 +66,18 @@ type galF struct {
 	ord *big.Int
 	// quadratic non-residue
 	qnr *big.Int
+	// the Montgomery arithmetic of the field
+	mont *montField
+	// 1/2 in Montgomery form
+	half montElem
+	// (ord + 1) / 4 if ord = 3 mod 4, nil otherwise
+	sqrtExp *big.Int
 }
 
+// galE is an element of the Galois field 'fld', which is kept in Montgomery
+// form on fixed-size limbs to avoid the allocations of big.Int
 type galE struct {
-	val *big.Int
+	m   montElem
 	fld *galF
 }
 
@@ -82,17 +97,23 @@ func newGalF(ordV string) *galF {
 		}
 
 		if qnrV.ModSqrt(qnrV, order) != nil {
-			return &galF{
-				ord: order,
-				qnr: qnrV,
+			f := &galF{
+				ord:  order,
+				qnr:  qnrV,
+				mont: newMontField(order),
 			}
+			f.mont.fromBig(&f.half, new(big.Int).ModInverse(intTwo, order))
+			if order.Bit(0) == 1 && order.Bit(1) == 1 {
+				f.sqrtExp = new(big.Int).Add(order, intOne)
+				f.sqrtExp.Rsh(f.sqrtExp, 2)
+			}
+			return f
 		}
 	}
 }
 
 func newGalE(f *galF) *galE {
 	return &galE{
-		val: new(big.Int),
 		fld: f,
 	}
 }
@@ -104,43 +125,51 @@ func randGalE(f *galF) (*galE, error) {
 		return nil, err
 	}
 
-	return &galE{
-		val: rVal,
-		fld: f,
-	}, nil
+	return newGalE(f).setV(rVal), nil
 }
 
 func newGalZero(f *galF) *galE {
-	return &galE{
-		val: big.NewInt(0),
-		fld: f,
-	}
+	return newGalE(f)
 }
 
 func newGalOne(f *galF) *galE {
 	return &galE{
-		val: big.NewInt(1),
+		m:   f.mont.one,
 		fld: f,
 	}
 }
 
 func (e *galE) set(a *galE) *galE {
-	e.val.Set(a.val)
+	e.m = a.m
 	e.fld = a.fld
-	e.val.Mod(e.val, e.fld.ord)
 	return e
 }
 
 func (e *galE) setV(v *big.Int) *galE {
-	e.val.Mod(v, e.fld.ord)
+	if v.Sign() < 0 || v.Cmp(e.fld.ord) >= 0 {
+		v = new(big.Int).Mod(v, e.fld.ord)
+	}
+	e.fld.mont.fromBig(&e.m, v)
 	return e
 }
 
 func (e *galE) setVI(i int64) *galE {
-	e.val.SetInt64(i)
+	switch i {
+	case 0:
+		e.m = montElem{}
+	case 1:
+		e.m = e.fld.mont.one
+	default:
+		e.setV(big.NewInt(i))
+	}
 	return e
 }
 
+// bigInt returns the value of the element
+func (e *galE) bigInt() *big.Int {
+	return e.fld.mont.toBig(&e.m)
+}
+
 // helper
 func lenInByte(n *big.Int) int {
 	return (n.BitLen() + sizeOfByte - 1) / sizeOfByte
@@ -191,19 +220,33 @@ func (e *galE) setBytes(data []byte) *galE {
 	return e.setV(new(big.Int).SetBytes(data))
 }
 
+// trySetBytes restores the element from the untrusted 'data', which should
+// be of the length that bytes() returns & less than the order
+func (e *galE) trySetBytes(data []byte) error {
+	if len(data) != lenInByte(e.fld.ord) {
+		return errors.New(errInvalidElemLen)
+	}
+	v := new(big.Int).SetBytes(data)
+	if v.Cmp(e.fld.ord) >= 0 {
+		return errors.New(errElemOutOfRange)
+	}
+	e.setV(v)
+	return nil
+}
+
 func (e *galE) bytes() []byte {
 	oLen := lenInByte(e.fld.ord)
-	vBytes := e.val.Bytes()
+	vBytes := e.bigInt().Bytes()
 	padding := make([]byte, oLen-len(vBytes))
 	return append(padding, vBytes...)
 }
 
 func (e *galE) isSqr() bool {
-	return big.Jacobi(e.val, e.fld.ord) == 1
+	return big.Jacobi(e.bigInt(), e.fld.ord) == 1
 }
 
 func (e *galE) equal(a *galE) bool {
-	return e.fld == a.fld && e.val.Cmp(a.val) == 0
+	return e.fld == a.fld && e.fld.mont.equal(&e.m, &a.m) == 1
 }
 
 func (e *galE) add(lhs, rhs *galE) *galE {
@@ -211,7 +254,8 @@ func (e *galE) add(lhs, rhs *galE) *galE {
 		panic(errOperandsInDiffFields)
 	}
 	e.fld = lhs.fld
-	return e.setV(new(big.Int).Add(lhs.val, rhs.val))
+	e.fld.mont.add(&e.m, &lhs.m, &rhs.m)
+	return e
 }
 
 func (e *galE) sub(lhs, rhs *galE) *galE {
@@ -219,7 +263,8 @@ func (e *galE) sub(lhs, rhs *galE) *galE {
 		panic(errOperandsInDiffFields)
 	}
 	e.fld = lhs.fld
-	return e.setV(new(big.Int).Sub(lhs.val, rhs.val))
+	e.fld.mont.sub(&e.m, &lhs.m, &rhs.m)
+	return e
 }
 
 func (e *galE) mul(lhs, rhs *galE) *galE {
@@ -227,35 +272,99 @@ func (e *galE) mul(lhs, rhs *galE) *galE {
 		panic(errOperandsInDiffFields)
 	}
 	e.fld = lhs.fld
-	return e.setV(new(big.Int).Mul(lhs.val, rhs.val))
+	e.fld.mont.mul(&e.m, &lhs.m, &rhs.m)
+	return e
 }
 
+// the max factor that mulI works on with additions only
+const mulIMaxAdd = 16
+
 func (e *galE) mulI(lhs *galE, i int64) *galE {
+	if i < 0 || i > mulIMaxAdd {
+		return e.mulV(lhs, big.NewInt(i))
+	}
+
+	// double & add from the highest bit of 'i'
+	f := lhs.fld.mont
+	a := lhs.m
+	var res montElem
+	for k := 4; k >= 0; k-- {
+		f.add(&res, &res, &res)
+		if i>>uint(k)&1 == 1 {
+			f.add(&res, &res, &a)
+		}
+	}
 	e.fld = lhs.fld
-	return e.setV(new(big.Int).Mul(lhs.val, big.NewInt(i)))
+	e.m = res
+	return e
 }
 
 func (e *galE) mulV(lhs *galE, v *big.Int) *galE {
-	e.fld = lhs.fld
-	return e.setV(new(big.Int).Mul(lhs.val, v))
+	f := newGalE(lhs.fld).setV(v)
+	return e.mul(lhs, f)
 }
 
 func (e *galE) powI(lhs *galE, i int64) *galE {
-	e.fld = lhs.fld
-	return e.setV(new(big.Int).Exp(lhs.val, big.NewInt(i), lhs.fld.ord))
+	return e.powV(lhs, big.NewInt(i))
 }
 
+// the window width of powV
+const powWindow = 4
+
+// powV calculates lhs^v with the fixed window method, a negative 'v'
+// works on the inverse of 'lhs'
 func (e *galE) powV(lhs *galE, v *big.Int) *galE {
+	f := lhs.fld.mont
+	base := newGalE(lhs.fld).set(lhs)
+	if v.Sign() < 0 {
+		base.inv(base)
+		v = new(big.Int).Neg(v)
+	}
+
+	// table[j] = base^j
+	var table [1 << powWindow]montElem
+	table[0] = f.one
+	for j := 1; j < len(table); j++ {
+		f.mul(&table[j], &table[j-1], &base.m)
+	}
+
+	res := f.one
+	windows := (v.BitLen() + powWindow - 1) / powWindow
+	for w := windows - 1; w >= 0; w-- {
+		for k := 0; k < powWindow; k++ {
+			f.sqr(&res, &res)
+		}
+		j := 0
+		for k := powWindow - 1; k >= 0; k-- {
+			j = j<<1 | int(v.Bit(w*powWindow+k))
+		}
+		if j != 0 {
+			f.mul(&res, &res, &table[j])
+		}
+	}
 	e.fld = lhs.fld
-	return e.setV(new(big.Int).Exp(lhs.val, v, lhs.fld.ord))
+	e.m = res
+	return e
 }
 
 func (e *galE) sqr(a *galE) *galE {
-	return e.powI(a, 2)
+	e.fld = a.fld
+	e.fld.mont.sqr(&e.m, &a.m)
+	return e
 }
 
+// sqrt returns nil if 'a' is not a square. For the fields of order 3 mod 4,
+// e.g. gFQ, the square root is a^((ord+1)/4)
 func (e *galE) sqrt(a *galE) *galE {
-	v := new(big.Int).ModSqrt(a.val, a.fld.ord)
+	if a.fld.sqrtExp != nil {
+		r := newGalE(a.fld).powV(a, a.fld.sqrtExp)
+		if !newGalE(a.fld).sqr(r).equal(a) {
+			return nil
+		}
+		return e.set(r)
+	}
+
+	v := new(big.Int).ModSqrt(a.bigInt(), a.fld.ord)
 
 	if v == nil {
 		return nil
@@ -265,34 +374,27 @@ func (e *galE) sqrt(a *galE) *galE {
 	return e.setV(v)
 }
 
+// inv works on big.Int, which is much faster than the Fermat's inversion
+// but takes variable time
 func (e *galE) inv(a *galE) *galE {
 	e.fld = a.fld
-	return e.setV(new(big.Int).ModInverse(a.val, a.fld.ord))
+	return e.setV(new(big.Int).ModInverse(a.bigInt(), a.fld.ord))
 }
 
-// sign() returns -1 for negative value;
-// returns 0 for zero;
-// returns +1 for positive value.
+// sign() returns 0 for zero;
+// returns +1 for the others.
 func (e *galE) sign() int {
-	return e.val.Cmp(intZero)
+	return int(1 ^ e.fld.mont.isZero(&e.m))
 }
 
 func (e *galE) neg(a *galE) *galE {
 	e.fld = a.fld
-
-	if a.sign() == 0 {
-		return e.setV(intZero)
-	}
-
-	return e.setV(new(big.Int).Sub(a.fld.ord, a.val))
+	e.fld.mont.neg(&e.m, &a.m)
+	return e
 }
 
 func (e *galE) halve(a *galE) *galE {
-	e.set(a)
-
-	if e.val.Bit(0) == 1 {
-		e.val.Add(e.val, e.fld.ord)
-	}
-
-	return e.setV(new(big.Int).Quo(e.val, intTwo))
+	e.fld = a.fld
+	e.fld.mont.mul(&e.m, &a.m, &e.fld.half)
+	return e
 }
@@ -18,6 +18,7 @@
 package math
 
 import (
+	"bytes"
 	"math/big"
 	"strings"
 	"testing"
@@ -43,7 +44,7 @@ func TestGaloisArith(t *testing.T) {
 		assert.True(t, rhs.equal(newGalE(gFQ).sub(sum1, lhs)))
 
 		sum2 := newGalE(gFQ).add(lhs, newGalE(gFQ).neg(lhs))
-		assert.True(t, intZero.Cmp(sum2.val) == 0)
+		assert.True(t, intZero.Cmp(sum2.bigInt()) == 0)
 
 		sum3 := newGalE(gFQ).add(rhs, rhs)
 		prd := newGalE(gFQ).mulI(rhs, 2)
@@ -73,7 +74,7 @@ func TestMulBasedGaloisCalc(t *testing.T) {
 		assert.True(t, a.equal(newGalE(gFR).mulI(halfA, 2)))
 
 		invA := newGalE(gFR).inv(a)
-		assert.True(t, intOne.Cmp(newGalE(gFR).mul(a, invA).val) == 0)
+		assert.True(t, intOne.Cmp(newGalE(gFR).mul(a, invA).bigInt()) == 0)
 
 		// Note that in Galois field, 'sqrtSqrA' and 'a' is *NOT*
 		// necessarily equal to each other, here is an example:
@@ -88,16 +89,158 @@ func TestMulBasedGaloisCalc(t *testing.T) {
 		x, err := randGalE(gFR)
 		assert.NoError(t, err)
 
-		aPowX := newGalE(gFR).powV(a, x.val)
-		invAPowX := newGalE(gFR).powV(invA, x.val)
-		assert.True(t, intOne.Cmp(newGalE(gFR).mul(aPowX, invAPowX).val) == 0)
+		aPowX := newGalE(gFR).powV(a, x.bigInt())
+		invAPowX := newGalE(gFR).powV(invA, x.bigInt())
+		assert.True(t, intOne.Cmp(newGalE(gFR).mul(aPowX, invAPowX).bigInt()) == 0)
 
 		// Note that for Galois elements,
-		// pow(a, x.neg()) != pow(a, x.val.neg())
-		aPowNegX := newGalE(gFR).powV(a, new(big.Int).Neg(x.val))
+		// pow(a, x.neg()) != pow(a, x.bigInt().neg())
+		aPowNegX := newGalE(gFR).powV(a, new(big.Int).Neg(x.bigInt()))
 		assert.True(t, invAPowX.equal(aPowNegX))
 	}
 }
 
-// TODO: need edge case checks
-// TODO: need benchmarks
+func TestGaloisEdges(t *testing.T) {
+	for _, f := range []*galF{gFQ, gFR} {
+		maxV := new(big.Int).Sub(f.ord, intOne)
+		maxE := newGalE(f).setV(maxV)
+		assert.Equal(t, 0, maxE.bigInt().Cmp(maxV))
+		assert.Equal(t, 0, newGalE(f).setV(f.ord).sign())
+		assert.True(t, newGalE(f).setV(big.NewInt(-1)).equal(maxE))
+		assert.True(t, newGalE(f).add(maxE, newGalOne(f)).equal(newGalZero(f)))
+		assert.True(t, newGalE(f).sub(newGalZero(f), newGalOne(f)).equal(maxE))
+		assert.True(t, newGalE(f).neg(newGalZero(f)).equal(newGalZero(f)))
+		assert.True(t, newGalE(f).mul(maxE, maxE).equal(newGalOne(f)))
+		assert.True(t, newGalE(f).halve(newGalOne(f)).equal(newGalE(f).setV(
+			new(big.Int).Rsh(new(big.Int).Add(f.ord, intOne), 1))))
+
+		a, err := randGalE(f)
+		assert.NoError(t, err)
+		for _, i := range []int64{-3, 0, 1, 7, mulIMaxAdd, mulIMaxAdd + 1, 1 << 40} {
+			expected := new(big.Int).Mul(a.bigInt(), big.NewInt(i))
+			assert.True(t, newGalE(f).mulI(a, i).equal(newGalE(f).setV(expected)))
+			expected.Exp(a.bigInt(), big.NewInt(i), f.ord)
+			assert.True(t, newGalE(f).powI(a, i).equal(newGalE(f).setV(expected)), "i: %d", i)
+		}
+
+		// a non-residue has no square root
+		nr := big.NewInt(2)
+		for big.Jacobi(nr, f.ord) != -1 {
+			nr.Add(nr, intOne)
+		}
+		assert.Nil(t, newGalE(f).sqrt(newGalE(f).setV(nr)))
+		assert.True(t, newGalE(f).sqrt(newGalZero(f)).equal(newGalZero(f)))
+	}
+}
+
+func BenchmarkGaloisMul(b *testing.B) {
+	lhs, err := randGalE(gFQ)
+	assert.NoError(b, err)
+	rhs, err := randGalE(gFQ)
+	assert.NoError(b, err)
+
+	b.Run("mont", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			lhs.mul(lhs, rhs)
+		}
+	})
+	// the big.Int routine that galE used to work with
+	b.Run("big", func(b *testing.B) {
+		l, r := lhs.bigInt(), rhs.bigInt()
+		for i := 0; i < b.N; i++ {
+			l = new(big.Int).Mod(new(big.Int).Mul(l, r), gFQ.ord)
+		}
+	})
+}
+
+func BenchmarkGaloisInv(b *testing.B) {
+	a, err := randGalE(gFQ)
+	assert.NoError(b, err)
+
+	b.Run("galE", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			newGalE(gFQ).inv(a)
+		}
+	})
+	b.Run("fermat", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			gFQ.mont.inv(new(montElem), &a.m)
+		}
+	})
+}
+
+func BenchmarkGaloisSqrt(b *testing.B) {
+	a, err := randGalE(gFQ)
+	assert.NoError(b, err)
+	a.sqr(a)
+
+	b.Run("mont", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			newGalE(gFQ).sqrt(a)
+		}
+	})
+	b.Run("big", func(b *testing.B) {
+		v := a.bigInt()
+		for i := 0; i < b.N; i++ {
+			new(big.Int).ModSqrt(v, gFQ.ord)
+		}
+	})
+}
+
+func TestParseGaloisElem(t *testing.T) {
+	x, err := RandGaloisElem()
+	assert.NoError(t, err)
+	res, err := ParseGaloisElem(x.Marshal())
+	assert.NoError(t, err)
+	assert.True(t, res.Equal(x))
+
+	maxV := newGalE(gFR).setV(new(big.Int).Sub(gFR.ord, intOne))
+	_, err = ParseGaloisElem(toBase64Str(maxV.bytes()))
+	assert.NoError(t, err)
+
+	for _, data := range [][]byte{
+		{},
+		x.Bytes()[1:],
+		append(x.Bytes(), 0),
+		gFR.ord.Bytes(),
+		bytes.Repeat([]byte{0xff}, lenInByte(gFR.ord)),
+	} {
+		_, err := ParseGaloisElem(toBase64Str(data))
+		assert.Error(t, err)
+	}
+}
+
+func TestGaloisElemBinary(t *testing.T) {
+	assert.Equal(t, lenInByte(gFR.ord), GaloisElemSize)
+	e, err := RandGaloisElem()
+	assert.NoError(t, err)
+
+	data, err := e.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Equal(t, GaloisElemSize, len(data))
+	var res GaloisElem
+	assert.NoError(t, res.UnmarshalBinary(data))
+	assert.True(t, res.Equal(e))
+
+	assert.Error(t, res.UnmarshalBinary(data[1:]))
+	assert.Error(t, res.UnmarshalBinary(gFR.ord.Bytes()))
+}
+
+func TestGaloisElemArith(t *testing.T) {
+	one, two := NewGaloisElem(1), NewGaloisElem(2)
+	sum := GaloisAdd(one, one)
+	assert.True(t, sum.Equal(two))
+	diff := GaloisSub(one, two)
+	assert.True(t, diff.Equal(NewGaloisElem(-1)))
+	sum = GaloisAdd(diff, two)
+	assert.True(t, sum.Equal(one))
+
+	for i := 0; i < galTestRound; i++ {
+		e, err := RandGaloisElem()
+		assert.NoError(t, err)
+		prd := GaloisMul(e, GaloisInv(e))
+		assert.True(t, prd.Equal(one))
+		diff = GaloisSub(e, e)
+		assert.True(t, diff.Equal(NewGaloisElem(0)))
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package math
+
+import (
+	"math/big"
+)
+
+// the window width of the w-NAF recoding
+const wNAFWidth = 5
+
+// jacP is a point of the elliptic curve y^2 = x^3 + x in Jacobian coordinates
+// (X, Y, Z), which stands for the affine point (X/Z^2, Y/Z^3). Z == 0 stands
+// for the infinity point. Unlike curP, the arithmetic of jacP requires no
+// field inversion.
+type jacP struct {
+	x *galE
+	y *galE
+	z *galE
+}
+
+func newJacP() *jacP {
+	return &jacP{
+		x: newGalOne(gFQ),
+		y: newGalOne(gFQ),
+		z: newGalZero(gFQ),
+	}
+}
+
+func (p *jacP) isInf() bool {
+	return p.z.sign() == 0
+}
+
+func (p *jacP) set(a *jacP) *jacP {
+	p.x.set(a.x)
+	p.y.set(a.y)
+	p.z.set(a.z)
+	return p
+}
+
+func (p *jacP) setAffine(a *curP) *jacP {
+	if a.inf {
+		p.x.setVI(1)
+		p.y.setVI(1)
+		p.z.setVI(0)
+		return p
+	}
+	p.x.set(a.x)
+	p.y.set(a.y)
+	p.z.setVI(1)
+	return p
+}
+
+// toAffine converts the point back to affine coordinates into 'a', which
+// takes the only field inversion
+func (p *jacP) toAffine(a *curP) *curP {
+	if p.isInf() {
+		a.inf = true
+		return a
+	}
+	zInv := newGalE(gFQ).inv(p.z)
+	zInvSqr := newGalE(gFQ).sqr(zInv)
+	a.inf = false
+	a.x.mul(p.x, zInvSqr)
+	zInvSqr.mul(zInvSqr, zInv)
+	a.y.mul(p.y, zInvSqr)
+	return a
+}
+
+func (p *jacP) neg(a *jacP) *jacP {
+	p.set(a)
+	p.y.neg(p.y)
+	return p
+}
+
+// double works with the formulas for a = 1:
+// M = 3 * X^2 + Z^4, S = 4 * X * Y^2
+// X' = M^2 - 2 * S, Y' = M * (S - X') - 8 * Y^4, Z' = 2 * Y * Z
+func (p *jacP) double(a *jacP) *jacP {
+	if a.isInf() || a.y.sign() == 0 {
+		p.z.setVI(0)
+		return p
+	}
+
+	yy := newGalE(gFQ).sqr(a.y)
+	zz := newGalE(gFQ).sqr(a.z)
+	m := newGalE(gFQ).sqr(a.x)
+	m.mulI(m, 3)
+	zz.sqr(zz)
+	m.add(m, zz)
+	s := newGalE(gFQ).mul(a.x, yy)
+	s.mulI(s, 4)
+
+	z := newGalE(gFQ).mul(a.y, a.z)
+	p.z.add(z, z)
+	p.x.sqr(m)
+	p.x.sub(p.x, s)
+	p.x.sub(p.x, s)
+	yy.sqr(yy)
+	yy.mulI(yy, 8)
+	s.sub(s, p.x)
+	p.y.mul(m, s)
+	p.y.sub(p.y, yy)
+	return p
+}
+
+// add works with the formulas:
+// U1 = X1 * Z2^2, U2 = X2 * Z1^2, S1 = Y1 * Z2^3, S2 = Y2 * Z1^3
+// H = U2 - U1, R = S2 - S1
+// X' = R^2 - H^3 - 2 * U1 * H^2, Y' = R * (U1 * H^2 - X') - S1 * H^3, Z' = Z1 * Z2 * H
+func (p *jacP) add(lhs, rhs *jacP) *jacP {
+	if lhs.isInf() {
+		return p.set(rhs)
+	}
+	if rhs.isInf() {
+		return p.set(lhs)
+	}
+
+	z1z1 := newGalE(gFQ).sqr(lhs.z)
+	z2z2 := newGalE(gFQ).sqr(rhs.z)
+	u1 := newGalE(gFQ).mul(lhs.x, z2z2)
+	u2 := newGalE(gFQ).mul(rhs.x, z1z1)
+	s1 := newGalE(gFQ).mul(lhs.y, rhs.z)
+	s1.mul(s1, z2z2)
+	s2 := newGalE(gFQ).mul(rhs.y, lhs.z)
+	s2.mul(s2, z1z1)
+
+	h := newGalE(gFQ).sub(u2, u1)
+	r := newGalE(gFQ).sub(s2, s1)
+	if h.sign() == 0 {
+		if r.sign() == 0 {
+			return p.double(lhs)
+		}
+		p.z.setVI(0)
+		return p
+	}
+
+	hh := newGalE(gFQ).sqr(h)
+	hhh := newGalE(gFQ).mul(h, hh)
+	v := newGalE(gFQ).mul(u1, hh)
+
+	z := newGalE(gFQ).mul(lhs.z, rhs.z)
+	p.z.mul(z, h)
+	x := newGalE(gFQ).sqr(r)
+	x.sub(x, hhh)
+	x.sub(x, v)
+	x.sub(x, v)
+	v.sub(v, x)
+	p.y.mul(r, v)
+	s1.mul(s1, hhh)
+	p.y.sub(p.y, s1)
+	p.x.set(x)
+	return p
+}
+
+// addAffine adds an affine point 'rhs' to 'lhs', i.e. add() with Z2 == 1,
+// which saves a few multiplications
+func (p *jacP) addAffine(lhs *jacP, rhs *curP) *jacP {
+	if rhs.inf {
+		return p.set(lhs)
+	}
+	if lhs.isInf() {
+		return p.setAffine(rhs)
+	}
+
+	z1z1 := newGalE(gFQ).sqr(lhs.z)
+	u2 := newGalE(gFQ).mul(rhs.x, z1z1)
+	s2 := newGalE(gFQ).mul(rhs.y, lhs.z)
+	s2.mul(s2, z1z1)
+
+	h := newGalE(gFQ).sub(u2, lhs.x)
+	r := newGalE(gFQ).sub(s2, lhs.y)
+	if h.sign() == 0 {
+		if r.sign() == 0 {
+			return p.double(lhs)
+		}
+		p.z.setVI(0)
+		return p
+	}
+
+	hh := newGalE(gFQ).sqr(h)
+	hhh := newGalE(gFQ).mul(h, hh)
+	v := newGalE(gFQ).mul(lhs.x, hh)
+	s1 := newGalE(gFQ).mul(lhs.y, hhh)
+
+	p.z.mul(lhs.z, h)
+	x := newGalE(gFQ).sqr(r)
+	x.sub(x, hhh)
+	x.sub(x, v)
+	x.sub(x, v)
+	v.sub(v, x)
+	p.y.mul(r, v)
+	p.y.sub(p.y, s1)
+	p.x.set(x)
+	return p
+}
+
+// wNAF returns the width-w non-adjacent form of 'n' > 0, from the lowest
+// digit to the highest one. Each digit is either 0 or an odd value in
+// (-2^(w-1), 2^(w-1)).
+func wNAF(n *big.Int, w uint) []int8 {
+	k := new(big.Int).Set(n)
+	mask := big.NewInt(1<<w - 1)
+	mod := new(big.Int)
+	naf := make([]int8, 0, n.BitLen()+1)
+	for k.Sign() > 0 {
+		d := int64(0)
+		if k.Bit(0) == 1 {
+			d = mod.And(k, mask).Int64()
+			if d >= 1<<(w-1) {
+				d -= 1 << w
+			}
+			k.Sub(k, big.NewInt(d))
+		}
+		naf = append(naf, int8(d))
+		k.Rsh(k, 1)
+	}
+	return naf
+}
+
+// oddMultiples returns the table of [P, 3P, 5P, ..., (2^(w-1) - 1)P]
+func oddMultiples(a *jacP, w uint) []*jacP {
+	table := make([]*jacP, 1<<(w-2))
+	table[0] = newJacP().set(a)
+	dbl := newJacP().double(a)
+	for i := 1; i < len(table); i++ {
+		table[i] = newJacP().add(table[i-1], dbl)
+	}
+	return table
+}
+
+// powNJacobian calculates a^n in Jacobian coordinates with the w-NAF of 'n'
+func powNJacobian(a *curP, n *big.Int) *jacP {
+	res := newJacP()
+	if a.inf || n.Sign() <= 0 {
+		return res
+	}
+
+	table := oddMultiples(newJacP().setAffine(a), wNAFWidth)
+	naf := wNAF(n, wNAFWidth)
+	neg := newJacP()
+	for i := len(naf) - 1; i >= 0; i-- {
+		res.double(res)
+		d := naf[i]
+		if d > 0 {
+			res.add(res, table[d/2])
+		} else if d < 0 {
+			res.add(res, neg.neg(table[-d/2]))
+		}
+	}
+	return res
+}
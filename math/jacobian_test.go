@@ -0,0 +1,129 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package math
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJacobianArith(t *testing.T) {
+	for i := 0; i < ellipticTestRound; i++ {
+		a, err := randCurP()
+		assert.NoError(t, err)
+		b, err := randCurP()
+		assert.NoError(t, err)
+
+		ja := newJacP().setAffine(a)
+		jb := newJacP().setAffine(b)
+
+		sum := newJacP().add(ja, jb)
+		assert.True(t, sum.toAffine(newCurP()).equal(newCurP().add(a, b)))
+
+		// mixed Z coordinates
+		sum.add(sum, ja)
+		expected := newCurP().add(a, b)
+		expected.add(expected, a)
+		assert.True(t, sum.toAffine(newCurP()).equal(expected))
+
+		dbl := newJacP().double(ja)
+		assert.True(t, dbl.toAffine(newCurP()).equal(newCurP().double(a)))
+		assert.True(t, newJacP().add(ja, ja).toAffine(newCurP()).equal(newCurP().double(a)))
+
+		// p + (-p) is the infinity point
+		assert.True(t, newJacP().add(ja, newJacP().neg(ja)).isInf())
+		assert.True(t, newJacP().add(newJacP(), ja).toAffine(newCurP()).equal(a))
+	}
+}
+
+func TestWNAF(t *testing.T) {
+	for i := 0; i < ellipticTestRound; i++ {
+		n, err := rand.Int(rand.Reader, gFQ.ord)
+		assert.NoError(t, err)
+
+		naf := wNAF(n, wNAFWidth)
+		res := new(big.Int)
+		for j := len(naf) - 1; j >= 0; j-- {
+			res.Lsh(res, 1)
+			res.Add(res, big.NewInt(int64(naf[j])))
+			if naf[j] != 0 {
+				assert.True(t, naf[j]%2 != 0)
+				// no adjacent non-zero digits within the window
+				for k := 1; k < wNAFWidth && j+k < len(naf); k++ {
+					assert.Equal(t, int8(0), naf[j+k])
+				}
+			}
+		}
+		assert.Equal(t, 0, n.Cmp(res))
+	}
+}
+
+func TestEllipticPowJacobian(t *testing.T) {
+	a, err := randCurP()
+	assert.NoError(t, err)
+
+	edges := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(2),
+		big.NewInt(31),
+		big.NewInt(-1),
+		new(big.Int).Sub(gFR.ord, intOne),
+		new(big.Int).Set(gFR.ord),
+		new(big.Int).Add(gFR.ord, intOne),
+		new(big.Int).Set(coFac),
+	}
+	for i := 0; i < ellipticTestRound/4; i++ {
+		n, err := rand.Int(rand.Reader, gFQ.ord)
+		assert.NoError(t, err)
+		edges = append(edges, n)
+	}
+
+	for _, n := range edges {
+		assert.True(t, newCurP().powN(a, n).equal(newCurP().powNAffine(a, n)))
+	}
+	assert.True(t, newCurP().powN(newCurIdentity(), big.NewInt(5)).inf)
+	assert.True(t, newCurP().powN(a, gFR.ord).inf)
+}
+
+func BenchmarkEllipticPowAffine(b *testing.B) {
+	a, err := randCurP()
+	assert.NoError(b, err)
+	n, err := randGalE(gFR)
+	assert.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		newCurP().powNAffine(a, n.bigInt())
+	}
+}
+
+func BenchmarkEllipticPow(b *testing.B) {
+	a, err := randCurP()
+	assert.NoError(b, err)
+	n, err := randGalE(gFR)
+	assert.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		newCurP().powN(a, n.bigInt())
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package math
+
+// ctP is a point of the elliptic curve y^2 = x^3 + x in homogeneous
+// projective coordinates (X:Y:Z) over the Montgomery arithmetic of gFQ,
+// which stands for the affine point (X/Z, Y/Z). (0:1:0) stands for the
+// infinity point. Along with the complete addition formulas, the arithmetic
+// of ctP takes no branch on the coordinates, thus works for the secret
+// exponentiation.
+type ctP struct {
+	x montElem
+	y montElem
+	z montElem
+}
+
+func newCtP() *ctP {
+	p := &ctP{}
+	p.y = gFQ.mont.one
+	return p
+}
+
+// setAffine converts the affine point 'a' into 'p'
+func (p *ctP) setAffine(a *curP) *ctP {
+	f := gFQ.mont
+	if a.inf {
+		*p = ctP{y: f.one}
+		return p
+	}
+	p.x, p.y, p.z = a.x.m, a.y.m, f.one
+	return p
+}
+
+// toAffine converts the point back to affine coordinates into 'a'
+func (p *ctP) toAffine(a *curP) *curP {
+	f := gFQ.mont
+	if f.isZero(&p.z) == 1 {
+		a.inf = true
+		return a
+	}
+	var zInv, x, y montElem
+	f.inv(&zInv, &p.z)
+	f.mul(&x, &p.x, &zInv)
+	f.mul(&y, &p.y, &zInv)
+	a.inf = false
+	a.x.fld, a.x.m = gFQ, x
+	a.y.fld, a.y.m = gFQ, y
+	return a
+}
+
+// add sets p = lhs + rhs with the complete formulas of Renes, Costello &
+// Batina (Algorithm 1 of ePrint 2015/1060) for a = 1 & b = 0. The formulas
+// work for doubling & the infinity point as well, the only exceptions are
+// the pairs whose difference is of order 2, which never show up among the
+// points of the odd order subgroup.
+func (p *ctP) add(lhs, rhs *ctP) *ctP {
+	f := gFQ.mont
+	var t0, t1, t2, t3, t4, t5, x3, y3, z3 montElem
+	f.mul(&t0, &lhs.x, &rhs.x)
+	f.mul(&t1, &lhs.y, &rhs.y)
+	f.mul(&t2, &lhs.z, &rhs.z)
+	f.add(&t3, &lhs.x, &lhs.y)
+	f.add(&t4, &rhs.x, &rhs.y)
+	f.mul(&t3, &t3, &t4)
+	f.add(&t4, &t0, &t1)
+	f.sub(&t3, &t3, &t4)
+	f.add(&t4, &lhs.x, &lhs.z)
+	f.add(&t5, &rhs.x, &rhs.z)
+	f.mul(&t4, &t4, &t5)
+	f.add(&t5, &t0, &t2)
+	f.sub(&t4, &t4, &t5)
+	f.add(&t5, &lhs.y, &lhs.z)
+	f.add(&x3, &rhs.y, &rhs.z)
+	f.mul(&t5, &t5, &x3)
+	f.add(&x3, &t1, &t2)
+	f.sub(&t5, &t5, &x3)
+	// Z3 = a * t4 + b3 * t2 = t4
+	z3 = t4
+	f.sub(&x3, &t1, &z3)
+	f.add(&z3, &t1, &z3)
+	f.mul(&y3, &x3, &z3)
+	f.add(&t1, &t0, &t0)
+	f.add(&t1, &t1, &t0)
+	// t4 = b3 * t4 + a * (t0 - a * t2) = t0 - t2, t1 = t1 + a * t2
+	f.add(&t1, &t1, &t2)
+	f.sub(&t4, &t0, &t2)
+	f.mul(&t0, &t1, &t4)
+	f.add(&y3, &y3, &t0)
+	f.mul(&t0, &t5, &t4)
+	f.mul(&x3, &t3, &x3)
+	f.sub(&x3, &x3, &t0)
+	f.mul(&t0, &t3, &t1)
+	f.mul(&z3, &t5, &z3)
+	f.add(&z3, &z3, &t0)
+
+	p.x, p.y, p.z = x3, y3, z3
+	return p
+}
+
+// ctSwap swaps 'a' & 'b' if 'c' == 1, leaves them as is if 'c' == 0
+func ctSwap(a, b *ctP, c uint64) {
+	montSwap(&a.x, &b.x, c)
+	montSwap(&a.y, &b.y, c)
+	montSwap(&a.z, &b.z, c)
+}
+
+// powNSecret calculates a^n with the Montgomery ladder, which takes exactly
+// one addition & one doubling for each bit of the order of gFR, no matter
+// what 'k' is. Thus it is used for the secret exponents in gFR, 'a' should
+// be a point of the subgroup of order r & 'k' holds the ordinary value of
+// the exponent in the fixed-width limbs of gFR.mont, see montField.fromMont.
+func powNSecret(a *curP, k *montElem) *curP {
+	nBits := gFR.ord.BitLen()
+
+	// r0 = a^m & r1 = a^(m+1), where 'm' is the bits of 'n' processed
+	r0 := newCtP()
+	r1 := newCtP().setAffine(a)
+	for i := nBits - 1; i >= 0; i-- {
+		b := k[i/64] >> uint(i%64) & 1
+		ctSwap(r0, r1, b)
+		r1.add(r0, r1)
+		r0.add(r0, r0)
+		ctSwap(r0, r1, b)
+	}
+	return r0.toAffine(newCurP())
+}
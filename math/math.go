@@ -20,6 +20,19 @@ package math
 import (
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
+	"math/big"
+	"sync"
+)
+
+// the sizes in bytes of the fixed-length binary encodings
+const (
+	// GaloisElemSize is the size of a gFR element
+	GaloisElemSize = 20
+	// EllipticPointSize is the size of a compressed elliptic curve point
+	EllipticPointSize = 65
+	// QuadraticElemSize is the size of a compressed element of GT
+	QuadraticElemSize = 65
 )
 
 // package level init():
@@ -56,6 +69,23 @@ func (e *GaloisElem) Marshal() string {
 	return toBase64Str(e.Bytes())
 }
 
+// MarshalBinary implements encoding.BinaryMarshaler, the result is of
+// GaloisElemSize bytes
+func (e *GaloisElem) MarshalBinary() ([]byte, error) {
+	return e.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, an error is returned
+// for the data of wrong length or out of range
+func (e *GaloisElem) UnmarshalBinary(data []byte) error {
+	v := newGalE(gFR)
+	if err := v.trySetBytes(data); err != nil {
+		return err
+	}
+	e.v = v
+	return nil
+}
+
 // Equal validate if 2 GaloisElem instances are mathematically equal
 func (e *GaloisElem) Equal(a GaloisElem) bool {
 	return e.v.equal(a.v)
@@ -63,14 +93,21 @@ func (e *GaloisElem) Equal(a GaloisElem) bool {
 
 // ParseGaloisElem trys to restore a GaloisElem instance by
 // parsing given Base64 encoding string
-// WARNING: the result is in gFR field
+// WARNING: the result is in gFR field, an error is returned for the data
+// of wrong length or out of range
 func ParseGaloisElem(s string) (GaloisElem, error) {
 	bytes, err := fromBase64Str(s)
 	if err != nil {
 		return GaloisElem{}, err
 	}
 
-	return BytesToGaloisElem(bytes), nil
+	e := newGalE(gFR)
+	if err := e.trySetBytes(bytes); err != nil {
+		return GaloisElem{}, err
+	}
+	return GaloisElem{
+		v: e,
+	}, nil
 }
 
 // EllipticPoint presents a point on the elliptic curve
@@ -78,25 +115,109 @@ type EllipticPoint struct {
 	v *curP
 }
 
-// Bytes converts a EllipticPoint instance to a byte slice
+// Bytes converts a EllipticPoint instance to a byte slice, i.e. x || y
+// without any flag byte, where the infinity point is empty
 func (p *EllipticPoint) Bytes() []byte {
 	return p.v.bytes()
 }
 
-// Marshal converts a EllipticPoint instance to a Base64 encoded string
+// CompressedBytes converts a EllipticPoint instance to a byte slice in the
+// compressed encoding, i.e. a flag byte & the x coordinate
+func (p *EllipticPoint) CompressedBytes() []byte {
+	return p.v.compressedBytes()
+}
+
+// UncompressedBytes converts a EllipticPoint instance to a byte slice in the
+// uncompressed encoding, i.e. a flag byte & both the coordinates
+func (p *EllipticPoint) UncompressedBytes() []byte {
+	if p.v.inf {
+		return []byte{ptFlagInfinity}
+	}
+	return append([]byte{ptFlagUncompressed}, p.v.bytes()...)
+}
+
+// Marshal converts a EllipticPoint instance to a Base64 encoded string of
+// the compressed encoding
 func (p *EllipticPoint) Marshal() string {
-	return toBase64Str(p.Bytes())
+	return toBase64Str(p.CompressedBytes())
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The result is the
+// compressed encoding of EllipticPointSize bytes, where the infinity point is
+// padded with zeros.
+func (p *EllipticPoint) MarshalBinary() ([]byte, error) {
+	res := make([]byte, EllipticPointSize)
+	copy(res, p.CompressedBytes())
+	return res, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, which works just as
+// ParseEllipticPt does on the data of EllipticPointSize bytes
+func (p *EllipticPoint) UnmarshalBinary(data []byte) error {
+	if len(data) != EllipticPointSize {
+		return errors.New(errInvalidPointLen)
+	}
+	if data[0] == ptFlagInfinity {
+		// the padding of the infinity point
+		for _, b := range data[1:] {
+			if b != 0 {
+				return errors.New(errInvalidPointFlag)
+			}
+		}
+		data = data[:1]
+	}
+
+	v := newCurP()
+	if err := v.trySetBytes(data, true); err != nil {
+		return err
+	}
+	p.v = v
+	return nil
+}
+
+// InSubgroup validates if the point is in the subgroup of order r, that the
+// generator belongs to, i.e. p^r is the infinity point
+func (p *EllipticPoint) InSubgroup() bool {
+	return p.v.inSubgroup()
 }
 
-// ParseEllipticPt trys to restore an elliptic curve point from given string
+// IsInfinity validates if the point is the infinity point, i.e. the identity
+func (p *EllipticPoint) IsInfinity() bool {
+	return p.v.inf
+}
+
+// Equal validate if 2 EllipticPoint instances are the same point
+func (p *EllipticPoint) Equal(a EllipticPoint) bool {
+	return p.v.equal(a.v)
+}
+
+// ParseEllipticPt trys to restore an elliptic curve point from given string,
+// in either the compressed or the uncompressed encoding. An error is returned
+// for the data of wrong length, the coordinates out of range, & the points
+// off the curve or outside the subgroup of order r.
 func ParseEllipticPt(s string) (EllipticPoint, error) {
+	return parseEllipticPt(s, true)
+}
+
+// ParseEllipticPtTrusted works just as ParseEllipticPt does, except that the
+// subgroup check is skipped, which is the most costly part of the parsing.
+// It should be used for the trusted input only, e.g. the data stored locally.
+func ParseEllipticPtTrusted(s string) (EllipticPoint, error) {
+	return parseEllipticPt(s, false)
+}
+
+func parseEllipticPt(s string, checkSubgroup bool) (EllipticPoint, error) {
 	bytes, err := fromBase64Str(s)
 	if err != nil {
 		return EllipticPoint{}, err
 	}
 
+	p := newCurP()
+	if err := p.trySetBytes(bytes, checkSubgroup); err != nil {
+		return EllipticPoint{}, err
+	}
 	return EllipticPoint{
-		v: newCurP().setBytes(bytes),
+		v: p,
 	}, nil
 }
 
@@ -106,26 +227,104 @@ type QuadraticElem struct {
 	v *quadE
 }
 
-// Bytes converts a QuadraticElem instance to a byte slice
+// Bytes converts a QuadraticElem instance to a byte slice, i.e. x || y
+// without any flag byte
 func (e *QuadraticElem) Bytes() []byte {
 	return e.v.bytes()
 }
 
-// Marshal converts a QuadraticElem instance to a Base64 encoded string
+// CompressedBytes converts a QuadraticElem instance to a byte slice in the
+// compressed encoding. The elements of GT, e.g. the pairing results, are
+// compressed to a flag byte & the x coordinate, as the y coordinate is
+// restored from x^2 + y^2 == 1, the others fall back to the uncompressed one.
+func (e *QuadraticElem) CompressedBytes() []byte {
+	return e.v.compressedBytes()
+}
+
+// UncompressedBytes converts a QuadraticElem instance to a byte slice in the
+// uncompressed encoding, i.e. a flag byte & both the coordinates
+func (e *QuadraticElem) UncompressedBytes() []byte {
+	return append([]byte{ptFlagUncompressed}, e.v.bytes()...)
+}
+
+// Marshal converts a QuadraticElem instance to a Base64 encoded string of
+// the compressed encoding
 func (e *QuadraticElem) Marshal() string {
-	return toBase64Str(e.Bytes())
+	return toBase64Str(e.CompressedBytes())
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The result is the
+// compressed encoding of QuadraticElemSize bytes, thus an error is returned
+// for the elements not of norm 1, which are never in GT.
+func (e *QuadraticElem) MarshalBinary() ([]byte, error) {
+	if !e.v.isNorm1() {
+		return nil, errors.New(errElemNotOfNorm1)
+	}
+	return e.v.compressedBytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, which works just as
+// ParseQuadraticElem does on the data of QuadraticElemSize bytes
+func (e *QuadraticElem) UnmarshalBinary(data []byte) error {
+	if len(data) != QuadraticElemSize {
+		return errors.New(errInvalidElemLen)
+	}
+
+	v := newQuadE()
+	if err := v.trySetBytes(data); err != nil {
+		return err
+	}
+	if !v.inGT() {
+		return errors.New(errElemNotInGT)
+	}
+	e.v = v
+	return nil
+}
+
+// InGT validates if the element is in the group of the pairing results,
+// i.e. e^r == 1
+func (e *QuadraticElem) InGT() bool {
+	return e.v.inGT()
 }
 
 // ParseQuadraticElem try to restore a QuadraticElem instance by parsing
-// given string
+// given string, in either the compressed or the uncompressed encoding. An
+// error is returned for the data of wrong length, the coordinates out of
+// range, & the elements not of norm 1 or outside the group of order r.
 func ParseQuadraticElem(s string) (QuadraticElem, error) {
+	return parseQuadraticElem(s, true)
+}
+
+// ParseQuadraticElemTrusted works just as ParseQuadraticElem does, except
+// that the group check is skipped. It should be used for the trusted input only.
+func ParseQuadraticElemTrusted(s string) (QuadraticElem, error) {
+	return parseQuadraticElem(s, false)
+}
+
+// ParseQuadraticElemBytes works just as ParseQuadraticElem does on the raw
+// bytes, e.g. the result of Bytes, CompressedBytes or UncompressedBytes
+func ParseQuadraticElemBytes(data []byte) (QuadraticElem, error) {
+	return parseQuadraticElemBytes(data, true)
+}
+
+func parseQuadraticElem(s string, checkGT bool) (QuadraticElem, error) {
 	bytes, err := fromBase64Str(s)
 	if err != nil {
 		return QuadraticElem{}, err
 	}
+	return parseQuadraticElemBytes(bytes, checkGT)
+}
 
+func parseQuadraticElemBytes(data []byte, checkGT bool) (QuadraticElem, error) {
+	e := newQuadE()
+	if err := e.trySetBytes(data); err != nil {
+		return QuadraticElem{}, err
+	}
+	if checkGT && !e.inGT() {
+		return QuadraticElem{}, errors.New(errElemNotInGT)
+	}
 	return QuadraticElem{
-		v: newQuadE().setBytes(bytes),
+		v: e,
 	}, nil
 }
 
@@ -152,6 +351,14 @@ func BytesToGaloisElem(b []byte) GaloisElem {
 	}
 }
 
+// NewGaloisElem returns the Galois field element of the integer 'i', where
+// a negative 'i' is reduced. Note that the result is in gFR.
+func NewGaloisElem(i int64) GaloisElem {
+	return GaloisElem{
+		v: newGalE(gFR).setVI(i),
+	}
+}
+
 // RandGaloisElem returns a random element in the Galois
 // field. Note that the result is in gFR.
 func RandGaloisElem() (GaloisElem, error) {
@@ -192,17 +399,103 @@ func BiLinearMap(u, v EllipticPoint) QuadraticElem {
 	}
 }
 
+// MultiPairing returns the product of the bi-linear maps of the given
+// pairs, i.e. Prod(e(as[i], bs[i])), which is much faster than multiplying
+// the BiLinearMap results since the final exponentiation is shared. It
+// panics if the num of 'as' & 'bs' does not match.
+// WARNING: a pair with the infinity point contributes the identity, thus the
+// callers validating the untrusted points should reject the infinity first
+func MultiPairing(as, bs []EllipticPoint) QuadraticElem {
+	asV := make([]*curP, len(as))
+	for i := range as {
+		asV[i] = as[i].v
+	}
+	bsV := make([]*curP, len(bs))
+	for i := range bs {
+		bsV[i] = bs[i].v
+	}
+	return QuadraticElem{
+		v: multiPairing(asV, bsV),
+	}
+}
+
+// PreparedPoint holds the precomputed Miller loop line coefficients of a
+// fixed elliptic curve point. A PreparedPoint instance is read-only once
+// created, thus safe for concurrent use.
+type PreparedPoint struct {
+	v *prepP
+}
+
+// NewPreparedPoint precomputes the pairing of the given point 'p', which
+// pays off when 'p' is paired with many other points
+func NewPreparedPoint(p EllipticPoint) *PreparedPoint {
+	return &PreparedPoint{
+		v: newPrepP(p.v),
+	}
+}
+
+// IsInfinity validates if the prepared point is the infinity point
+func (p *PreparedPoint) IsInfinity() bool {
+	return p.v.inf
+}
+
+// psuedo-constant, lazily built
+var (
+	genPrepOnce sync.Once
+	genPrep     *PreparedPoint
+)
+
+// GetPreparedGenerator returns the prepared form of the generator
+func GetPreparedGenerator() *PreparedPoint {
+	genPrepOnce.Do(func() {
+		genPrep = NewPreparedPoint(GetGenerator())
+	})
+	return genPrep
+}
+
+// BiLinearMapPrepared returns the same result that BiLinearMap(p, q) does,
+// reusing the precomputation of 'p'. Note that the pairing is symmetric, so
+// either param of a pairing can be the prepared one.
+func BiLinearMapPrepared(p *PreparedPoint, q EllipticPoint) QuadraticElem {
+	return MultiPairingPrepared([]*PreparedPoint{p}, []EllipticPoint{q})
+}
+
+// MultiPairingPrepared works in the same way that MultiPairing does,
+// with the prepared first params 'ps'
+// WARNING: the infinity points are not rejected either, see MultiPairing
+func MultiPairingPrepared(ps []*PreparedPoint, qs []EllipticPoint) QuadraticElem {
+	psV := make([]*prepP, len(ps))
+	for i := range ps {
+		psV[i] = ps[i].v
+	}
+	qsV := make([]*curP, len(qs))
+	for i := range qs {
+		qsV[i] = qs[i].v
+	}
+	return QuadraticElem{
+		v: multiPairingPrepared(psV, qsV),
+	}
+}
+
 // QuadraticEqual validate if 2 quadratic Galois field
 // elements' value is equal to each other
 func QuadraticEqual(a, b QuadraticElem) bool {
 	return a.v.equal(b.v)
 }
 
+// QuadraticIdentity returns the multiplicative identity of the quadratic
+// Galois field, which is also the identity of the pairing results
+func QuadraticIdentity() QuadraticElem {
+	return QuadraticElem{
+		v: newQuadE().setIdentity(),
+	}
+}
+
 // QuadraticPow returns the result of power calculation in
 // a Galois-based quadratic field
 func QuadraticPow(b QuadraticElem, e GaloisElem) QuadraticElem {
 	return QuadraticElem{
-		v: newQuadE().powN(b.v, e.v.val),
+		v: newQuadE().powN(b.v, e.v.bigInt()),
 	}
 }
 
@@ -215,10 +508,88 @@ func QuadraticMul(lhs, rhs QuadraticElem) QuadraticElem {
 }
 
 // EllipticPow returns the result of power calculation on
-// elliptic curve
+// elliptic curve. The precomputed table is used for the generator.
 func EllipticPow(g EllipticPoint, x GaloisElem) EllipticPoint {
+	if g.v == gen || g.v.equal(gen) {
+		return EllipticPoint{
+			v: genBase.pow(newCurP(), x.v.bigInt()),
+		}
+	}
+	return EllipticPoint{
+		v: newCurP().powN(g.v, x.v.bigInt()),
+	}
+}
+
+// EllipticPowSecret works just as EllipticPow does, except that its running
+// time does not depend on the value of 'x', which should be used for the
+// secret exponents, e.g. the private keys. The point 'g' should be in the
+// subgroup that the generator belongs to.
+func EllipticPowSecret(g EllipticPoint, x GaloisElem) EllipticPoint {
+	var k montElem
+	gFR.mont.fromMont(&k, &x.v.m)
 	return EllipticPoint{
-		v: newCurP().powN(g.v, x.v.val),
+		v: powNSecret(g.v, &k),
+	}
+}
+
+// FixedBase holds the precomputed table of a fixed elliptic curve point,
+// which speeds up the power calculation of that point. A FixedBase
+// instance is read-only once created, thus safe for concurrent use.
+type FixedBase struct {
+	v *fixedBase
+}
+
+// NewFixedBase precomputes the table of the given point 'p', which pays
+// off when 'p' is raised to many different powers
+func NewFixedBase(p EllipticPoint) *FixedBase {
+	return &FixedBase{
+		v: newFixedBase(p.v, gFR.ord.BitLen(), nil),
+	}
+}
+
+// Pow returns the same result that EllipticPow(p, x) does
+func (fb *FixedBase) Pow(x GaloisElem) EllipticPoint {
+	return EllipticPoint{
+		v: fb.v.pow(newCurP(), x.v.bigInt()),
+	}
+}
+
+// MultiExp returns Prod(ps[i]^xs[i]) by Pippenger's bucket method, which is
+// much faster than multiplying the EllipticPow results for many points. It
+// panics if the num of 'ps' & 'xs' does not match.
+func MultiExp(ps []EllipticPoint, xs []GaloisElem) EllipticPoint {
+	psV, xsV := multiExpValues(ps, xs)
+	return EllipticPoint{
+		v: multiExp(psV, xsV).toAffine(newCurP()),
+	}
+}
+
+// MultiExpParallel works just as MultiExp does, except that the work is
+// split across goroutines
+func MultiExpParallel(ps []EllipticPoint, xs []GaloisElem) EllipticPoint {
+	psV, xsV := multiExpValues(ps, xs)
+	return EllipticPoint{
+		v: multiExpParallel(psV, xsV).toAffine(newCurP()),
+	}
+}
+
+func multiExpValues(ps []EllipticPoint, xs []GaloisElem) ([]*curP, []*big.Int) {
+	psV := make([]*curP, len(ps))
+	for i := range ps {
+		psV[i] = ps[i].v
+	}
+	xsV := make([]*big.Int, len(xs))
+	for i := range xs {
+		xsV[i] = xs[i].v.bigInt()
+	}
+	return psV, xsV
+}
+
+// EllipticNeg returns the inverse of the given elliptic curve point, i.e.
+// the point that is x-axis symmetrical to it
+func EllipticNeg(p EllipticPoint) EllipticPoint {
+	return EllipticPoint{
+		v: newCurP().neg(p.v),
 	}
 }
 
@@ -253,3 +624,18 @@ func GaloisAdd(lhs, rhs GaloisElem) GaloisElem {
 		v: newGalE(lhs.v.fld).add(lhs.v, rhs.v),
 	}
 }
+
+// GaloisSub returns the difference of the given Galois field elements
+// Note that this require all the given elements comes from same field
+func GaloisSub(lhs, rhs GaloisElem) GaloisElem {
+	return GaloisElem{
+		v: newGalE(lhs.v.fld).sub(lhs.v, rhs.v),
+	}
+}
+
+// GaloisInv returns the inverse of the given non-zero Galois field element
+func GaloisInv(a GaloisElem) GaloisElem {
+	return GaloisElem{
+		v: newGalE(a.v.fld).inv(a.v),
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package math
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// the max num of 64-bit limbs, enough for the 512-bit gFQ
+const montMaxLimbs = 8
+
+// montElem is a field element 'a' in Montgomery form, i.e. a * R mod p in
+// little-endian 64-bit limbs, where R = 2^(64*n) with 'n' the num of limbs of
+// 'p'. Only the first 'n' limbs of the field are used.
+type montElem [montMaxLimbs]uint64
+
+// montField holds the constants of the Montgomery arithmetic modulo 'p'.
+// Unlike the big.Int backed galE, the running time of its routines depends on
+// the field only, never on the values of the operands, so they are safe for
+// the secrets.
+type montField struct {
+	n int
+	p montElem
+	// -p^-1 mod 2^64
+	pInv uint64
+	// R mod p, i.e. 1 in Montgomery form
+	one montElem
+	// R^2 mod p, for the conversion to Montgomery form
+	r2 montElem
+	// p - 2, the public exponent of the inversion
+	pMinus2 *big.Int
+}
+
+func newMontField(p *big.Int) *montField {
+	n := (p.BitLen() + 63) / 64
+	if n > montMaxLimbs || p.Bit(0) == 0 {
+		panic(errInitMontField)
+	}
+
+	f := &montField{
+		n:       n,
+		pMinus2: new(big.Int).Sub(p, intTwo),
+	}
+	f.p = f.limbs(p)
+
+	// Newton's iteration doubles the correct bits of p^-1 mod 2^64 each time
+	inv := uint64(1)
+	for i := 0; i < 6; i++ {
+		inv *= 2 - f.p[0]*inv
+	}
+	f.pInv = -inv
+
+	r := new(big.Int).Lsh(intOne, uint(64*n))
+	f.one = f.limbs(new(big.Int).Mod(r, p))
+	f.r2 = f.limbs(new(big.Int).Mod(new(big.Int).Mul(r, r), p))
+	return f
+}
+
+// limbs splits a non-negative 'v' < 2^(64*n) into limbs
+func (f *montField) limbs(v *big.Int) montElem {
+	var z montElem
+	data := v.Bytes()
+	for i := 0; i < len(data); i++ {
+		z[i/8] |= uint64(data[len(data)-1-i]) << uint(8*(i%8))
+	}
+	return z
+}
+
+// fromBig sets 'z' to the Montgomery form of 'v', which should be in [0, p)
+func (f *montField) fromBig(z *montElem, v *big.Int) *montElem {
+	a := f.limbs(v)
+	return f.mul(z, &a, &f.r2)
+}
+
+// fromMont sets 'z' to the ordinary value of 'a' in all the n limbs, which
+// takes the same time whatever 'a' is
+func (f *montField) fromMont(z, a *montElem) *montElem {
+	var one montElem
+	one[0] = 1
+	return f.mul(z, a, &one)
+}
+
+// toBig returns the ordinary value of 'a'
+func (f *montField) toBig(a *montElem) *big.Int {
+	var z montElem
+	f.fromMont(&z, a)
+
+	data := make([]byte, 8*f.n)
+	for i := 0; i < len(data); i++ {
+		data[len(data)-1-i] = byte(z[i/8] >> uint(8*(i%8)))
+	}
+	return new(big.Int).SetBytes(data)
+}
+
+// reduce sets 'z' to (hi, t) - p if that does not borrow, to 't' otherwise,
+// where 'hi' is the carry limb above 't'
+func (f *montField) reduce(z, t *montElem, hi uint64) {
+	var d montElem
+	var b uint64
+	for i := 0; i < f.n; i++ {
+		d[i], b = bits.Sub64(t[i], f.p[i], b)
+	}
+	_, b = bits.Sub64(hi, 0, b)
+	// b == 1 keeps 't'
+	mask := -b
+	for i := 0; i < f.n; i++ {
+		z[i] = t[i]&mask | d[i]&^mask
+	}
+}
+
+// add sets z = a + b
+func (f *montField) add(z, a, b *montElem) *montElem {
+	var t montElem
+	var c uint64
+	for i := 0; i < f.n; i++ {
+		t[i], c = bits.Add64(a[i], b[i], c)
+	}
+	f.reduce(z, &t, c)
+	return z
+}
+
+// sub sets z = a - b
+func (f *montField) sub(z, a, b *montElem) *montElem {
+	var br uint64
+	for i := 0; i < f.n; i++ {
+		z[i], br = bits.Sub64(a[i], b[i], br)
+	}
+	// add 'p' back on borrow
+	mask := -br
+	var c uint64
+	for i := 0; i < f.n; i++ {
+		z[i], c = bits.Add64(z[i], f.p[i]&mask, c)
+	}
+	return z
+}
+
+// neg sets z = -a
+func (f *montField) neg(z, a *montElem) *montElem {
+	var zero montElem
+	return f.sub(z, &zero, a)
+}
+
+// mul sets z = a * b * R^-1 by the CIOS method, which is the product of 'a'
+// & 'b' in Montgomery form
+func (f *montField) mul(z, a, b *montElem) *montElem {
+	var t [montMaxLimbs + 2]uint64
+	n := f.n
+	// the slices of the same length let the compiler drop the bound checks
+	as, ps, ts := a[:n], f.p[:n], t[:n]
+	for i := 0; i < n; i++ {
+		// t += a * b[i]
+		bi := b[i]
+		var c, cc uint64
+		for j, aj := range as {
+			hi, lo := bits.Mul64(aj, bi)
+			lo, cc = bits.Add64(lo, ts[j], 0)
+			hi += cc
+			lo, cc = bits.Add64(lo, c, 0)
+			hi += cc
+			ts[j], c = lo, hi
+		}
+		t[n], cc = bits.Add64(t[n], c, 0)
+		t[n+1] = cc
+
+		// t = (t + m * p) / 2^64, which clears the lowest limb
+		m := ts[0] * f.pInv
+		hi, lo := bits.Mul64(m, ps[0])
+		_, cc = bits.Add64(lo, ts[0], 0)
+		c = hi + cc
+		for j := 1; j < len(ps); j++ {
+			hi, lo = bits.Mul64(m, ps[j])
+			lo, cc = bits.Add64(lo, ts[j], 0)
+			hi += cc
+			lo, cc = bits.Add64(lo, c, 0)
+			hi += cc
+			ts[j-1], c = lo, hi
+		}
+		t[n-1], cc = bits.Add64(t[n], c, 0)
+		t[n] = t[n+1] + cc
+	}
+
+	var res montElem
+	copy(res[:n], t[:n])
+	f.reduce(z, &res, t[n])
+	return z
+}
+
+// sqr sets z = a^2
+func (f *montField) sqr(z, a *montElem) *montElem {
+	return f.mul(z, a, a)
+}
+
+// inv sets z = a^-1 = a^(p-2). The exponent is public, thus the square &
+// multiply pattern leaks nothing about 'a'. The inverse of 0 is 0.
+func (f *montField) inv(z, a *montElem) *montElem {
+	res := f.one
+	base := *a
+	for i := f.pMinus2.BitLen() - 1; i >= 0; i-- {
+		f.sqr(&res, &res)
+		if f.pMinus2.Bit(i) == 1 {
+			f.mul(&res, &res, &base)
+		}
+	}
+	*z = res
+	return z
+}
+
+// isZero returns 1 if 'a' is 0, 0 otherwise
+func (f *montField) isZero(a *montElem) uint64 {
+	var acc uint64
+	for i := 0; i < f.n; i++ {
+		acc |= a[i]
+	}
+	// the top bit of acc | -acc is set for any non-zero acc
+	return 1 ^ (acc|-acc)>>63
+}
+
+// equal returns 1 if 'a' == 'b', 0 otherwise
+func (f *montField) equal(a, b *montElem) uint64 {
+	var d montElem
+	for i := 0; i < f.n; i++ {
+		d[i] = a[i] ^ b[i]
+	}
+	return f.isZero(&d)
+}
+
+// montSwap swaps 'a' & 'b' if 'c' == 1, leaves them as is if 'c' == 0
+func montSwap(a, b *montElem, c uint64) {
+	mask := -c
+	for i := range a {
+		t := (a[i] ^ b[i]) & mask
+		a[i] ^= t
+		b[i] ^= t
+	}
+}
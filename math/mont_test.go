@@ -0,0 +1,180 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package math
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const montTestRound = 64
+
+func TestMontField(t *testing.T) {
+	for _, fld := range []*galF{gFQ, gFR} {
+		f := fld.mont
+		p := fld.ord
+		edges := []*big.Int{
+			big.NewInt(0),
+			big.NewInt(1),
+			new(big.Int).Sub(p, intOne),
+			new(big.Int).Sub(p, intTwo),
+		}
+		for i := 0; i < montTestRound; i++ {
+			v, err := rand.Int(rand.Reader, p)
+			assert.NoError(t, err)
+			edges = append(edges, v)
+		}
+
+		for i, a := range edges {
+			b := edges[(i*7+3)%len(edges)]
+			var am, bm, z montElem
+			f.fromBig(&am, a)
+			f.fromBig(&bm, b)
+			assert.Equal(t, 0, f.toBig(&am).Cmp(a))
+
+			expected := new(big.Int).Add(a, b)
+			assert.Equal(t, 0, f.toBig(f.add(&z, &am, &bm)).Cmp(expected.Mod(expected, p)))
+			expected = new(big.Int).Sub(a, b)
+			assert.Equal(t, 0, f.toBig(f.sub(&z, &am, &bm)).Cmp(expected.Mod(expected, p)))
+			expected = new(big.Int).Neg(a)
+			assert.Equal(t, 0, f.toBig(f.neg(&z, &am)).Cmp(expected.Mod(expected, p)))
+			expected = new(big.Int).Mul(a, b)
+			assert.Equal(t, 0, f.toBig(f.mul(&z, &am, &bm)).Cmp(expected.Mod(expected, p)))
+			expected = new(big.Int).Mul(a, a)
+			assert.Equal(t, 0, f.toBig(f.sqr(&z, &am)).Cmp(expected.Mod(expected, p)))
+
+			if a.Sign() == 0 {
+				assert.Equal(t, uint64(1), f.isZero(&am))
+				assert.Equal(t, 0, f.toBig(f.inv(&z, &am)).Sign())
+				continue
+			}
+			assert.Equal(t, uint64(0), f.isZero(&am))
+			expected = new(big.Int).ModInverse(a, p)
+			assert.Equal(t, 0, f.toBig(f.inv(&z, &am)).Cmp(expected))
+			assert.Equal(t, uint64(1), f.equal(&am, &am))
+		}
+	}
+}
+
+func TestMontSwap(t *testing.T) {
+	var a, b montElem
+	gFQ.mont.fromBig(&a, big.NewInt(3))
+	gFQ.mont.fromBig(&b, big.NewInt(5))
+	x, y := a, b
+
+	montSwap(&a, &b, 0)
+	assert.Equal(t, x, a)
+	assert.Equal(t, y, b)
+	montSwap(&a, &b, 1)
+	assert.Equal(t, y, a)
+	assert.Equal(t, x, b)
+}
+
+func TestCtPAdd(t *testing.T) {
+	a, err := randCurP()
+	assert.NoError(t, err)
+	b, err := randCurP()
+	assert.NoError(t, err)
+
+	pa := newCtP().setAffine(a)
+	pb := newCtP().setAffine(b)
+	inf := newCtP()
+	assert.True(t, newCtP().add(pa, pb).toAffine(newCurP()).equal(newCurP().add(a, b)))
+	assert.True(t, newCtP().add(pa, pa).toAffine(newCurP()).equal(newCurP().double(a)))
+	assert.True(t, newCtP().add(pa, inf).toAffine(newCurP()).equal(a))
+	assert.True(t, newCtP().add(inf, pa).toAffine(newCurP()).equal(a))
+	assert.True(t, newCtP().add(inf, inf).toAffine(newCurP()).inf)
+	negA := newCtP().setAffine(newCurP().neg(a))
+	assert.True(t, newCtP().add(pa, negA).toAffine(newCurP()).inf)
+}
+
+func TestEllipticPowSecret(t *testing.T) {
+	a, err := RandEllipticPt()
+	assert.NoError(t, err)
+
+	edges := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(2),
+		new(big.Int).Sub(gFR.ord, intOne),
+		new(big.Int).Set(gFR.ord),
+		new(big.Int).Add(gFR.ord, intTwo),
+	}
+	for i := 0; i < ellipticTestRound; i++ {
+		x, err := RandGaloisElem()
+		assert.NoError(t, err)
+		edges = append(edges, x.v.bigInt())
+	}
+	for _, n := range edges {
+		x := GaloisElem{v: newGalE(gFR).setV(n)}
+		assert.True(t, EllipticPowSecret(a, x).v.equal(EllipticPow(a, x).v))
+		k := gFR.mont.fromMont(new(montElem), &x.v.m)
+		assert.True(t, powNSecret(a.v, k).equal(newCurP().powN(a.v, n)))
+	}
+
+	inf := EllipticPoint{v: newCurIdentity()}
+	x, err := RandGaloisElem()
+	assert.NoError(t, err)
+	assert.True(t, EllipticPowSecret(inf, x).v.inf)
+}
+
+func TestPowNSecretZeroPrefix(t *testing.T) {
+	a, err := RandEllipticPt()
+	assert.NoError(t, err)
+
+	// the scalars with leading zero bytes still fill all the limbs, so the
+	// ladder walks the same bits & allocates just as for a full one
+	full := GaloisElem{v: newGalE(gFR).setV(new(big.Int).Sub(gFR.ord, intOne))}
+	small := GaloisElem{v: newGalE(gFR).setV(big.NewInt(0x1234))}
+
+	k := gFR.mont.fromMont(new(montElem), &small.v.m)
+	assert.Equal(t, montElem{0x1234}, *k)
+	assert.True(t, EllipticPowSecret(a, small).v.equal(EllipticPow(a, small).v))
+
+	fullAllocs := testing.AllocsPerRun(10, func() { EllipticPowSecret(a, full) })
+	smallAllocs := testing.AllocsPerRun(10, func() { EllipticPowSecret(a, small) })
+	assert.Equal(t, fullAllocs, smallAllocs)
+}
+
+func BenchmarkMontMul(b *testing.B) {
+	var x, y montElem
+	v, err := rand.Int(rand.Reader, gFQ.ord)
+	assert.NoError(b, err)
+	gFQ.mont.fromBig(&x, v)
+	gFQ.mont.fromBig(&y, v)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gFQ.mont.mul(&x, &x, &y)
+	}
+}
+
+func BenchmarkEllipticPowSecret(b *testing.B) {
+	a, err := RandEllipticPt()
+	assert.NoError(b, err)
+	x, err := RandGaloisElem()
+	assert.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		EllipticPowSecret(a, x)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package math
+
+import (
+	"math/big"
+	"runtime"
+	"sync"
+)
+
+const (
+	// below this num of points the bucket method does not pay off
+	multiExpMinPoints = 4
+	// the max window width of the bucket method
+	multiExpMaxWidth = 16
+
+	errUnmatchedMultiExpParams = "Unmatched num of the multi-exponentiation params"
+)
+
+// multiExpWidth picks the window width 'c' minimizing the num of point
+// additions of the bucket method, which is roughly
+// (bits / c) * (n + 2^c) for 'n' exponents of 'bits' bits
+func multiExpWidth(n, bits int) uint {
+	best, bestCost := uint(1), -1
+	for c := 1; c <= multiExpMaxWidth; c++ {
+		cost := (bits + c - 1) / c * (n + 1<<uint(c))
+		if bestCost < 0 || cost < bestCost {
+			best, bestCost = uint(c), cost
+		}
+	}
+	return best
+}
+
+// windowDigit returns the 'c' bits of 'n' starting from bit 'start'
+func windowDigit(n *big.Int, start, c uint) int {
+	d := 0
+	for k := int(c) - 1; k >= 0; k-- {
+		d = d<<1 | int(n.Bit(int(start)+k))
+	}
+	return d
+}
+
+// multiExpWindow sums up Sum(d_i * P_i) for the 'c'-bit digits d_i of the
+// window starting from bit 'start', by Pippenger's bucket method: each P_i
+// is added to bucket d_i, and the buckets are then summed up as
+// Sum(j * B_j) = Sum(Sum(B_k, k >= j)) with 2 additions per bucket
+func multiExpWindow(ps []*curP, ns []*big.Int, start, c uint) *jacP {
+	buckets := make([]*jacP, 1<<c-1)
+	for j := range buckets {
+		buckets[j] = newJacP()
+	}
+	for i := range ps {
+		if d := windowDigit(ns[i], start, c); d != 0 {
+			buckets[d-1].addAffine(buckets[d-1], ps[i])
+		}
+	}
+
+	sum := newJacP()
+	res := newJacP()
+	for j := len(buckets) - 1; j >= 0; j-- {
+		sum.add(sum, buckets[j])
+		res.add(res, sum)
+	}
+	return res
+}
+
+// multiExpParams drops the trivial terms & returns the window width & num
+func multiExpParams(ps []*curP, ns []*big.Int) ([]*curP, []*big.Int, uint, int) {
+	if len(ps) != len(ns) {
+		panic(errUnmatchedMultiExpParams)
+	}
+
+	psR := make([]*curP, 0, len(ps))
+	nsR := make([]*big.Int, 0, len(ns))
+	bits := 0
+	for i := range ps {
+		if ps[i].inf || ns[i].Sign() <= 0 {
+			continue
+		}
+		psR = append(psR, ps[i])
+		nsR = append(nsR, ns[i])
+		if ns[i].BitLen() > bits {
+			bits = ns[i].BitLen()
+		}
+	}
+
+	c := multiExpWidth(len(psR), bits)
+	return psR, nsR, c, (bits + int(c) - 1) / int(c)
+}
+
+// multiExpNaive calculates Prod(ps[i]^ns[i]) one term by another, which
+// works better for a few terms
+func multiExpNaive(ps []*curP, ns []*big.Int) *jacP {
+	res := newJacP()
+	for i := range ps {
+		res.add(res, powNJacobian(ps[i], ns[i]))
+	}
+	return res
+}
+
+// multiExp calculates Prod(ps[i]^ns[i]) window by window, from the highest
+// window to the lowest one
+func multiExp(ps []*curP, ns []*big.Int) *jacP {
+	ps, ns, c, windows := multiExpParams(ps, ns)
+	if len(ps) < multiExpMinPoints {
+		return multiExpNaive(ps, ns)
+	}
+
+	res := newJacP()
+	for w := windows - 1; w >= 0; w-- {
+		for k := uint(0); k < c; k++ {
+			res.double(res)
+		}
+		res.add(res, multiExpWindow(ps, ns, uint(w)*c, c))
+	}
+	return res
+}
+
+// multiExpParallel works just as multiExp does, except that the windows are
+// summed up by concurrent goroutines
+func multiExpParallel(ps []*curP, ns []*big.Int) *jacP {
+	ps, ns, c, windows := multiExpParams(ps, ns)
+	if len(ps) < multiExpMinPoints {
+		return multiExpNaive(ps, ns)
+	}
+
+	sums := make([]*jacP, windows)
+	workers := runtime.GOMAXPROCS(0)
+	if workers > windows {
+		workers = windows
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for k := 0; k < workers; k++ {
+		go func(k int) {
+			defer wg.Done()
+			for w := k; w < windows; w += workers {
+				sums[w] = multiExpWindow(ps, ns, uint(w)*c, c)
+			}
+		}(k)
+	}
+	wg.Wait()
+
+	res := newJacP()
+	for w := windows - 1; w >= 0; w-- {
+		for k := uint(0); k < c; k++ {
+			res.double(res)
+		}
+		res.add(res, sums[w])
+	}
+	return res
+}
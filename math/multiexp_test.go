@@ -0,0 +1,114 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package math
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func genMultiExpParams(n int) ([]EllipticPoint, []GaloisElem, error) {
+	ps := make([]EllipticPoint, n)
+	xs := make([]GaloisElem, n)
+	for i := 0; i < n; i++ {
+		p, err := RandEllipticPt()
+		if err != nil {
+			return nil, nil, err
+		}
+		x, err := RandGaloisElem()
+		if err != nil {
+			return nil, nil, err
+		}
+		ps[i], xs[i] = p, x
+	}
+	return ps, xs, nil
+}
+
+func multiExpByLoop(ps []EllipticPoint, xs []GaloisElem) EllipticPoint {
+	res := EllipticPoint{v: newCurIdentity()}
+	for i := range ps {
+		res = EllipticMul(res, EllipticPow(ps[i], xs[i]))
+	}
+	return res
+}
+
+func TestMultiExp(t *testing.T) {
+	for _, n := range []int{0, 1, 3, 4, 17, 64} {
+		ps, xs, err := genMultiExpParams(n)
+		assert.NoError(t, err)
+
+		expected := multiExpByLoop(ps, xs)
+		assert.True(t, MultiExp(ps, xs).v.equal(expected.v), "n: %d", n)
+		assert.True(t, MultiExpParallel(ps, xs).v.equal(expected.v), "n: %d", n)
+	}
+
+	assert.Panics(t, func() {
+		MultiExp([]EllipticPoint{GetGenerator()}, nil)
+	})
+}
+
+func TestMultiExpEdges(t *testing.T) {
+	ps, xs, err := genMultiExpParams(8)
+	assert.NoError(t, err)
+
+	// the zero exponents, the infinity points & the repeated points
+	xs[0] = GaloisElem{v: newGalZero(gFR)}
+	ps[1] = EllipticPoint{v: newCurIdentity()}
+	ps[2], xs[2] = ps[3], xs[3]
+	ps[4] = EllipticNeg(ps[5])
+	xs[4] = xs[5]
+	xs[6] = GaloisElem{v: newGalOne(gFR)}
+
+	expected := multiExpByLoop(ps, xs)
+	assert.True(t, MultiExp(ps, xs).v.equal(expected.v))
+	assert.True(t, MultiExpParallel(ps, xs).v.equal(expected.v))
+}
+
+func TestMultiExpWidth(t *testing.T) {
+	assert.Equal(t, uint(1), multiExpWidth(0, 160))
+	for _, n := range []int{16, 256, 4096} {
+		c := multiExpWidth(n, 160)
+		assert.True(t, c > 1 && c <= multiExpMaxWidth)
+		assert.True(t, c >= multiExpWidth(n/16, 160))
+	}
+}
+
+func BenchmarkMultiExp(b *testing.B) {
+	for _, n := range []int{16, 256} {
+		ps, xs, err := genMultiExpParams(n)
+		assert.NoError(b, err)
+
+		b.Run(fmt.Sprintf("loop/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				multiExpByLoop(ps, xs)
+			}
+		})
+		b.Run(fmt.Sprintf("pippenger/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				MultiExp(ps, xs)
+			}
+		})
+		b.Run(fmt.Sprintf("parallel/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				MultiExpParallel(ps, xs)
+			}
+		})
+	}
+}
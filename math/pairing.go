@@ -27,7 +27,8 @@ const (
 	exp2 = 159
 	exp1 = 107
 
-	errInitPairingParam = "Failed to initialize the parameter of the pairing structure"
+	errInitPairingParam       = "Failed to initialize the parameter of the pairing structure"
+	errUnmatchedPairingParams = "Unmatched num of the pairing params"
 )
 
 // pseudo-constant
@@ -131,11 +132,12 @@ func calcLine(t0, t1, t2, t3, aX, aY, bX, bY, cX, cY *galE, f0, f1 *quadE) {
 	f0.mul(f0, f1)
 }
 
-// this is a bi-linear map of the pairing from elliptic field to itself
-func projPairing(out *quadE, in1, in2 *curP) {
+// millerLoop calculates the Miller loop part of the pairing into 'f', the
+// final exponentiation is left to the caller
+// WARNING: in1's content will change
+func millerLoop(f *quadE, in1, in2 *curP) {
 	in1Dup := dupCurP(in1)
 	// intermediate result holders
-	f := newQuadE().setIdentity()
 	f0 := newQuadE()
 	f1 := newQuadE()
 	t0 := newGalZero(gFQ)
@@ -145,6 +147,7 @@ func projPairing(out *quadE, in1, in2 *curP) {
 	z := newGalOne(gFQ)
 	zSqr := newGalOne(gFQ)
 
+	f.setIdentity()
 	// projection calculation
 	i := int(0)
 	for ; i < exp1; i++ {
@@ -166,7 +169,18 @@ func projPairing(out *quadE, in1, in2 *curP) {
 	f.mul(f, f1)
 	toAffine(t0, in1.x, in1.y, z, zSqr)
 	calcLine(t0, t1, t2, t3, in1.x, in1.y, in1Dup.x, in1Dup.y, in2.x, in2.y, f, f0)
-	calcTateExp(out, f, f0, phi)
+}
+
+// this is a bi-linear map of the pairing from elliptic field to itself
+func projPairing(out *quadE, in1, in2 *curP) {
+	if in1.inf || in2.inf {
+		out.setIdentity()
+		return
+	}
+
+	f := newQuadE()
+	millerLoop(f, in1, in2)
+	calcTateExp(out, f, newQuadE(), phi)
 }
 
 // wrapper
@@ -177,3 +191,120 @@ func biLinearMap(a, b *curP) *quadE {
 	projPairing(r, aDup, bDup)
 	return r
 }
+
+// multiPairing returns Prod(e(as[i], bs[i])). The Miller loop results are
+// multiplied together so that the final exponentiation is done only once.
+// The pairs with the infinity point are skipped, as e(O, b) == e(a, O) == 1.
+func multiPairing(as, bs []*curP) *quadE {
+	if len(as) != len(bs) {
+		panic(errUnmatchedPairingParams)
+	}
+
+	f := newQuadE().setIdentity()
+	tmp := newQuadE()
+	for i := range as {
+		if as[i].inf || bs[i].inf {
+			continue
+		}
+		millerLoop(tmp, dupCurP(as[i]), bs[i])
+		f.mul(f, tmp)
+	}
+
+	r := newQuadE()
+	calcTateExp(r, f, tmp, phi)
+	return r
+}
+
+// prepP holds the line coefficients (a, b, c) of the Miller loop for a fixed
+// first pairing param, which only depend on that param. The lines are the
+// 'exp2' tangents followed by the final chord.
+type prepP struct {
+	inf   bool
+	coefs [][3]*galE
+}
+
+func newPrepP(a *curP) *prepP {
+	if a.inf {
+		return &prepP{inf: true}
+	}
+
+	in1 := dupCurP(a)
+	in1Dup := dupCurP(a)
+	// intermediate result holders
+	t0 := newGalZero(gFQ)
+	t1 := newGalZero(gFQ)
+	t2 := newGalZero(gFQ)
+	t3 := newGalZero(gFQ)
+	z := newGalOne(gFQ)
+	zSqr := newGalOne(gFQ)
+
+	coefs := make([][3]*galE, 0, exp2+1)
+	save := func() {
+		coefs = append(coefs, [3]*galE{
+			newGalE(gFQ).set(t1),
+			newGalE(gFQ).set(t2),
+			newGalE(gFQ).set(t3),
+		})
+	}
+
+	for i := 0; i < exp2; i++ {
+		// the same steps as millerLoop() does
+		if i == exp1 {
+			toAffine(t0, in1.x, in1.y, z, zSqr)
+			in1Dup.set(in1)
+		}
+		projABCTan(t0, t1, t2, t3, in1.x, in1.y, z, zSqr)
+		save()
+		projDouble(t0, t1, t2, t3, in1.x, in1.y, z, zSqr)
+	}
+
+	toAffine(t0, in1.x, in1.y, z, zSqr)
+	calcABCLine(t0, t1, t2, t3, in1.x, in1.y, in1Dup.x, in1Dup.y)
+	save()
+
+	return &prepP{coefs: coefs}
+}
+
+// millerLoopPrepared works in the same way that millerLoop does, using
+// the precomputed line coefficients of the first param
+func millerLoopPrepared(f *quadE, p *prepP, q *curP) {
+	f0 := newQuadE()
+	f1 := newQuadE()
+
+	f.setIdentity()
+	for i, c := range p.coefs[:exp2] {
+		if i == exp1 {
+			f1.set(f)
+		}
+		f.sqr(f)
+		evalMiller(f0, c[0], c[1], c[2], q.x, q.y)
+		f.mul(f, f0)
+	}
+
+	f.mul(f, f1)
+	c := p.coefs[exp2]
+	evalMiller(f0, c[0], c[1], c[2], q.x, q.y)
+	f.mul(f, f0)
+}
+
+// multiPairingPrepared returns Prod(e(ps[i], qs[i])) with the prepared
+// first params, where the pairs with the infinity point are skipped
+func multiPairingPrepared(ps []*prepP, qs []*curP) *quadE {
+	if len(ps) != len(qs) {
+		panic(errUnmatchedPairingParams)
+	}
+
+	f := newQuadE().setIdentity()
+	tmp := newQuadE()
+	for i := range ps {
+		if ps[i].inf || qs[i].inf {
+			continue
+		}
+		millerLoopPrepared(tmp, ps[i], qs[i])
+		f.mul(f, tmp)
+	}
+
+	r := newQuadE()
+	calcTateExp(r, f, tmp, phi)
+	return r
+}
@@ -115,3 +115,107 @@ func TestBiLinearMapByProperty(t *testing.T) {
 		assert.True(t, lhs.equal(rhs))
 	}
 }
+
+func TestMultiPairing(t *testing.T) {
+	for n := 0; n < 5; n++ {
+		as := make([]EllipticPoint, n)
+		bs := make([]EllipticPoint, n)
+		expected := QuadraticIdentity()
+		for i := 0; i < n; i++ {
+			a, err := RandEllipticPt()
+			assert.NoError(t, err)
+			b, err := RandEllipticPt()
+			assert.NoError(t, err)
+			as[i], bs[i] = a, b
+			expected = QuadraticMul(expected, BiLinearMap(a, b))
+		}
+		assert.True(t, QuadraticEqual(expected, MultiPairing(as, bs)))
+
+		// the params stay unchanged
+		for i := 0; i < n; i++ {
+			assert.True(t, validateCurP(as[i].v))
+			assert.True(t, validateCurP(bs[i].v))
+		}
+	}
+
+	// e(a, b) * e(-a, b) == 1 and the infinity point is skipped
+	a, err := RandEllipticPt()
+	assert.NoError(t, err)
+	b, err := RandEllipticPt()
+	assert.NoError(t, err)
+	inf := EllipticPoint{v: newCurIdentity()}
+	res := MultiPairing([]EllipticPoint{a, EllipticNeg(a), inf}, []EllipticPoint{b, b, b})
+	assert.True(t, QuadraticEqual(res, QuadraticIdentity()))
+	assert.True(t, QuadraticEqual(BiLinearMap(inf, b), QuadraticIdentity()))
+
+	assert.Panics(t, func() { MultiPairing([]EllipticPoint{a}, nil) })
+}
+
+func BenchmarkBiLinearMap(b *testing.B) {
+	u, err := randCurP()
+	assert.NoError(b, err)
+	v, err := randCurP()
+	assert.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		biLinearMap(u, v)
+	}
+}
+
+func BenchmarkMultiPairing(b *testing.B) {
+	as := make([]EllipticPoint, 4)
+	bs := make([]EllipticPoint, 4)
+	for i := range as {
+		var err error
+		as[i], err = RandEllipticPt()
+		assert.NoError(b, err)
+		bs[i], err = RandEllipticPt()
+		assert.NoError(b, err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		MultiPairing(as, bs)
+	}
+}
+
+func TestBiLinearMapPrepared(t *testing.T) {
+	for i := 0; i < pairingTestRound/8; i++ {
+		u, err := RandEllipticPt()
+		assert.NoError(t, err)
+		v, err := RandEllipticPt()
+		assert.NoError(t, err)
+
+		prepU := NewPreparedPoint(u)
+		expected := BiLinearMap(u, v)
+		assert.True(t, QuadraticEqual(expected, BiLinearMapPrepared(prepU, v)))
+		// the pairing is symmetric
+		assert.True(t, QuadraticEqual(expected, BiLinearMapPrepared(NewPreparedPoint(v), u)))
+		// the prepared point can be reused
+		assert.True(t, QuadraticEqual(BiLinearMap(u, u), BiLinearMapPrepared(prepU, u)))
+		assert.True(t, QuadraticEqual(BiLinearMap(GetGenerator(), v), BiLinearMapPrepared(GetPreparedGenerator(), v)))
+
+		res := MultiPairingPrepared([]*PreparedPoint{prepU, GetPreparedGenerator()}, []EllipticPoint{v, EllipticNeg(u)})
+		assert.True(t, QuadraticEqual(res, MultiPairing([]EllipticPoint{u, GetGenerator()}, []EllipticPoint{v, EllipticNeg(u)})))
+	}
+
+	inf := EllipticPoint{v: newCurIdentity()}
+	u, err := RandEllipticPt()
+	assert.NoError(t, err)
+	assert.True(t, QuadraticEqual(BiLinearMapPrepared(NewPreparedPoint(inf), u), QuadraticIdentity()))
+	assert.True(t, QuadraticEqual(BiLinearMapPrepared(NewPreparedPoint(u), inf), QuadraticIdentity()))
+}
+
+func BenchmarkBiLinearMapPrepared(b *testing.B) {
+	u, err := RandEllipticPt()
+	assert.NoError(b, err)
+	v, err := RandEllipticPt()
+	assert.NoError(b, err)
+	prepU := NewPreparedPoint(u)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		BiLinearMapPrepared(prepU, v)
+	}
+}
@@ -18,6 +18,7 @@
 package math
 
 import (
+	"errors"
 	"math/big"
 )
 
@@ -56,6 +57,95 @@ func (e *quadE) setBytes(data []byte) *quadE {
 	return e
 }
 
+// trySetBytes restores the element from the untrusted data, 'e' is left
+// unchanged on error. The data is told apart by its length & leading flag
+// byte, which works the same as the elliptic curve points:
+//
+//	0x02 | y%2, x    : the compressed element of norm 1
+//	0x04, x, y       : the uncompressed element
+//	x, y             : the legacy uncompressed element, without the flag byte
+func (e *quadE) trySetBytes(data []byte) error {
+	l := lenInByte(gFQ.ord)
+	a := newQuadE()
+	switch {
+	case len(data) == 1+l:
+		if data[0]&^1 != ptFlagCompressed {
+			return errors.New(errInvalidElemFlag)
+		}
+		if err := a.trySetX(data[1:], uint(data[0]&1)); err != nil {
+			return err
+		}
+	case len(data) == 1+2*l:
+		if data[0] != ptFlagUncompressed {
+			return errors.New(errInvalidElemFlag)
+		}
+		if err := a.trySetXY(data[1:]); err != nil {
+			return err
+		}
+	case len(data) == 2*l:
+		if err := a.trySetXY(data); err != nil {
+			return err
+		}
+	default:
+		return errors.New(errInvalidElemLen)
+	}
+	e.x, e.y = a.x, a.y
+	return nil
+}
+
+// trySetXY restores the element from x || y
+func (e *quadE) trySetXY(data []byte) error {
+	l := len(data) / 2
+	if err := e.x.trySetBytes(data[:l]); err != nil {
+		return err
+	}
+	return e.y.trySetBytes(data[l:])
+}
+
+// trySetX restores the element of norm 1 from x & the parity of y, where
+// x^2 + y^2 == 1
+func (e *quadE) trySetX(data []byte, parity uint) error {
+	if err := e.x.trySetBytes(data); err != nil {
+		return err
+	}
+	ySqr := newGalE(gFQ).sqr(e.x)
+	ySqr.sub(newGalOne(gFQ), ySqr)
+	if e.y.sqrt(ySqr) == nil {
+		return errors.New(errElemNotOfNorm1)
+	}
+	if e.y.bigInt().Bit(0) != parity {
+		// y == 0 has no odd counterpart
+		if e.y.sign() == 0 {
+			return errors.New(errElemNotOfNorm1)
+		}
+		e.y.neg(e.y)
+	}
+	return nil
+}
+
+// isNorm1 validates if x^2 + y^2 == 1, which holds for all the elements of GT
+func (e *quadE) isNorm1() bool {
+	norm := newGalE(gFQ).sqr(e.x)
+	norm.add(norm, newGalE(gFQ).sqr(e.y))
+	return norm.equal(newGalOne(gFQ))
+}
+
+// compressedBytes encodes the element of norm 1 as 0x02 | y%2, x, the others
+// are encoded as 0x04, x, y
+func (e *quadE) compressedBytes() []byte {
+	if !e.isNorm1() {
+		return append([]byte{ptFlagUncompressed}, e.bytes()...)
+	}
+	flag := byte(ptFlagCompressed) | byte(e.y.bigInt().Bit(0))
+	return append([]byte{flag}, e.x.bytes()...)
+}
+
+// inGT validates if e^r == 1, i.e. 'e' is in the group of the pairing
+// results
+func (e *quadE) inGT() bool {
+	return newQuadE().powN(e, gFR.ord).equal(newQuadE().setIdentity())
+}
+
 func (e *quadE) equal(a *quadE) bool {
 	return e.x.equal(a.x) && e.y.equal(a.y)
 }
@@ -120,6 +210,12 @@ func calcTateExp(out, in, tmp *quadE, cofac *big.Int) {
 	tmp.inv(in)
 	in.y.neg(in.y)
 	in.mul(in, tmp)
+	// a norm-1 element in gFQ is +1/-1, whose power of the even (q + 1)/r is 1,
+	// which is also where the Lucas sequence below cannot handle
+	if in.y.sign() == 0 {
+		out.setIdentity()
+		return
+	}
 	// calc expo by (q + 1)/r using Lucas sequence
 	calcLucasSeq(out, in, tmp, cofac)
 }
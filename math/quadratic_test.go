@@ -118,3 +118,157 @@ func TestQuadraticArith(t *testing.T) {
 		assert.True(t, strings.Compare(toBase64Str(out.bytes()), results[7]) == 0)
 	}
 }
+
+func TestParseQuadraticElem(t *testing.T) {
+	a, err := RandEllipticPt()
+	assert.NoError(t, err)
+	e := BiLinearMap(a, GetGenerator())
+	res, err := ParseQuadraticElem(e.Marshal())
+	assert.NoError(t, err)
+	assert.True(t, QuadraticEqual(res, e))
+
+	valid := e.v.bytes()
+	for _, data := range [][]byte{
+		{},
+		valid[1:],
+		append(append([]byte{}, valid...), 0),
+		append(gFQ.ord.Bytes(), valid[len(valid)/2:]...),
+		append(append([]byte{}, valid[:len(valid)/2]...), gFQ.ord.Bytes()...),
+	} {
+		_, err := ParseQuadraticElem(toBase64Str(data))
+		assert.Error(t, err)
+	}
+}
+
+func TestInGT(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		a, err := RandEllipticPt()
+		assert.NoError(t, err)
+		e := BiLinearMap(a, GetGenerator())
+		assert.True(t, e.InGT())
+
+		// w^(q-1) is of norm 1, but not in GT in general
+		w := newQuadE()
+		w.x, err = randGalE(gFQ)
+		assert.NoError(t, err)
+		w.y, err = randGalE(gFQ)
+		assert.NoError(t, err)
+		conj := newQuadE().set(w)
+		conj.y.neg(conj.y)
+		norm1 := newQuadE().mul(conj, newQuadE().inv(w))
+		// clear the GT part, which leaves an element of order dividing (q+1)/r
+		low := newQuadE().powN(norm1, gFR.ord)
+
+		for _, v := range []*quadE{norm1, low, newQuadE().mul(e.v, low)} {
+			elem := QuadraticElem{v: v}
+			assert.False(t, elem.InGT())
+			_, err := ParseQuadraticElem(elem.Marshal())
+			assert.Error(t, err)
+			res, err := ParseQuadraticElemTrusted(elem.Marshal())
+			assert.NoError(t, err)
+			assert.True(t, QuadraticEqual(res, elem))
+		}
+	}
+
+	// -1 & i = sqrt(-1) are of order 2 & 4
+	minusOne := newQuadE().setIdentity()
+	minusOne.x.neg(minusOne.x)
+	i := newQuadE()
+	i.y.setVI(1)
+	for _, v := range []*quadE{minusOne, i, newQuadE()} {
+		elem := QuadraticElem{v: v}
+		assert.False(t, elem.InGT())
+	}
+	one := QuadraticIdentity()
+	assert.True(t, one.InGT())
+}
+
+func TestCompressedQuadraticElem(t *testing.T) {
+	l := lenInByte(gFQ.ord)
+	for i := 0; i < 16; i++ {
+		a, err := RandEllipticPt()
+		assert.NoError(t, err)
+		e := BiLinearMap(a, GetGenerator())
+
+		compressed := e.CompressedBytes()
+		assert.Equal(t, 1+l, len(compressed))
+		uncompressed := e.UncompressedBytes()
+		legacy := e.Bytes()
+		assert.Equal(t, 2*l, len(legacy))
+		for _, data := range [][]byte{compressed, uncompressed, legacy} {
+			res, err := ParseQuadraticElem(toBase64Str(data))
+			assert.NoError(t, err)
+			assert.True(t, QuadraticEqual(res, e))
+			res, err = ParseQuadraticElemBytes(data)
+			assert.NoError(t, err)
+			assert.True(t, QuadraticEqual(res, e))
+		}
+
+		// the flipped parity results in the conjugate, i.e. the inverse
+		compressed[0] ^= 1
+		res, err := ParseQuadraticElem(toBase64Str(compressed))
+		assert.NoError(t, err)
+		assert.True(t, res.v.equal(newQuadE().inv(e.v)))
+
+		for _, flag := range []byte{0x00, 0x01, 0x04, 0xff} {
+			compressed[0] = flag
+			_, err := ParseQuadraticElem(toBase64Str(compressed))
+			assert.Error(t, err)
+		}
+	}
+
+	one := QuadraticIdentity()
+	res, err := ParseQuadraticElemBytes(one.CompressedBytes())
+	assert.NoError(t, err)
+	assert.True(t, QuadraticEqual(res, one))
+
+	// x with no y of x^2 + y^2 == 1
+	x := newGalE(gFQ).setVI(2)
+	for {
+		ySqr := newGalE(gFQ).sqr(x)
+		ySqr.sub(newGalOne(gFQ), ySqr)
+		if !ySqr.isSqr() {
+			break
+		}
+		x.add(x, newGalOne(gFQ))
+	}
+	_, err = ParseQuadraticElemTrusted(toBase64Str(append([]byte{ptFlagCompressed}, x.bytes()...)))
+	assert.Error(t, err)
+
+	// the elements not of norm 1 are kept uncompressed
+	notNorm1 := QuadraticElem{v: newQuadE()}
+	notNorm1.v.x.setVI(2)
+	assert.Equal(t, 1+2*l, len(notNorm1.CompressedBytes()))
+	res, err = ParseQuadraticElemTrusted(notNorm1.Marshal())
+	assert.NoError(t, err)
+	assert.True(t, QuadraticEqual(res, notNorm1))
+	_, err = ParseQuadraticElem(notNorm1.Marshal())
+	assert.Error(t, err)
+}
+
+func TestQuadraticElemBinary(t *testing.T) {
+	assert.Equal(t, 1+lenInByte(gFQ.ord), QuadraticElemSize)
+	a, err := RandEllipticPt()
+	assert.NoError(t, err)
+	e := BiLinearMap(a, GetGenerator())
+
+	data, err := e.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Equal(t, QuadraticElemSize, len(data))
+	var res QuadraticElem
+	assert.NoError(t, res.UnmarshalBinary(data))
+	assert.True(t, QuadraticEqual(res, e))
+	assert.Error(t, res.UnmarshalBinary(e.UncompressedBytes()))
+
+	// (0, 1) is of norm 1 but outside GT
+	low := QuadraticElem{v: newQuadE()}
+	low.v.y.setVI(1)
+	data, err = low.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Error(t, res.UnmarshalBinary(data))
+
+	notNorm1 := QuadraticElem{v: newQuadE()}
+	notNorm1.v.x.setVI(2)
+	_, err = notNorm1.MarshalBinary()
+	assert.Error(t, err)
+}
@@ -19,16 +19,16 @@ package proofDP
 
 import (
 	"bytes"
-	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"io"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/LambdaIM/proofDP/math"
-	"golang.org/x/crypto/scrypt"
 )
 
 // constant
@@ -38,10 +38,15 @@ const (
 	errGenerateDataTagFmt      = "Failed to generate tag for given data (index:%s): %s"
 	errGenerateDataChalFmt     = "Failed to generate challenge for given data (index:%s): %s"
 	errProveFmt                = "Failed to prove against challenge (index:%s): %s"
+	errProveSetFmt             = "Failed to prove against challenge set: %s"
 	errParseChalFmt            = "Failed to restore Chal: %s"
+	errParseChalSetFmt         = "Failed to restore ChalSet: %s"
 	errParseProofFmt           = "Failed to restore Proof: %s"
 
 	intStrRadix = 10
+
+	// separator between the Chal items in a marshaled ChalSet
+	chalSetSep = ";"
 )
 
 // PublicParams holds the public paramters of a specific PDP proof.
@@ -51,42 +56,99 @@ type PublicParams struct {
 	v math.EllipticPoint
 	u math.EllipticPoint
 	e math.QuadraticElem
+	// the per-sector generators u_1..u_s in sector mode, where u_1 == u;
+	// nil for the PublicParams working on block digests only
+	us []math.EllipticPoint
+
+	// the lazily built prepared form of 'v'
+	vPrep *preparedCache
+	// the fixed-base tables of the generators, built by Precompute
+	bases *fixedBaseCache
 }
 
-// Marshal works as the serialization routine
+// Marshal works as the serialization routine. The generators u_2..u_s
+// are appended in sector mode.
 func (pp *PublicParams) Marshal() string {
-	return fmt.Sprintf("%s,%s,%s", pp.v.Marshal(), pp.u.Marshal(), pp.e.Marshal())
+	res := fmt.Sprintf("%s,%s,%s", pp.v.Marshal(), pp.u.Marshal(), pp.e.Marshal())
+	for i := 1; i < len(pp.us); i++ {
+		res += "," + pp.us[i].Marshal()
+	}
+	return res
 }
 
-// ParsePublicParams trys to restore a PublicParams instance from a given string
+// ParsePublicParams trys to restore a PublicParams instance from a given string.
+// All the points are checked to be in the subgroup of order r.
 func ParsePublicParams(s string) (*PublicParams, error) {
+	return parsePublicParams(s, untrustedParsers)
+}
+
+// ParsePublicParamsTrusted works just as ParsePublicParams does, except that
+// the subgroup checks are skipped. It should be used for the trusted input
+// only, e.g. the PublicParams generated & stored locally.
+func ParsePublicParamsTrusted(s string) (*PublicParams, error) {
+	return parsePublicParams(s, trustedParsers)
+}
+
+func parsePublicParams(s string, ps parsers) (*PublicParams, error) {
 	parts := strings.Split(s, ",")
-	if len(parts) != 3 {
+	if len(parts) < 3 {
 		return nil, fmt.Errorf(errParsePublicParamsFmt, "unmatched parts num")
 	}
+	return parsePublicParamsFields(parts[0], parts[1], parts[2], parts[3:], ps)
+}
 
-	v, err := math.ParseEllipticPt(parts[0])
+// parsePublicParamsFields restores a PublicParams instance from the encoded
+// 'v', 'u', 'e' & the sector generators u_2..u_s in 'us'
+func parsePublicParamsFields(vStr, uStr, eStr string, usStr []string, ps parsers) (*PublicParams, error) {
+	v, err := ps.point(vStr)
 	if err != nil {
 		return nil, fmt.Errorf(errParsePublicParamsFmt, err.Error())
 	}
 
-	u, err := math.ParseEllipticPt(parts[1])
+	u, err := ps.point(uStr)
 	if err != nil {
 		return nil, fmt.Errorf(errParsePublicParamsFmt, err.Error())
 	}
 
-	e, err := math.ParseQuadraticElem(parts[2])
+	e, err := ps.quadratic(eStr)
 	if err != nil {
 		return nil, fmt.Errorf(errParsePublicParamsFmt, err.Error())
 	}
 
-	return &PublicParams{
-		v: v,
-		u: u,
-		e: e,
-	}, nil
+	var us []math.EllipticPoint
+	if len(usStr) > 0 {
+		us = []math.EllipticPoint{u}
+		for _, part := range usStr {
+			uj, err := ps.point(part)
+			if err != nil {
+				return nil, fmt.Errorf(errParsePublicParamsFmt, err.Error())
+			}
+			us = append(us, uj)
+		}
+	}
+
+	return restorePublicParams(v, u, e, us), nil
+}
+
+// parsers picks the routines to parse the points & the quadratic elements,
+// with or without the group checks
+type parsers struct {
+	point     func(string) (math.EllipticPoint, error)
+	quadratic func(string) (math.QuadraticElem, error)
 }
 
+// psuedo-constant
+var (
+	untrustedParsers = parsers{
+		point:     math.ParseEllipticPt,
+		quadratic: math.ParseQuadraticElem,
+	}
+	trustedParsers = parsers{
+		point:     math.ParseEllipticPtTrusted,
+		quadratic: math.ParseQuadraticElemTrusted,
+	}
+)
+
 // PrivateParams holds the private parameters of a specific PDP proof.
 // Note a PrivateParams instance can be used to validate multiple
 // PublicParams's proof.
@@ -108,11 +170,19 @@ func ParsePrivateParams(s string) (*PrivateParams, error) {
 // Tag is the product of GenTag & a param of the VerifyProof
 type Tag = math.EllipticPoint
 
-// ParseTag try to restore a Tag instance
+// ParseTag try to restore a Tag instance, which is checked to be in the
+// subgroup of order r
 func ParseTag(s string) (Tag, error) {
 	return math.ParseEllipticPt(s)
 }
 
+// ParseTagTrusted works just as ParseTag does, except that the subgroup check
+// is skipped. It should be used for the trusted input only, e.g. the tags
+// that the prover stores along with the data.
+func ParseTagTrusted(s string) (Tag, error) {
+	return math.ParseEllipticPtTrusted(s)
+}
+
 // Chal wraps a validator created random value & corespoding idx
 type Chal struct {
 	idx []byte
@@ -144,8 +214,12 @@ func ParseChal(s string) (Chal, error) {
 	if err != nil {
 		return Chal{}, fmt.Errorf(errParseChalFmt, err.Error())
 	}
+	return parseChalFields(idx, parts[1])
+}
 
-	nu, err := math.ParseGaloisElem(parts[1])
+// parseChalFields restores a Chal instance from the 'idx' & the encoded 'nu'
+func parseChalFields(idx []byte, nuStr string) (Chal, error) {
+	nu, err := math.ParseGaloisElem(nuStr)
 	if err != nil {
 		return Chal{}, fmt.Errorf(errParseChalFmt, err.Error())
 	}
@@ -156,6 +230,51 @@ func ParseChal(s string) (Chal, error) {
 	}, nil
 }
 
+// ChalSet holds a set of Chal instances against multiple blocks. All the
+// challenged blocks are answered by *ONE* aggregated Proof, which is the
+// way the original PDP paper works.
+type ChalSet []Chal
+
+// Marshal works as a serialization routine
+func (cs ChalSet) Marshal() string {
+	parts := make([]string, len(cs))
+	for i := range cs {
+		parts[i] = cs[i].Marshal()
+	}
+	return strings.Join(parts, chalSetSep)
+}
+
+// Equal works
+func (cs ChalSet) Equal(a ChalSet) bool {
+	if len(cs) != len(a) {
+		return false
+	}
+	for i := range cs {
+		if !cs[i].Equal(a[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseChalSet trys to restore a ChalSet instance
+func ParseChalSet(s string) (ChalSet, error) {
+	if len(s) == 0 {
+		return nil, fmt.Errorf(errParseChalSetFmt, "empty challenge set")
+	}
+
+	parts := strings.Split(s, chalSetSep)
+	cs := make(ChalSet, len(parts))
+	for i, part := range parts {
+		c, err := ParseChal(part)
+		if err != nil {
+			return nil, fmt.Errorf(errParseChalSetFmt, err.Error())
+		}
+		cs[i] = c
+	}
+	return cs, nil
+}
+
 // Proof is the product of Prove
 type Proof struct {
 	miu   math.GaloisElem
@@ -168,24 +287,40 @@ func (p *Proof) Marshal() string {
 	return fmt.Sprintf("%s,%s,%s", p.miu.Marshal(), p.sigma.Marshal(), p.r.Marshal())
 }
 
-// ParseProof trys to restore a Proof instance by parsing given string
+// ParseProof trys to restore a Proof instance by parsing given string.
+// The sigma & r are checked to be in the groups of order r.
 func ParseProof(s string) (Proof, error) {
+	return parseProof(s, untrustedParsers)
+}
+
+// ParseProofTrusted works just as ParseProof does, except that the group
+// checks are skipped. It should be used for the trusted input only.
+func ParseProofTrusted(s string) (Proof, error) {
+	return parseProof(s, trustedParsers)
+}
+
+func parseProof(s string, ps parsers) (Proof, error) {
 	parts := strings.Split(s, ",")
 	if len(parts) != 3 {
 		return Proof{}, fmt.Errorf(errParseProofFmt, "unmatched parts num")
 	}
+	return parseProofFields(parts[0], parts[1], parts[2], ps)
+}
 
-	miu, err := math.ParseGaloisElem(parts[0])
+// parseProofFields restores a Proof instance from the encoded 'miu', 'sigma'
+// & 'r'
+func parseProofFields(miuStr, sigmaStr, rStr string, ps parsers) (Proof, error) {
+	miu, err := math.ParseGaloisElem(miuStr)
 	if err != nil {
 		return Proof{}, fmt.Errorf(errParseProofFmt, err.Error())
 	}
 
-	sigma, err := math.ParseEllipticPt(parts[1])
+	sigma, err := ps.point(sigmaStr)
 	if err != nil {
 		return Proof{}, fmt.Errorf(errParseProofFmt, err.Error())
 	}
 
-	r, err := math.ParseQuadraticElem(parts[2])
+	r, err := ps.quadratic(rStr)
 	if err != nil {
 		return Proof{}, fmt.Errorf(errParseProofFmt, err.Error())
 	}
@@ -198,58 +333,101 @@ func ParseProof(s string) (Proof, error) {
 }
 
 // GeneratePrivateParams returns the PrivateParams instance created using
-// given crypto.PrivKey
+// given crypto.PrivKey. A random salt is drawn every time, see
+// DerivePrivateParams for the reproducible keys.
 func GeneratePrivateParams(sk []byte) (*PrivateParams, error) {
-	salt := make([]byte, scryptR)
-	_, err := rand.Read(salt)
+	kp, err := NewKeyDerivationParams("")
 	if err != nil {
 		return nil, fmt.Errorf(errGeneratePrivateParamFmt, err.Error())
 	}
 
-	saltedKey, err := scrypt.Key(sk, salt, scryptN, scryptR, scryptP, scryptL)
+	key, err := kp.deriveKey(sk, kdfUsagePrivateParams)
 	if err != nil {
 		return nil, fmt.Errorf(errGeneratePrivateParamFmt, err.Error())
 	}
 
 	return &PrivateParams{
-		x: math.HashToGaloisElem(saltedKey),
+		x: math.HashToGaloisElem(key),
 	}, nil
 }
 
 // GeneratePublicParams returns a PublicParams instance generated using
 // the given elliptic curve point 'u'
 func (sp *PrivateParams) GeneratePublicParams(u math.EllipticPoint) *PublicParams {
-	v := math.EllipticPow(math.GetGenerator(), sp.x)
+	return newPublicParams(math.EllipticPowSecret(math.GetGenerator(), sp.x), u)
+}
+
+// newPublicParams returns the PublicParams instance of the public key 'v',
+// i.e. g^x, & the given point 'u'
+func newPublicParams(v, u math.EllipticPoint) *PublicParams {
+	return restorePublicParams(v, u, math.BiLinearMap(u, v), nil)
+}
+
+// restorePublicParams creates a PublicParams instance of the given params,
+// along with the empty precomputation caches
+func restorePublicParams(v, u math.EllipticPoint, e math.QuadraticElem, us []math.EllipticPoint) *PublicParams {
 	return &PublicParams{
-		v: v,
-		u: u,
-		e: math.BiLinearMap(u, v),
+		v:     v,
+		u:     u,
+		e:     e,
+		us:    us,
+		vPrep: &preparedCache{},
+		bases: &fixedBaseCache{},
 	}
 }
 
 // GenTag calculates the tag for given 'data' & 'idx'. Since the 'data' block may
 // be too huge to load into memory, a SHA256 digest is applied here.
 // Note that 'idx' here is actually refers to the (Fid||index) parameter in PDP paper.
-// And we actually apply a different way of 'idx'-related calculating.
+// And we actually apply a different way of 'idx'-related calculating: the same
+// 'idx' of different files results in the same tag index, use GenBlockTag to
+// tell the files apart.
 func GenTag(sp *PrivateParams, pp *PublicParams, idx int64, data io.Reader) (Tag, error) {
 	idxStr := strconv.FormatInt(idx, intStrRadix)
-	hasher := sha256.New() // a singleton hasher maybe?
-	if _, err := io.Copy(hasher, data); err != nil {
+	t, err := genTag(sp, pp, []byte(idxStr), data)
+	if err != nil {
 		return Tag{}, fmt.Errorf(errGenerateDataTagFmt, idxStr, err.Error())
 	}
-	m := math.BytesToGaloisElem(hasher.Sum(nil))
+	return t, nil
+}
 
-	t := math.HashToEllipticPt([]byte(idxStr))
-	t = math.EllipticMul(t, math.EllipticPow(pp.u, m))
-	return math.EllipticPow(t, sp.x), nil
+// GenBlockTag calculates the tag for given 'data' of the block identified by 'id'.
+// It works in the same way that GenTag does, except that the file the block
+// belongs to is also bound to the tag.
+func GenBlockTag(sp *PrivateParams, pp *PublicParams, id BlockID, data io.Reader) (Tag, error) {
+	t, err := genTag(sp, pp, id.Bytes(), data)
+	if err != nil {
+		return Tag{}, fmt.Errorf(errGenerateDataTagFmt, id.String(), err.Error())
+	}
+	return t, nil
+}
+
+// genTag calculates the tag (H(idx) * u^m)^x for the given raw 'idx'
+func genTag(sp *PrivateParams, pp *PublicParams, idx []byte, data io.Reader) (Tag, error) {
+	t, err := tagBase(pp, idx, data)
+	if err != nil {
+		return Tag{}, err
+	}
+	return math.EllipticPowSecret(t, sp.x), nil
+}
+
+// tagBase calculates H(idx) * u^m, which a tag is the x-th power of
+func tagBase(pp *PublicParams, idx []byte, data io.Reader) (math.EllipticPoint, error) {
+	m, err := hashData(data)
+	if err != nil {
+		return math.EllipticPoint{}, err
+	}
+
+	t := math.HashToEllipticPt(idx)
+	return math.EllipticMul(t, pp.genPow(0, m)), nil
 }
 
 // GenChal created a challenge instance for given 'idx'.
 // Note that 'idx' here is actually refers to the (Fid||index) parameter in PDP paper.
 // Here the 'idx' works just as in GenTag() implementation.
 // Also, GenChal creates just *ONE* challenge against the given 'idx'. According to
-// the original paper, there should be a set of challenge against *ONE* file, which,
-// however, is not going well with Lambda's system design.
+// the original paper, there should be a set of challenge against *ONE* file, which
+// is what GenChalSet does.
 func GenChal(idx int64) (Chal, error) {
 	idxStr := strconv.FormatInt(idx, intStrRadix)
 	nu, err := math.RandGaloisElem()
@@ -274,30 +452,131 @@ func GenChalWithSeed(idx int64, rand []byte) (Chal, error) {
 	}, nil
 }
 
+// GenBlockChal creates a challenge instance for the block identified by 'id'.
+// Here the 'id' works just as in GenBlockTag() implementation.
+func GenBlockChal(id BlockID) (Chal, error) {
+	nu, err := math.RandGaloisElem()
+	if err != nil {
+		return Chal{}, fmt.Errorf(errGenerateDataChalFmt, id.String(), err.Error())
+	}
+	return Chal{
+		idx: id.Bytes(),
+		nu:  nu,
+	}, nil
+}
+
+// GenBlockChalWithSeed creates a challenge instance for the block identified
+// by 'id', with an external entropy input 'rand'.
+func GenBlockChalWithSeed(id BlockID, rand []byte) (Chal, error) {
+	return Chal{
+		idx: id.Bytes(),
+		nu:  math.BytesToGaloisElem(rand),
+	}, nil
+}
+
+// GenChalSet creates a challenge set against all the given 'idxs', each of
+// which comes with its own random value.
+func GenChalSet(idxs []int64) (ChalSet, error) {
+	cs := make(ChalSet, len(idxs))
+	for i, idx := range idxs {
+		c, err := GenChal(idx)
+		if err != nil {
+			return nil, err
+		}
+		cs[i] = c
+	}
+	return cs, nil
+}
+
+// GenBlockChalSet creates a challenge set against all the blocks identified
+// by 'ids', each of which comes with its own random value.
+func GenBlockChalSet(ids []BlockID) (ChalSet, error) {
+	cs := make(ChalSet, len(ids))
+	for i, id := range ids {
+		c, err := GenBlockChal(id)
+		if err != nil {
+			return nil, err
+		}
+		cs[i] = c
+	}
+	return cs, nil
+}
+
+// GenChalSetWithSeed creates a challenge set against all the given 'idxs'.
+// The random value of each Chal is derived from 'rand' & its position in
+// the set, so a given (idxs, rand) pair always results in the same set.
+func GenChalSetWithSeed(idxs []int64, rand []byte) (ChalSet, error) {
+	cs := make(ChalSet, len(idxs))
+	for i, idx := range idxs {
+		h := sha256.New()
+		h.Write(rand)
+		h.Write([]byte(strconv.Itoa(i)))
+		c, err := GenChalWithSeed(idx, h.Sum(nil))
+		if err != nil {
+			return nil, err
+		}
+		cs[i] = c
+	}
+	return cs, nil
+}
+
 // Prove created a Proof instance against the given challenge & the local storage.
 // Note that in this implementation a Chal instance contains only *ONE* pair of
-// challenge target index & coresponding random value.
+// challenge target index & coresponding random value. Use ProveSet to answer
+// challenges against multiple blocks at once.
 func Prove(pp *PublicParams, c Chal, t Tag, data io.Reader) (Proof, error) {
-	rand, err := math.RandGaloisElem()
+	p, err := prove(pp, ChalSet{c}, []Tag{t}, []io.Reader{data})
 	if err != nil {
 		return Proof{}, fmt.Errorf(errProveFmt, string(c.idx), err.Error())
 	}
+	return p, nil
+}
+
+// ProveSet creates *ONE* aggregated Proof instance against all the challenges
+// in 'cs'. The 'tags' & 'data' should be given in the same order as 'cs', i.e.
+// tags[i] & data[i] are the tag & the block content challenged by cs[i].
+func ProveSet(pp *PublicParams, cs ChalSet, tags []Tag, data []io.Reader) (Proof, error) {
+	if len(cs) == 0 {
+		return Proof{}, fmt.Errorf(errProveSetFmt, "empty challenge set")
+	}
+	if len(cs) != len(tags) || len(cs) != len(data) {
+		return Proof{}, fmt.Errorf(errProveSetFmt, "unmatched challenges, tags & data num")
+	}
+
+	p, err := prove(pp, cs, tags, data)
+	if err != nil {
+		return Proof{}, fmt.Errorf(errProveSetFmt, err.Error())
+	}
+	return p, nil
+}
+
+// prove folds the challenged blocks into one proof, where
+// sigma = Prod(t_i^nu_i) and miu = gamma * Sum(nu_i * m_i) + rand
+func prove(pp *PublicParams, cs ChalSet, tags []Tag, data []io.Reader) (Proof, error) {
+	rand, err := math.RandGaloisElem()
+	if err != nil {
+		return Proof{}, err
+	}
 	r := math.QuadraticPow(pp.e, rand)
 
-	hasher := sha256.New()
-	if _, err := io.Copy(hasher, data); err != nil {
-		return Proof{}, fmt.Errorf(errProveFmt, string(c.idx), err.Error())
+	var miu math.GaloisElem
+	for i, c := range cs {
+		m, err := hashData(data[i])
+		if err != nil {
+			return Proof{}, err
+		}
+		if i == 0 {
+			miu = math.GaloisMul(c.nu, m)
+			continue
+		}
+		miu = math.GaloisAdd(miu, math.GaloisMul(c.nu, m))
 	}
-	m := math.BytesToGaloisElem(hasher.Sum(nil))
-	miu := math.GaloisMul(c.nu, m)
 	miu = math.GaloisMul(miu, math.HashQuadraticToGalois(r))
 	miu = math.GaloisAdd(miu, rand)
 
-	sigma := math.EllipticPow(t, c.nu)
-
 	return Proof{
 		miu:   miu,
-		sigma: sigma,
+		sigma: math.MultiExp(tags, cs.nus()),
 		r:     r,
 	}, nil
 }
@@ -305,16 +584,134 @@ func Prove(pp *PublicParams, c Chal, t Tag, data io.Reader) (Proof, error) {
 // VerifyProof validates if the given 'p' is exactly a sound
 // proof against the given challenge 'c'
 func VerifyProof(pp *PublicParams, c Chal, p Proof) bool {
+	return VerifyProofSet(pp, ChalSet{c}, p)
+}
+
+// VerifyProofSet validates if the given 'p' is exactly a sound
+// aggregated proof against all the challenges in 'cs'
+func VerifyProofSet(pp *PublicParams, cs ChalSet, p Proof) bool {
+	if len(cs) == 0 {
+		return false
+	}
+
 	gamma := math.HashQuadraticToGalois(p.r)
 
+	// r * e(sigma^gamma, g) == e(X^gamma * u^miu, v) is checked as
+	// r * e(sigma^gamma, g) * e(-(X^gamma * u^miu), v) == 1
 	lhsParam := math.EllipticPow(p.sigma, gamma)
-	lhs := math.BiLinearMap(lhsParam, math.GetGenerator())
-	lhs = math.QuadraticMul(p.r, lhs)
 
-	rhsParam := math.EllipticPow(math.HashToEllipticPt(c.idx), c.nu)
-	rhsParam = math.EllipticPow(rhsParam, gamma)
-	rhsParam = math.EllipticMul(rhsParam, math.EllipticPow(pp.u, p.miu))
-	rhs := math.BiLinearMap(rhsParam, pp.v)
+	rhsParam := math.EllipticPow(chalPoint(cs), gamma)
+	rhsParam = math.EllipticMul(rhsParam, pp.genPow(0, p.miu))
+
+	return pairingCheck(p.r,
+		[]*math.PreparedPoint{math.GetPreparedGenerator(), pp.preparedV()},
+		[]math.EllipticPoint{lhsParam, math.EllipticNeg(rhsParam)})
+}
+
+// pairingCheck validates if r * Prod(e(as[i], bs[i])) == 1. Any infinity
+// param fails the check, since its pairing is always 1 & the verifiers would
+// accept the forged inputs otherwise.
+func pairingCheck(r math.QuadraticElem, as []*math.PreparedPoint, bs []math.EllipticPoint) bool {
+	for i := range as {
+		if as[i].IsInfinity() {
+			return false
+		}
+	}
+	for i := range bs {
+		if bs[i].IsInfinity() {
+			return false
+		}
+	}
+	res := math.QuadraticMul(r, math.MultiPairingPrepared(as, bs))
+	return math.QuadraticEqual(res, math.QuadraticIdentity())
+}
+
+// preparedCache lazily builds & keeps the prepared form of a fixed pairing
+// param, which is shared by all the copies of its owner
+type preparedCache struct {
+	once sync.Once
+	p    *math.PreparedPoint
+}
 
-	return math.QuadraticEqual(lhs, rhs)
+// get returns the prepared form of 'p', a nil cache prepares 'p' every time
+func (c *preparedCache) get(p math.EllipticPoint) *math.PreparedPoint {
+	if c == nil {
+		return math.NewPreparedPoint(p)
+	}
+	c.once.Do(func() {
+		c.p = math.NewPreparedPoint(p)
+	})
+	return c.p
+}
+
+// preparedV returns the prepared form of 'v'
+func (pp *PublicParams) preparedV() *math.PreparedPoint {
+	return pp.vPrep.get(pp.v)
+}
+
+// fixedBaseCache keeps the fixed-base tables of the generators u_1..u_s,
+// which is shared by all the copies of its owner
+type fixedBaseCache struct {
+	once  sync.Once
+	bases atomic.Value // []*math.FixedBase
+}
+
+// Precompute builds the fixed-base tables of the generators u_1..u_s, which
+// speeds up the u^m terms in tagging, proving & verification. It pays off
+// for a long-lived PublicParams instance working on many blocks, and is
+// called by TagFile & its siblings on large files automatically.
+func (pp *PublicParams) Precompute() {
+	if pp.bases == nil {
+		return
+	}
+	pp.bases.once.Do(func() {
+		us := pp.generators()
+		bases := make([]*math.FixedBase, len(us))
+		for j := range us {
+			bases[j] = math.NewFixedBase(us[j])
+		}
+		pp.bases.bases.Store(bases)
+	})
+}
+
+// genPow returns u_j^x, using the fixed-base table if built
+func (pp *PublicParams) genPow(j int, x math.GaloisElem) math.EllipticPoint {
+	if pp.bases != nil {
+		if bases, ok := pp.bases.bases.Load().([]*math.FixedBase); ok {
+			return bases[j].Pow(x)
+		}
+	}
+	return math.EllipticPow(pp.generators()[j], x)
+}
+
+// chalPoint calculates Prod(H(idx_i)^nu_i) of the given challenges
+func chalPoint(cs ChalSet) math.EllipticPoint {
+	return math.MultiExp(cs.points(), cs.nus())
+}
+
+// points returns H(idx_i) of the challenges
+func (cs ChalSet) points() []math.EllipticPoint {
+	res := make([]math.EllipticPoint, len(cs))
+	for i := range cs {
+		res[i] = math.HashToEllipticPt(cs[i].idx)
+	}
+	return res
+}
+
+// nus returns the random values nu_i of the challenges
+func (cs ChalSet) nus() []math.GaloisElem {
+	res := make([]math.GaloisElem, len(cs))
+	for i := range cs {
+		res[i] = cs[i].nu
+	}
+	return res
+}
+
+// hashData maps the content of a data block to a Galois field element
+func hashData(data io.Reader) (math.GaloisElem, error) {
+	hasher := sha256.New() // a singleton hasher maybe?
+	if _, err := io.Copy(hasher, data); err != nil {
+		return math.GaloisElem{}, err
+	}
+	return math.BytesToGaloisElem(hasher.Sum(nil)), nil
 }
@@ -20,9 +20,12 @@ package proofDP
 import (
 	"bytes"
 	"crypto/rand"
+	"encoding/base64"
+	"io"
 	"io/ioutil"
 	mrand "math/rand"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -190,3 +193,255 @@ func TestSampleValue(t *testing.T) {
 
 	t.Logf("VerifyProof(samplePP, sampleChal, sampleProof) = %t\n", VerifyProof(pp, chal, proof))
 }
+
+func TestProofDPSetScheme(t *testing.T) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	require.NoError(t, err)
+
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	pp := sp.GeneratePublicParams(u)
+
+	idxs := make([]int64, len(sampleFiles))
+	blocks := make([][]byte, len(sampleFiles))
+	tags := make([]Tag, len(sampleFiles))
+	for i, sampleFilePath := range sampleFiles {
+		idxs[i] = int64(i)
+		blocks[i], err = ioutil.ReadFile(sampleFilePath)
+		require.NoError(t, err)
+		tags[i], err = GenTag(sp, pp, idxs[i], bytes.NewReader(blocks[i]))
+		require.NoError(t, err)
+	}
+
+	cs, err := GenChalSet(idxs)
+	require.NoError(t, err)
+
+	readers := func() []io.Reader {
+		res := make([]io.Reader, len(blocks))
+		for i := range blocks {
+			res[i] = bytes.NewReader(blocks[i])
+		}
+		return res
+	}
+
+	proof, err := ProveSet(pp, cs, tags, readers())
+	require.NoError(t, err)
+	require.True(t, VerifyProofSet(pp, cs, proof))
+
+	// the proof is bound to the whole challenge set
+	require.False(t, VerifyProofSet(pp, cs[1:], proof))
+	require.False(t, VerifyProof(pp, cs[0], proof))
+
+	// a bit flip in any of the challenged blocks
+	mrand.Seed(time.Now().UnixNano())
+	blkIdx := mrand.Intn(len(blocks))
+	blocks[blkIdx][mrand.Intn(len(blocks[blkIdx]))] ^= byte(1 << 7)
+	failPrf, err := ProveSet(pp, cs, tags, readers())
+	require.NoError(t, err)
+	require.False(t, VerifyProofSet(pp, cs, failPrf))
+
+	// unmatched inputs
+	_, err = ProveSet(pp, cs, tags[1:], readers())
+	require.Error(t, err)
+	_, err = ProveSet(pp, ChalSet{}, nil, nil)
+	require.Error(t, err)
+}
+
+func TestChalSetMarshal(t *testing.T) {
+	cs, err := GenChalSet([]int64{3, 1, 4, 1, 5})
+	require.NoError(t, err)
+
+	restored, err := ParseChalSet(cs.Marshal())
+	require.NoError(t, err)
+	require.True(t, cs.Equal(restored))
+
+	_, err = ParseChalSet("")
+	require.Error(t, err)
+
+	seed := getRandSecret()
+	cs1, err := GenChalSetWithSeed([]int64{9, 2, 6}, seed)
+	require.NoError(t, err)
+	cs2, err := GenChalSetWithSeed([]int64{9, 2, 6}, seed)
+	require.NoError(t, err)
+	require.True(t, cs1.Equal(cs2))
+	require.False(t, cs1[0].nu.Equal(cs1[1].nu))
+}
+
+func TestVerifyProofPrepared(t *testing.T) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	require.NoError(t, err)
+
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	pp := sp.GeneratePublicParams(u)
+
+	data := getRandFile(fileTestBlockSize)
+	id := NewBlockID([]byte("prepared-file"), 0)
+	tag, err := GenBlockTag(sp, pp, id, bytes.NewReader(data))
+	require.NoError(t, err)
+	chal, err := GenBlockChal(id)
+	require.NoError(t, err)
+	proof, err := Prove(pp, chal, tag, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// the prepared 'v' is built once & shared by concurrent verifications
+	res := make(chan bool, 4)
+	for i := 0; i < cap(res); i++ {
+		go func() {
+			res <- VerifyProof(pp, chal, proof)
+		}()
+	}
+	for i := 0; i < cap(res); i++ {
+		require.True(t, <-res)
+	}
+
+	// a PublicParams instance without the cache still works
+	bare := &PublicParams{v: pp.v, u: pp.u, e: pp.e}
+	require.True(t, VerifyProof(bare, chal, proof))
+}
+
+func TestPairingCheckRejectsInfinity(t *testing.T) {
+	g := math.GetGenerator()
+	inf := math.EllipticMul(g, math.EllipticNeg(g))
+	require.True(t, inf.IsInfinity())
+
+	// e(O, g) * e(O, g) == 1 holds, but must not pass
+	gen := math.GetPreparedGenerator()
+	one := math.QuadraticIdentity()
+	require.False(t, pairingCheck(one, []*math.PreparedPoint{gen, gen}, []math.EllipticPoint{inf, inf}))
+	prepInf := math.NewPreparedPoint(inf)
+	require.False(t, pairingCheck(one, []*math.PreparedPoint{gen, prepInf}, []math.EllipticPoint{inf, g}))
+
+	// nor does a forged proof under the identity 'v'
+	pp := &PublicParams{v: inf, u: g, e: math.BiLinearMap(g, inf)}
+	chal, err := GenBlockChal(NewBlockID([]byte("forged-file"), 0))
+	require.NoError(t, err)
+	require.False(t, VerifyProof(pp, chal, Proof{miu: math.NewGaloisElem(0), sigma: inf, r: one}))
+}
+
+func TestPrecompute(t *testing.T) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	require.NoError(t, err)
+
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	pp := sp.GeneratePublicParams(u)
+
+	data := getRandFile(fileTestBlockSize)
+	id := NewBlockID([]byte("precompute-file"), 0)
+	tag, err := GenBlockTag(sp, pp, id, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// the tags & proofs stay the same with the fixed-base tables built
+	pp.Precompute()
+	precomputed, err := GenBlockTag(sp, pp, id, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, tag.Marshal(), precomputed.Marshal())
+
+	chal, err := GenBlockChal(id)
+	require.NoError(t, err)
+	proof, err := Prove(pp, chal, tag, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.True(t, VerifyProof(pp, chal, proof))
+
+	bare := &PublicParams{v: pp.v, u: pp.u, e: pp.e}
+	bare.Precompute()
+	require.True(t, VerifyProof(bare, chal, proof))
+}
+
+func TestParseLowOrder(t *testing.T) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	require.NoError(t, err)
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	pp := sp.GeneratePublicParams(u)
+
+	data := getRandFile(fileTestBlockSize)
+	id := NewBlockID([]byte("low-order-file"), 0)
+	tag, err := GenBlockTag(sp, pp, id, bytes.NewReader(data))
+	require.NoError(t, err)
+	chal, err := GenBlockChal(id)
+	require.NoError(t, err)
+	proof, err := Prove(pp, chal, tag, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// (0, 0) is a point of order 2 & (0, 1) = sqrt(-1) is of order 4
+	lowPt := base64.StdEncoding.EncodeToString(make([]byte, 128))
+	lowQuad := base64.StdEncoding.EncodeToString(append(make([]byte, 127), 1))
+
+	_, err = ParseTag(lowPt)
+	assert.Error(t, err)
+	_, err = ParseTagTrusted(lowPt)
+	assert.NoError(t, err)
+	_, err = ParseTagTrusted(tag.Marshal())
+	assert.NoError(t, err)
+
+	parts := strings.Split(proof.Marshal(), ",")
+	for _, s := range []string{
+		strings.Join([]string{parts[0], lowPt, parts[2]}, ","),
+		strings.Join([]string{parts[0], parts[1], lowQuad}, ","),
+	} {
+		_, err = ParseProof(s)
+		assert.Error(t, err)
+		_, err = ParseProofTrusted(s)
+		assert.NoError(t, err)
+	}
+	res, err := ParseProofTrusted(proof.Marshal())
+	require.NoError(t, err)
+	assert.True(t, VerifyProof(pp, chal, res))
+
+	parts = strings.Split(pp.Marshal(), ",")
+	for _, s := range []string{
+		strings.Join([]string{lowPt, parts[1], parts[2]}, ","),
+		strings.Join([]string{parts[0], lowPt, parts[2]}, ","),
+		strings.Join([]string{parts[0], parts[1], lowQuad}, ","),
+		strings.Join(append(parts, lowPt), ","),
+	} {
+		_, err = ParsePublicParams(s)
+		assert.Error(t, err)
+		_, err = ParsePublicParamsTrusted(s)
+		assert.NoError(t, err)
+	}
+}
+
+func TestCompressedEncoding(t *testing.T) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	require.NoError(t, err)
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	pp := sp.GeneratePublicParams(u)
+
+	data := getRandFile(fileTestBlockSize)
+	id := NewBlockID([]byte("compressed-file"), 0)
+	tag, err := GenBlockTag(sp, pp, id, bytes.NewReader(data))
+	require.NoError(t, err)
+	chal, err := GenBlockChal(id)
+	require.NoError(t, err)
+	proof, err := Prove(pp, chal, tag, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// the legacy encoding, x || y without the flag byte
+	legacy := func(p math.EllipticPoint) string {
+		return base64.StdEncoding.EncodeToString(p.UncompressedBytes()[1:])
+	}
+	assert.True(t, len(tag.Marshal()) < len(legacy(tag))/2+8)
+
+	res, err := ParseTag(legacy(tag))
+	require.NoError(t, err)
+	assert.Equal(t, tag.Marshal(), res.Marshal())
+
+	parts := strings.Split(proof.Marshal(), ",")
+	parts[1] = legacy(proof.sigma)
+	parts[2] = base64.StdEncoding.EncodeToString(proof.r.UncompressedBytes()[1:])
+	legacyProof, err := ParseProof(strings.Join(parts, ","))
+	require.NoError(t, err)
+	assert.Equal(t, proof.Marshal(), legacyProof.Marshal())
+
+	parts = strings.Split(pp.Marshal(), ",")
+	parts[0], parts[1] = legacy(pp.v), legacy(pp.u)
+	parts[2] = base64.StdEncoding.EncodeToString(pp.e.UncompressedBytes()[1:])
+	legacyPP, err := ParsePublicParams(strings.Join(parts, ","))
+	require.NoError(t, err)
+	assert.Equal(t, pp.Marshal(), legacyPP.Marshal())
+	assert.True(t, VerifyProof(legacyPP, chal, legacyProof))
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/LambdaIM/proofDP/erasure"
+	"github.com/LambdaIM/proofDP/math"
+)
+
+// constant
+const (
+	errEncodeFileFmt  = "Failed to encode file: %s"
+	errExtractFileFmt = "Failed to extract file: %s"
+	errFileNotEncoded = "file not encoded"
+	errInvalidCoding  = "invalid coding parameters"
+)
+
+// Encoded tells if the file is erasure-coded by EncodeFile
+func (m *FileMeta) Encoded() bool {
+	return m.dataBlocks > 0
+}
+
+// DataBlocks returns the num of the original blocks in a stripe
+func (m *FileMeta) DataBlocks() int64 {
+	return m.dataBlocks
+}
+
+// ParityBlocks returns the num of the parity blocks in a stripe
+func (m *FileMeta) ParityBlocks() int64 {
+	return m.parityBlocks
+}
+
+// OrigSize returns the size of the original file before encoding
+func (m *FileMeta) OrigSize() int64 {
+	return m.origSize
+}
+
+// stripeSize returns the size of a stripe of the encoded file in bytes
+func (m *FileMeta) stripeSize() int64 {
+	return (m.dataBlocks + m.parityBlocks) * m.blockSize
+}
+
+func (m *FileMeta) validateCoding() error {
+	if m.dataBlocks <= 0 || m.parityBlocks < 0 || m.dataBlocks+m.parityBlocks > 256 {
+		return errors.New(errInvalidCoding)
+	}
+	// the stripe size overflows int64
+	if m.stripeSize()/(m.dataBlocks+m.parityBlocks) != m.blockSize {
+		return errors.New(errInvalidCoding)
+	}
+	if m.size%m.stripeSize() != 0 {
+		return errors.New(errInvalidCoding)
+	}
+	capacity := m.size / m.stripeSize() * m.dataBlocks * m.blockSize
+	if m.origSize < 0 || m.origSize > capacity || m.origSize <= capacity-m.dataBlocks*m.blockSize && m.size > 0 {
+		return errors.New(errInvalidCoding)
+	}
+	return nil
+}
+
+// porEncoder reads the original file stripe by stripe and outputs the
+// encoded content
+type porEncoder struct {
+	src       io.Reader
+	rs        *erasure.ReedSolomon
+	blockSize int
+	origSize  int64
+	buf       bytes.Buffer
+	eof       bool
+}
+
+func (e *porEncoder) Read(p []byte) (int, error) {
+	for e.buf.Len() == 0 {
+		if e.eof {
+			return 0, io.EOF
+		}
+		if err := e.encodeStripe(); err != nil {
+			return 0, err
+		}
+	}
+	return e.buf.Read(p)
+}
+
+func (e *porEncoder) encodeStripe() error {
+	shards := make([][]byte, e.rs.TotalShards())
+	data := make([]byte, e.rs.DataShards()*e.blockSize)
+	n, err := io.ReadFull(e.src, data)
+	if err == io.EOF {
+		e.eof = true
+		return nil
+	}
+	if err == io.ErrUnexpectedEOF {
+		e.eof = true
+	} else if err != nil {
+		return err
+	}
+	e.origSize += int64(n)
+
+	for i := range shards {
+		if i < e.rs.DataShards() {
+			shards[i] = data[i*e.blockSize : (i+1)*e.blockSize]
+			continue
+		}
+		shards[i] = make([]byte, e.blockSize)
+	}
+	if err := e.rs.Encode(shards); err != nil {
+		return err
+	}
+	for _, shard := range shards {
+		e.buf.Write(shard)
+	}
+	return nil
+}
+
+// EncodeFile erasure-codes the content read from 'r' in stripes of 'dataBlocks'
+// blocks, each of which comes with 'parityBlocks' parity blocks of the
+// systematic Reed-Solomon code over GF(2^8). The encoded file is written to
+// 'w' and all its blocks are tagged as TagFile does, so it is challenged &
+// proved just as a file tagged by TagFile is. The coding parameters are kept
+// in the returned FileMeta.
+func EncodeFile(sp *PrivateParams, pp *PublicParams, fileID []byte, r io.Reader, w io.Writer,
+	blockSize, dataBlocks, parityBlocks int) ([]Tag, *FileMeta, error) {
+	if blockSize <= 0 {
+		return nil, nil, fmt.Errorf(errEncodeFileFmt, errInvalidBlockSize)
+	}
+	rs, err := erasure.New(dataBlocks, parityBlocks)
+	if err != nil {
+		return nil, nil, fmt.Errorf(errEncodeFileFmt, err.Error())
+	}
+
+	encoder := &porEncoder{
+		src:       r,
+		rs:        rs,
+		blockSize: blockSize,
+	}
+	tags, meta, err := tagFile(sp, pp, fileID, io.TeeReader(encoder, w), blockSize, GenBlockTag)
+	if err != nil {
+		return nil, nil, fmt.Errorf(errEncodeFileFmt, err.Error())
+	}
+
+	meta.dataBlocks = int64(dataBlocks)
+	meta.parityBlocks = int64(parityBlocks)
+	meta.origSize = encoder.origSize
+	return tags, meta, nil
+}
+
+// VerifyBlock validates if 'data' is exactly the content of the block
+// identified by 'id' & tagged with 't', i.e. e(t, g) == e(H(id) * u^m, v)
+func VerifyBlock(pp *PublicParams, id BlockID, t Tag, data io.Reader) bool {
+	m, err := hashData(data)
+	if err != nil {
+		return false
+	}
+
+	rhsParam := math.HashToEllipticPt(id.Bytes())
+	rhsParam = math.EllipticMul(rhsParam, pp.genPow(0, m))
+
+	return pairingCheck(math.QuadraticIdentity(),
+		[]*math.PreparedPoint{math.GetPreparedGenerator(), pp.preparedV()},
+		[]math.EllipticPoint{t, math.EllipticNeg(rhsParam)})
+}
+
+// Extract rebuilds the original content of a file encoded by EncodeFile and
+// writes it to 'w'. 'blocks' holds the retrieved blocks of the encoded file
+// by their block numbers. Every block is validated against its tag before
+// being used, so the missing blocks & the corrupted ones are both treated as
+// erasures. At least DataBlocks() valid blocks are required for each stripe.
+func Extract(pp *PublicParams, meta *FileMeta, tags []Tag, blocks map[int64][]byte, w io.Writer) error {
+	if !meta.Encoded() {
+		return fmt.Errorf(errExtractFileFmt, errFileNotEncoded)
+	}
+	if int64(len(tags)) != meta.BlockNum() {
+		return fmt.Errorf(errExtractFileFmt, errUnmatchedTagsNum)
+	}
+	rs, err := erasure.New(int(meta.dataBlocks), int(meta.parityBlocks))
+	if err != nil {
+		return fmt.Errorf(errExtractFileFmt, err.Error())
+	}
+
+	remaining := meta.origSize
+	stripeBlocks := meta.dataBlocks + meta.parityBlocks
+	for first := int64(0); first < meta.BlockNum(); first += stripeBlocks {
+		shards := make([][]byte, stripeBlocks)
+		for i := range shards {
+			blockNo := first + int64(i)
+			data, ok := blocks[blockNo]
+			if !ok || int64(len(data)) != meta.blockSize {
+				continue
+			}
+			if VerifyBlock(pp, meta.blockID(blockNo), tags[blockNo], bytes.NewReader(data)) {
+				shards[i] = data
+			}
+		}
+		if err := rs.Reconstruct(shards); err != nil {
+			return fmt.Errorf(errExtractFileFmt, err.Error())
+		}
+
+		for _, shard := range shards[:meta.dataBlocks] {
+			if remaining < int64(len(shard)) {
+				shard = shard[:remaining]
+			}
+			if _, err := w.Write(shard); err != nil {
+				return fmt.Errorf(errExtractFileFmt, err.Error())
+			}
+			remaining -= int64(len(shard))
+		}
+	}
+	return nil
+}
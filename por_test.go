@@ -0,0 +1,124 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"bytes"
+	mrand "math/rand"
+	"testing"
+	"time"
+
+	"github.com/LambdaIM/proofDP/math"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	porTestDataBlocks   = 4
+	porTestParityBlocks = 2
+)
+
+func TestPoRScheme(t *testing.T) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	require.NoError(t, err)
+
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	pp := sp.GeneratePublicParams(u)
+
+	data := getRandFile(fileTestSize)
+	encoded := &bytes.Buffer{}
+	tags, meta, err := EncodeFile(sp, pp, []byte("por-file"), bytes.NewReader(data), encoded,
+		fileTestBlockSize, porTestDataBlocks, porTestParityBlocks)
+	require.NoError(t, err)
+	require.True(t, meta.Encoded())
+	require.Equal(t, int64(fileTestSize), meta.OrigSize())
+	// 11 original blocks -> 3 stripes of 6 blocks
+	require.Equal(t, int64(18), meta.BlockNum())
+	require.Equal(t, int64(encoded.Len()), meta.Size())
+
+	restored, err := ParseFileMeta(meta.Marshal())
+	require.NoError(t, err)
+	require.Equal(t, meta, restored)
+
+	// the encoded file works with the ordinary proofs
+	cs, err := GenFileChalSet(meta, []int64{0, 5, 17})
+	require.NoError(t, err)
+	proof, err := ProveFile(pp, meta, cs, tags, bytes.NewReader(encoded.Bytes()))
+	require.NoError(t, err)
+	require.True(t, VerifyProofSet(pp, cs, proof))
+
+	blocks := map[int64][]byte{}
+	for i := int64(0); i < meta.BlockNum(); i++ {
+		blocks[i] = encoded.Bytes()[i*fileTestBlockSize : (i+1)*fileTestBlockSize]
+	}
+
+	// lose or corrupt random blocks up to the parity num of each stripe
+	mrand.Seed(time.Now().UnixNano())
+	stripeBlocks := porTestDataBlocks + porTestParityBlocks
+	for first := 0; first < int(meta.BlockNum()); first += stripeBlocks {
+		lost := mrand.Perm(stripeBlocks)[:porTestParityBlocks]
+		delete(blocks, int64(first+lost[0]))
+		corrupted := append([]byte{}, blocks[int64(first+lost[1])]...)
+		corrupted[mrand.Intn(len(corrupted))] ^= byte(1 << 7)
+		blocks[int64(first+lost[1])] = corrupted
+	}
+
+	extracted := &bytes.Buffer{}
+	require.NoError(t, Extract(pp, meta, tags, blocks, extracted))
+	require.True(t, bytes.Equal(data, extracted.Bytes()))
+
+	// one more loss of the intact blocks is beyond the recovery
+	for i := int64(0); i < int64(stripeBlocks); i++ {
+		if block, ok := blocks[i]; ok && bytes.Equal(block, encoded.Bytes()[i*fileTestBlockSize:(i+1)*fileTestBlockSize]) {
+			delete(blocks, i)
+			break
+		}
+	}
+	require.Error(t, Extract(pp, meta, tags, blocks, &bytes.Buffer{}))
+}
+
+func TestPoREdgeCases(t *testing.T) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	require.NoError(t, err)
+
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	pp := sp.GeneratePublicParams(u)
+
+	_, _, err = EncodeFile(sp, pp, []byte("por-file"), bytes.NewReader(nil), &bytes.Buffer{}, fileTestBlockSize, 0, 1)
+	require.Error(t, err)
+
+	tags, meta, err := EncodeFile(sp, pp, []byte("por-file"), bytes.NewReader(nil), &bytes.Buffer{}, fileTestBlockSize, 2, 1)
+	require.NoError(t, err)
+	require.Empty(t, tags)
+	extracted := &bytes.Buffer{}
+	require.NoError(t, Extract(pp, meta, tags, nil, extracted))
+	require.Equal(t, 0, extracted.Len())
+
+	tags, meta, err = TagFile(sp, pp, []byte("plain-file"), bytes.NewReader(getRandFile(10)), fileTestBlockSize)
+	require.NoError(t, err)
+	require.Error(t, Extract(pp, meta, tags, nil, &bytes.Buffer{}))
+
+	_, err = ParseFileMeta("AAAA,1024,6144,4,2,99999")
+	require.Error(t, err)
+	_, err = ParseFileMeta("AAAA,1024,6000,4,2,100")
+	require.Error(t, err)
+	// the stripe size overflows int64
+	_, err = ParseFileMeta("AAAA,72057594037927936,0,1,255,0")
+	require.Error(t, err)
+}
@@ -0,0 +1,269 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/LambdaIM/proofDP/math"
+)
+
+// constant
+const (
+	// SectorSize is the size of a sector in bytes, which keeps the value
+	// of a sector less than the order of gFR
+	SectorSize = 19
+
+	errGenerateSectorTagFmt = "Failed to generate sector tag for given data (index:%s): %s"
+	errProveSectorsFmt      = "Failed to prove sectors against challenge set: %s"
+	errParseSectorProofFmt  = "Failed to restore SectorProof: %s"
+	errNoSectorGenerator    = "no sector generator given"
+	errNoSectorGiven        = "no sector given"
+	errBlockTooLarge        = "block larger than the sectors can hold"
+)
+
+// GenerateSectorPublicParams returns a PublicParams instance working in sector
+// mode, with the given generators 'us' for each sector. Note that the result
+// works as an ordinary PublicParams instance using us[0] as 'u' as well.
+// In sector mode, a block is split into s sectors of gFR elements, and its tag
+// is (H(id) * Prod(u_j^m_j))^x, so that the prover has to keep the data itself
+// rather than the block digests.
+func (sp *PrivateParams) GenerateSectorPublicParams(us []math.EllipticPoint) (*PublicParams, error) {
+	if len(us) == 0 {
+		return nil, errors.New(errNoSectorGenerator)
+	}
+
+	pp := sp.GeneratePublicParams(us[0])
+	pp.us = append([]math.EllipticPoint{}, us...)
+	return pp, nil
+}
+
+// Sectors returns the number of sectors per block
+func (pp *PublicParams) Sectors() int {
+	if len(pp.us) == 0 {
+		return 1
+	}
+	return len(pp.us)
+}
+
+// SectorBlockSize returns the max size of a block in bytes in sector mode
+func (pp *PublicParams) SectorBlockSize() int {
+	return pp.Sectors() * SectorSize
+}
+
+// generators returns u_1..u_s
+func (pp *PublicParams) generators() []math.EllipticPoint {
+	if len(pp.us) == 0 {
+		return []math.EllipticPoint{pp.u}
+	}
+	return pp.us
+}
+
+// readSectors reads the content of a block & splits it into sectors. The last
+// sector is right-padded with zeros, as are the sectors beyond the content.
+func readSectors(pp *PublicParams, data io.Reader) ([]math.GaloisElem, error) {
+	buf := make([]byte, pp.SectorBlockSize())
+	if _, err := io.ReadFull(data, buf); err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+
+	// make sure nothing is left behind
+	n, err := data.Read(make([]byte, 1))
+	if n != 0 {
+		return nil, errors.New(errBlockTooLarge)
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	res := make([]math.GaloisElem, pp.Sectors())
+	for j := range res {
+		res[j] = math.BytesToGaloisElem(buf[j*SectorSize : (j+1)*SectorSize])
+	}
+	return res, nil
+}
+
+// GenSectorTag calculates the sector mode tag for the given 'data' of the
+// block identified by 'id'. The 'data' should be no more than
+// pp.SectorBlockSize() bytes.
+func GenSectorTag(sp *PrivateParams, pp *PublicParams, id BlockID, data io.Reader) (Tag, error) {
+	m, err := readSectors(pp, data)
+	if err != nil {
+		return Tag{}, fmt.Errorf(errGenerateSectorTagFmt, id.String(), err.Error())
+	}
+
+	t := math.HashToEllipticPt(id.Bytes())
+	for j := range m {
+		t = math.EllipticMul(t, pp.genPow(j, m[j]))
+	}
+	return math.EllipticPowSecret(t, sp.x), nil
+}
+
+// SectorProof is the product of ProveSectors, which holds one miu per sector
+type SectorProof struct {
+	mius  []math.GaloisElem
+	sigma math.EllipticPoint
+	r     math.QuadraticElem
+}
+
+// Marshal works as a serialization routine
+func (p *SectorProof) Marshal() string {
+	parts := make([]string, 0, len(p.mius)+2)
+	parts = append(parts, p.sigma.Marshal(), p.r.Marshal())
+	for i := range p.mius {
+		parts = append(parts, p.mius[i].Marshal())
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseSectorProof trys to restore a SectorProof instance by parsing given string
+func ParseSectorProof(s string) (SectorProof, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) < 3 {
+		return SectorProof{}, fmt.Errorf(errParseSectorProofFmt, "unmatched parts num")
+	}
+	return parseSectorProofFields(parts[0], parts[1], parts[2:], untrustedParsers)
+}
+
+// parseSectorProofFields restores a SectorProof instance from the encoded
+// 'sigma', 'r' & the mius of all the sectors
+func parseSectorProofFields(sigmaStr, rStr string, miusStr []string, ps parsers) (SectorProof, error) {
+	if len(miusStr) == 0 {
+		return SectorProof{}, fmt.Errorf(errParseSectorProofFmt, errNoSectorGiven)
+	}
+
+	sigma, err := ps.point(sigmaStr)
+	if err != nil {
+		return SectorProof{}, fmt.Errorf(errParseSectorProofFmt, err.Error())
+	}
+
+	r, err := ps.quadratic(rStr)
+	if err != nil {
+		return SectorProof{}, fmt.Errorf(errParseSectorProofFmt, err.Error())
+	}
+
+	mius := make([]math.GaloisElem, len(miusStr))
+	for i, part := range miusStr {
+		mius[i], err = math.ParseGaloisElem(part)
+		if err != nil {
+			return SectorProof{}, fmt.Errorf(errParseSectorProofFmt, err.Error())
+		}
+	}
+
+	return SectorProof{
+		mius:  mius,
+		sigma: sigma,
+		r:     r,
+	}, nil
+}
+
+// ProveSectors creates *ONE* aggregated SectorProof against all the challenges
+// in 'cs', where sigma = Prod(t_i^nu_i) and miu_j = gamma * Sum(nu_i * m_ij) + rand_j.
+// The 'tags' & 'data' should be given in the same order as 'cs'.
+func ProveSectors(pp *PublicParams, cs ChalSet, tags []Tag, data []io.Reader) (SectorProof, error) {
+	if len(cs) == 0 {
+		return SectorProof{}, fmt.Errorf(errProveSectorsFmt, "empty challenge set")
+	}
+	if len(cs) != len(tags) || len(cs) != len(data) {
+		return SectorProof{}, fmt.Errorf(errProveSectorsFmt, "unmatched challenges, tags & data num")
+	}
+
+	// r = e(Prod(u_j^rand_j), v) masks all the mius
+	us := pp.generators()
+	rands := make([]math.GaloisElem, len(us))
+	var mask math.EllipticPoint
+	for j := range us {
+		rand, err := math.RandGaloisElem()
+		if err != nil {
+			return SectorProof{}, fmt.Errorf(errProveSectorsFmt, err.Error())
+		}
+		rands[j] = rand
+		if j == 0 {
+			mask = pp.genPow(j, rand)
+			continue
+		}
+		mask = math.EllipticMul(mask, pp.genPow(j, rand))
+	}
+	r := math.BiLinearMap(mask, pp.v)
+	gamma := math.HashQuadraticToGalois(r)
+
+	mius := make([]math.GaloisElem, len(us))
+	for i, c := range cs {
+		m, err := readSectors(pp, data[i])
+		if err != nil {
+			return SectorProof{}, fmt.Errorf(errProveSectorsFmt, err.Error())
+		}
+		for j := range mius {
+			if i == 0 {
+				mius[j] = math.GaloisMul(c.nu, m[j])
+				continue
+			}
+			mius[j] = math.GaloisAdd(mius[j], math.GaloisMul(c.nu, m[j]))
+		}
+	}
+	for j := range mius {
+		mius[j] = math.GaloisAdd(math.GaloisMul(mius[j], gamma), rands[j])
+	}
+
+	return SectorProof{
+		mius:  mius,
+		sigma: math.MultiExp(tags, cs.nus()),
+		r:     r,
+	}, nil
+}
+
+// VerifySectorProof validates if the given 'p' is exactly a sound
+// sector mode proof against all the challenges in 'cs'
+func VerifySectorProof(pp *PublicParams, cs ChalSet, p SectorProof) bool {
+	us := pp.generators()
+	if len(cs) == 0 || len(p.mius) != len(us) {
+		return false
+	}
+
+	gamma := math.HashQuadraticToGalois(p.r)
+
+	lhsParam := math.EllipticPow(p.sigma, gamma)
+
+	rhsParam := math.EllipticPow(chalPoint(cs), gamma)
+	for j := range us {
+		rhsParam = math.EllipticMul(rhsParam, pp.genPow(j, p.mius[j]))
+	}
+
+	return pairingCheck(p.r,
+		[]*math.PreparedPoint{math.GetPreparedGenerator(), pp.preparedV()},
+		[]math.EllipticPoint{lhsParam, math.EllipticNeg(rhsParam)})
+}
+
+// TagFileSectors works in the same way that TagFile does, except that the
+// blocks are pp.SectorBlockSize() bytes each and tagged in sector mode.
+func TagFileSectors(sp *PrivateParams, pp *PublicParams, fileID []byte, r io.Reader) ([]Tag, *FileMeta, error) {
+	return tagFile(sp, pp, fileID, r, pp.SectorBlockSize(), GenSectorTag)
+}
+
+// ProveFileSectors works in the same way that ProveFile does, on a file
+// tagged by TagFileSectors.
+func ProveFileSectors(pp *PublicParams, meta *FileMeta, cs ChalSet, tags []Tag, r io.ReaderAt) (SectorProof, error) {
+	chalTags, data, err := meta.challengedBlocks(cs, tags, r)
+	if err != nil {
+		return SectorProof{}, fmt.Errorf(errProveFileFmt, err.Error())
+	}
+	return ProveSectors(pp, cs, chalTags, data)
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/LambdaIM/proofDP/math"
+	"github.com/stretchr/testify/require"
+)
+
+const sectorTestNum = 4
+
+func getSectorParams(t *testing.T) (*PrivateParams, *PublicParams) {
+	sp, err := GeneratePrivateParams(getRandSecret())
+	require.NoError(t, err)
+
+	us := make([]math.EllipticPoint, sectorTestNum)
+	for j := range us {
+		us[j], err = math.RandEllipticPt()
+		require.NoError(t, err)
+	}
+	pp, err := sp.GenerateSectorPublicParams(us)
+	require.NoError(t, err)
+	return sp, pp
+}
+
+func TestSectorScheme(t *testing.T) {
+	sp, pp := getSectorParams(t)
+	require.Equal(t, sectorTestNum, pp.Sectors())
+
+	restoredPP, err := ParsePublicParams(pp.Marshal())
+	require.NoError(t, err)
+	require.Equal(t, pp.Marshal(), restoredPP.Marshal())
+	require.Equal(t, sectorTestNum, restoredPP.Sectors())
+
+	data := getRandFile(5*pp.SectorBlockSize() + 7)
+	tags, meta, err := TagFileSectors(sp, pp, []byte("sector-file"), bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, int64(6), meta.BlockNum())
+
+	cs, err := GenFileChalSet(meta, []int64{1, 3, 5})
+	require.NoError(t, err)
+
+	proof, err := ProveFileSectors(restoredPP, meta, cs, tags, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.True(t, VerifySectorProof(restoredPP, cs, proof))
+
+	restoredProof, err := ParseSectorProof(proof.Marshal())
+	require.NoError(t, err)
+	require.True(t, VerifySectorProof(pp, cs, restoredProof))
+
+	// the proof is bound to the challenge set
+	require.False(t, VerifySectorProof(pp, cs[:2], proof))
+	restoredProof.mius = restoredProof.mius[1:]
+	require.False(t, VerifySectorProof(pp, cs, restoredProof))
+
+	// a bit flip in any sector of a challenged block
+	data[3*pp.SectorBlockSize()+2*SectorSize+5] ^= byte(1 << 3)
+	proof, err = ProveFileSectors(pp, meta, cs, tags, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.False(t, VerifySectorProof(pp, cs, proof))
+}
+
+func TestSectorTagEdgeCases(t *testing.T) {
+	sp, pp := getSectorParams(t)
+
+	_, err := sp.GenerateSectorPublicParams(nil)
+	require.Error(t, err)
+
+	id := NewBlockID([]byte("sector-file"), 0)
+	_, err = GenSectorTag(sp, pp, id, bytes.NewReader(getRandFile(pp.SectorBlockSize()+1)))
+	require.Error(t, err)
+
+	// an ordinary PublicParams works as a single sector one
+	u, err := math.RandEllipticPt()
+	require.NoError(t, err)
+	single := sp.GeneratePublicParams(u)
+	require.Equal(t, 1, single.Sectors())
+
+	data := getRandFile(SectorSize)
+	tag, err := GenSectorTag(sp, single, id, bytes.NewReader(data))
+	require.NoError(t, err)
+	cs, err := GenBlockChalSet([]BlockID{id})
+	require.NoError(t, err)
+	proof, err := ProveSectors(single, cs, []Tag{tag}, []io.Reader{bytes.NewReader(data)})
+	require.NoError(t, err)
+	require.True(t, VerifySectorProof(single, cs, proof))
+}
@@ -18,30 +18,35 @@
 package proofDP
 
 import (
-	"crypto/rand"
 	"crypto/sha256"
+	"fmt"
 
 	"github.com/LambdaIM/proofDP/math"
-	"golang.org/x/crypto/scrypt"
 )
 
+// Here I implement a pairing-based BLS DSA. Every verification takes a
+// pairing product of 2 pairings, thus the signatures of many signers are
+// better aggregated & verified at once, see aggregate.go.
+
+// constant
 const (
-	scryptN = 32768
-	scryptR = 8
-	scryptP = 1
-	scryptL = 32
+	errIdentitySignPubKey = "identity public key"
 )
 
-// Here I implement a pairing-based BLS DSA. However, the performance of
-// this implementation is not that good. 1024 times sign-verification takes
-// around 140+ seconds which is a little bit too much.
-
 // Signature is a wrapper of the inner type
 type Signature = math.EllipticPoint
 
 // SignPubKey is the public key for PDP signature verification
 type SignPubKey struct {
 	key math.EllipticPoint
+
+	// the lazily built prepared form of 'key'
+	prep *preparedCache
+}
+
+// prepared returns the prepared form of the key
+func (pk SignPubKey) prepared() *math.PreparedPoint {
+	return pk.prep.get(pk.key)
 }
 
 // SignPrivKey is the private key for PDP signature
@@ -50,44 +55,73 @@ type SignPrivKey struct {
 	Pk  SignPubKey
 }
 
-// GenerateSignPrivKeyFromSecret creates a new SignPrivKey instance
+// GenerateSignPrivKeyFromSecret creates a new SignPrivKey instance. A random
+// salt is drawn every time, see DeriveSignPrivKey for the reproducible keys.
 func GenerateSignPrivKeyFromSecret(secret []byte) (*SignPrivKey, error) {
-	salt := make([]byte, scryptR)
-	_, err := rand.Read(salt)
+	kp, err := NewKeyDerivationParams("")
 	if err != nil {
 		return nil, err
 	}
 
-	saltedSecret, err := scrypt.Key(secret, salt, scryptN, scryptR, scryptP, scryptL)
+	key, err := kp.deriveKey(secret, kdfUsageSignPrivKey)
 	if err != nil {
 		return nil, err
 	}
 
-	k := math.HashToGaloisElem(saltedSecret)
+	return newSignPrivKey(math.HashToGaloisElem(key)), nil
+}
+
+// newSignPrivKey creates a SignPrivKey instance of the private key 'k' along
+// with its public key
+func newSignPrivKey(k math.GaloisElem) *SignPrivKey {
 	return &SignPrivKey{
 		key: k,
-		Pk: SignPubKey{
-			key: math.EllipticPow(math.GetGenerator(), k),
-		},
-	}, nil
+		Pk:  newSignPubKey(math.EllipticPowSecret(math.GetGenerator(), k)),
+	}
+}
+
+// newSignPubKey creates a SignPubKey instance of the public key 'key'
+func newSignPubKey(key math.EllipticPoint) SignPubKey {
+	return SignPubKey{
+		key:  key,
+		prep: &preparedCache{},
+	}
+}
+
+// ParseSignPubKey trys to restore a SignPubKey instance from the base64
+// encoded key. The key is checked to be in the subgroup of order r, and the
+// identity key is rejected.
+func ParseSignPubKey(s string) (SignPubKey, error) {
+	key, err := math.ParseEllipticPt(s)
+	if err != nil {
+		return SignPubKey{}, fmt.Errorf(errParseSignPubKeyFmt, err.Error())
+	}
+	if key.IsInfinity() {
+		return SignPubKey{}, fmt.Errorf(errParseSignPubKeyFmt, errIdentitySignPubKey)
+	}
+	return newSignPubKey(key), nil
 }
 
 // Sign generates a signature using SignPrivKey instance on
 // given hash
 func (sk *SignPrivKey) Sign(h [sha256.Size]byte) Signature {
 	d := math.HashToEllipticPt(h[:])
-	return math.EllipticPow(d, sk.key)
+	return math.EllipticPowSecret(d, sk.key)
 }
 
 // VerifySignature validates if a signature is signed using 'pk'-responding
-// SignPrivKey instance on the given hash 'h'.
+// SignPrivKey instance on the given hash 'h'. The identity signature & the
+// identity key are always rejected, as they pass the pairing check for any
+// hash.
 func VerifySignature(s Signature, h [sha256.Size]byte, pk SignPubKey) bool {
-	lhs := math.BiLinearMap(s, math.GetGenerator())
-
+	if s.IsInfinity() || pk.key.IsInfinity() {
+		return false
+	}
+	// e(s, g) == e(d, pk) is checked as e(s, g) * e(-d, pk) == 1
 	d := math.HashToEllipticPt(h[:])
-	rhs := math.BiLinearMap(d, pk.key)
-
-	return math.QuadraticEqual(lhs, rhs)
+	return pairingCheck(math.QuadraticIdentity(),
+		[]*math.PreparedPoint{math.GetPreparedGenerator(), pk.prepared()},
+		[]math.EllipticPoint{s, math.EllipticNeg(d)})
 }
 
 // TODO: implement the github.com/tendermint/crypto.PrivKey & PubKey interfaces
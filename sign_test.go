@@ -22,7 +22,9 @@ import (
 	"crypto/sha256"
 	"testing"
 
+	"github.com/LambdaIM/proofDP/math"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -48,3 +50,36 @@ func TestBLSScheme(t *testing.T) {
 		assert.True(t, VerifySignature(signature, hash, sk.Pk))
 	}
 }
+
+func TestIdentitySignature(t *testing.T) {
+	g := math.GetGenerator()
+	inf := math.EllipticMul(g, math.EllipticNeg(g))
+	identity := newSignPubKey(inf)
+	hash := sha256.Sum256([]byte("any message"))
+
+	// e(O, g) == e(H(h), O) holds for any hash, but must not pass
+	require.False(t, VerifySignature(inf, hash, identity))
+
+	k, err := math.RandGaloisElem()
+	require.NoError(t, err)
+	sk := newSignPrivKey(k)
+	require.False(t, VerifySignature(inf, hash, sk.Pk))
+	require.False(t, VerifySignature(sk.Sign(hash), hash, identity))
+
+	// the identity key is never restored
+	text, err := identity.MarshalText()
+	require.NoError(t, err)
+	_, err = ParseSignPubKey(string(text))
+	require.Error(t, err)
+	var pk SignPubKey
+	require.Error(t, pk.UnmarshalText(text))
+	data, err := identity.MarshalBinary()
+	require.NoError(t, err)
+	require.Error(t, pk.UnmarshalBinary(data))
+
+	text, err = sk.Pk.MarshalText()
+	require.NoError(t, err)
+	restored, err := ParseSignPubKey(string(text))
+	require.NoError(t, err)
+	require.True(t, VerifySignature(sk.Sign(hash), hash, restored))
+}
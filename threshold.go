@@ -0,0 +1,376 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/LambdaIM/proofDP/math"
+)
+
+// constant
+const (
+	errSplitSignKeyFmt         = "Failed to split SignPrivKey: %s"
+	errCombineSignaturesFmt    = "Failed to combine partial signatures: %s"
+	errParseSignKeyShareFmt    = "Failed to restore SignKeyShare: %s"
+	errParsePartialSigFmt      = "Failed to restore PartialSignature: %s"
+	errParseShareCommitmentFmt = "Failed to restore ShareCommitments: %s"
+	errInvalidThreshold        = "invalid threshold"
+	errInvalidShareIndex       = "invalid share index"
+	errDuplicateShareIndex     = "duplicate share index"
+	errIdentityCommitment      = "identity commitment"
+
+	// the max num of the members of a committee
+	maxShareNum = 1 << 16
+)
+
+// SignKeyShare is the share of a SignPrivKey held by a member of the committee,
+// i.e. f(i) of the member i, where f(z) = x + a_1 z + ... + a_{t-1} z^{t-1} is
+// the Shamir sharing polynomial of the private key x over gFR
+type SignKeyShare struct {
+	index int
+	key   math.GaloisElem
+}
+
+// Index returns the index of the member holding the share, starting from 1
+func (s *SignKeyShare) Index() int {
+	return s.index
+}
+
+// Marshal works as a serialization routine
+func (s *SignKeyShare) Marshal() string {
+	return fmt.Sprintf("%d,%s", s.index, s.key.Marshal())
+}
+
+// ParseSignKeyShare trys to restore a SignKeyShare instance
+func ParseSignKeyShare(s string) (SignKeyShare, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return SignKeyShare{}, fmt.Errorf(errParseSignKeyShareFmt, "unmatched parts num")
+	}
+
+	index, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return SignKeyShare{}, fmt.Errorf(errParseSignKeyShareFmt, err.Error())
+	}
+	return parseSignKeyShareFields(index, parts[1])
+}
+
+// parseSignKeyShareFields restores a SignKeyShare instance from the 'index'
+// & the encoded key
+func parseSignKeyShareFields(index int, keyStr string) (SignKeyShare, error) {
+	if err := checkShareIndex(index); err != nil {
+		return SignKeyShare{}, fmt.Errorf(errParseSignKeyShareFmt, err.Error())
+	}
+
+	key, err := math.ParseGaloisElem(keyStr)
+	if err != nil {
+		return SignKeyShare{}, fmt.Errorf(errParseSignKeyShareFmt, err.Error())
+	}
+
+	return SignKeyShare{
+		index: index,
+		key:   key,
+	}, nil
+}
+
+// Sign generates the partial signature of the member on the given hash
+func (s *SignKeyShare) Sign(h [sha256.Size]byte) PartialSignature {
+	d := math.HashToEllipticPt(h[:])
+	return PartialSignature{
+		index: s.index,
+		sig:   math.EllipticPowSecret(d, s.key),
+	}
+}
+
+// parseShareIndex restores a share index in [1, maxShareNum]
+func parseShareIndex(s string) (int, error) {
+	index, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	return index, checkShareIndex(index)
+}
+
+// checkShareIndex validates if 'index' is in [1, maxShareNum]
+func checkShareIndex(index int) error {
+	if index < 1 || index > maxShareNum {
+		return errors.New(errInvalidShareIndex)
+	}
+	return nil
+}
+
+// ShareCommitments holds the Feldman commitments of the coefficients of
+// the sharing polynomial, i.e. g^x, g^a_1, ..., g^a_{t-1}, the first of which
+// is the SignPubKey. It should never be empty.
+type ShareCommitments []math.EllipticPoint
+
+// Threshold returns the num of the shares required to sign
+func (c ShareCommitments) Threshold() int {
+	return len(c)
+}
+
+// PubKey returns the SignPubKey of the shared SignPrivKey
+func (c ShareCommitments) PubKey() SignPubKey {
+	return newSignPubKey(c[0])
+}
+
+// SharePubKey returns the public key of the 'index'-th share, i.e. g^f(i),
+// which verifies the partial signatures of the member
+func (c ShareCommitments) SharePubKey(index int) SignPubKey {
+	return newSignPubKey(math.MultiExp(c, shareIndexPowers(index, len(c))))
+}
+
+// VerifyShare validates if 's' is a share of the SignPrivKey committed by 'c'
+func (c ShareCommitments) VerifyShare(s SignKeyShare) bool {
+	return c.verifyShare(s.index, s.key)
+}
+
+// verifyShare validates if g^share == g^f(index), where the identity g^f(index)
+// is always rejected
+func (c ShareCommitments) verifyShare(index int, share math.GaloisElem) bool {
+	if index < 1 || index > maxShareNum || len(c) == 0 {
+		return false
+	}
+	pk := c.SharePubKey(index)
+	if pk.key.IsInfinity() {
+		return false
+	}
+	ps := math.EllipticPowSecret(math.GetGenerator(), share)
+	return ps.Equal(pk.key)
+}
+
+// Marshal works as a serialization routine
+func (c ShareCommitments) Marshal() string {
+	parts := make([]string, len(c))
+	for i := range c {
+		parts[i] = c[i].Marshal()
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseShareCommitments trys to restore a ShareCommitments instance, all the
+// points are checked to be in the subgroup of order r, and the infinity
+// point is rejected
+func ParseShareCommitments(s string) (ShareCommitments, error) {
+	if len(s) == 0 {
+		return nil, fmt.Errorf(errParseShareCommitmentFmt, "no commitment given")
+	}
+
+	parts := strings.Split(s, ",")
+	c := make(ShareCommitments, len(parts))
+	for i, part := range parts {
+		p, err := math.ParseEllipticPt(part)
+		if err != nil {
+			return nil, fmt.Errorf(errParseShareCommitmentFmt, err.Error())
+		}
+		if p.IsInfinity() {
+			return nil, fmt.Errorf(errParseShareCommitmentFmt, errIdentityCommitment)
+		}
+		c[i] = p
+	}
+	return c, nil
+}
+
+// shareIndexPowers returns i^0, i^1, ..., i^(n-1)
+func shareIndexPowers(index, n int) []math.GaloisElem {
+	i := math.NewGaloisElem(int64(index))
+	res := make([]math.GaloisElem, n)
+	res[0] = math.NewGaloisElem(1)
+	for j := 1; j < n; j++ {
+		res[j] = math.GaloisMul(res[j-1], i)
+	}
+	return res
+}
+
+// Split shares the SignPrivKey among 'n' members, any 't' of whom are able
+// to sign on behalf of the key. The i-th share goes to the member of index
+// i + 1, along with the commitments to verify it.
+func (sk *SignPrivKey) Split(t, n int) ([]SignKeyShare, ShareCommitments, error) {
+	if t < 1 || t > n || n > maxShareNum {
+		return nil, nil, fmt.Errorf(errSplitSignKeyFmt, errInvalidThreshold)
+	}
+
+	coefs, commitments, err := newSharingPolynomial(sk.key, t)
+	if err != nil {
+		return nil, nil, fmt.Errorf(errSplitSignKeyFmt, err.Error())
+	}
+
+	shares := make([]SignKeyShare, n)
+	for i := range shares {
+		shares[i] = SignKeyShare{
+			index: i + 1,
+			key:   evalPolynomial(coefs, i+1),
+		}
+	}
+	return shares, commitments, nil
+}
+
+// newSharingPolynomial returns the random coefficients of a polynomial of
+// degree t - 1, where f(0) = secret, along with their commitments
+func newSharingPolynomial(secret math.GaloisElem, t int) ([]math.GaloisElem, ShareCommitments, error) {
+	coefs := make([]math.GaloisElem, t)
+	commitments := make(ShareCommitments, t)
+	coefs[0] = secret
+	for j := 1; j < t; j++ {
+		a, err := math.RandGaloisElem()
+		if err != nil {
+			return nil, nil, err
+		}
+		coefs[j] = a
+	}
+	for j := range coefs {
+		commitments[j] = math.EllipticPowSecret(math.GetGenerator(), coefs[j])
+	}
+	return coefs, commitments, nil
+}
+
+// evalPolynomial returns f(index) by the Horner's method
+func evalPolynomial(coefs []math.GaloisElem, index int) math.GaloisElem {
+	z := math.NewGaloisElem(int64(index))
+	y := coefs[len(coefs)-1]
+	for j := len(coefs) - 2; j >= 0; j-- {
+		y = math.GaloisAdd(math.GaloisMul(y, z), coefs[j])
+	}
+	return y
+}
+
+// PartialSignature is the product of SignKeyShare.Sign
+type PartialSignature struct {
+	index int
+	sig   Signature
+}
+
+// Index returns the index of the member signing the partial signature
+func (p *PartialSignature) Index() int {
+	return p.index
+}
+
+// Marshal works as a serialization routine
+func (p *PartialSignature) Marshal() string {
+	return fmt.Sprintf("%d,%s", p.index, p.sig.Marshal())
+}
+
+// ParsePartialSignature trys to restore a PartialSignature instance
+func ParsePartialSignature(s string) (PartialSignature, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return PartialSignature{}, fmt.Errorf(errParsePartialSigFmt, "unmatched parts num")
+	}
+
+	index, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return PartialSignature{}, fmt.Errorf(errParsePartialSigFmt, err.Error())
+	}
+	return parsePartialSignatureFields(index, parts[1])
+}
+
+// parsePartialSignatureFields restores a PartialSignature instance from the
+// 'index' & the encoded signature
+func parsePartialSignatureFields(index int, sigStr string) (PartialSignature, error) {
+	if err := checkShareIndex(index); err != nil {
+		return PartialSignature{}, fmt.Errorf(errParsePartialSigFmt, err.Error())
+	}
+
+	sig, err := math.ParseEllipticPt(sigStr)
+	if err != nil {
+		return PartialSignature{}, fmt.Errorf(errParsePartialSigFmt, err.Error())
+	}
+
+	return PartialSignature{
+		index: index,
+		sig:   sig,
+	}, nil
+}
+
+// VerifyPartialSignature validates if 'p' is signed on the hash 'h' by the
+// share committed by 'c', which tells the bad partial signatures apart
+// before they are combined. The identity share key is always rejected.
+func VerifyPartialSignature(p PartialSignature, h [sha256.Size]byte, c ShareCommitments) bool {
+	if p.index < 1 || p.index > maxShareNum || len(c) == 0 {
+		return false
+	}
+	pk := c.SharePubKey(p.index)
+	if pk.key.IsInfinity() {
+		return false
+	}
+	return VerifySignature(p.sig, h, pk)
+}
+
+// CombineSignatures combines the partial signatures of distinct members into
+// the signature of the shared SignPrivKey, i.e. Prod(s_i^l_i), where l_i is
+// the Lagrange coefficient of the member i at 0. At least the threshold num
+// of valid partial signatures are required, or the result fails to verify
+// against the SignPubKey.
+func CombineSignatures(parts []PartialSignature) (Signature, error) {
+	indices := make([]int, len(parts))
+	sigs := make([]math.EllipticPoint, len(parts))
+	for i := range parts {
+		indices[i], sigs[i] = parts[i].index, parts[i].sig
+	}
+	res, err := combineInExponent(indices, sigs)
+	if err != nil {
+		return Signature{}, fmt.Errorf(errCombineSignaturesFmt, err.Error())
+	}
+	return res, nil
+}
+
+// combineInExponent returns Prod(p_i^l_i), where l_i is the Lagrange
+// coefficient of the distinct index i at 0
+func combineInExponent(indices []int, ps []math.EllipticPoint) (math.EllipticPoint, error) {
+	if len(indices) == 0 {
+		return math.EllipticPoint{}, errors.New(errNothingToAggregate)
+	}
+
+	xs := make([]math.GaloisElem, len(indices))
+	seen := make(map[int]bool, len(indices))
+	for i, index := range indices {
+		if index < 1 || index > maxShareNum {
+			return math.EllipticPoint{}, errors.New(errInvalidShareIndex)
+		}
+		if seen[index] {
+			return math.EllipticPoint{}, errors.New(errDuplicateShareIndex)
+		}
+		seen[index] = true
+		xs[i] = math.NewGaloisElem(int64(index))
+	}
+
+	coefs := make([]math.GaloisElem, len(indices))
+	for i := range coefs {
+		coefs[i] = lagrangeCoef(xs, i)
+	}
+	return math.MultiExp(ps, coefs), nil
+}
+
+// lagrangeCoef returns the Lagrange coefficient of xs[i] at 0, i.e.
+// Prod(x_j / (x_j - x_i)) for all j != i
+func lagrangeCoef(xs []math.GaloisElem, i int) math.GaloisElem {
+	num, den := math.NewGaloisElem(1), math.NewGaloisElem(1)
+	for j := range xs {
+		if j == i {
+			continue
+		}
+		num = math.GaloisMul(num, xs[j])
+		den = math.GaloisMul(den, math.GaloisSub(xs[j], xs[i]))
+	}
+	return math.GaloisMul(num, math.GaloisInv(den))
+}
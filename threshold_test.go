@@ -0,0 +1,159 @@
+// Copyright (c) 2019 lambdastorage.com
+// --------
+// This file is part of The proofDP library.
+//
+// The proofDP is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The proofDP is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the proofDP. If not, see <http://www.gnu.org/licenses/>.
+
+package proofDP
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/LambdaIM/proofDP/math"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	thresholdTestT = 3
+	thresholdTestN = 5
+)
+
+func TestThresholdSign(t *testing.T) {
+	sks, _ := getRandSignPrivKeys(t, 1)
+	sk := sks[0]
+	shares, c, err := sk.Split(thresholdTestT, thresholdTestN)
+	require.NoError(t, err)
+	require.Len(t, shares, thresholdTestN)
+	require.Equal(t, thresholdTestT, c.Threshold())
+	pk := c.PubKey()
+	require.Equal(t, sk.Pk.key.Marshal(), pk.key.Marshal())
+
+	h := sha256.Sum256([]byte("audit verdict"))
+	parts := make([]PartialSignature, len(shares))
+	for i, s := range shares {
+		require.Equal(t, i+1, s.Index())
+		require.True(t, c.VerifyShare(s))
+		parts[i] = s.Sign(h)
+		require.True(t, VerifyPartialSignature(parts[i], h, c))
+	}
+
+	// any t of the n partial signatures work
+	for i := 0; i < thresholdTestN; i++ {
+		for j := i + 1; j < thresholdTestN; j++ {
+			for k := j + 1; k < thresholdTestN; k++ {
+				s, err := CombineSignatures([]PartialSignature{parts[i], parts[j], parts[k]})
+				require.NoError(t, err)
+				require.True(t, VerifySignature(s, h, sk.Pk))
+			}
+		}
+	}
+	s, err := CombineSignatures(parts)
+	require.NoError(t, err)
+	require.True(t, VerifySignature(s, h, pk))
+
+	// but t - 1 of them do not
+	s, err = CombineSignatures(parts[:thresholdTestT-1])
+	require.NoError(t, err)
+	require.False(t, VerifySignature(s, h, sk.Pk))
+
+	// a bad partial signature is caught by the share commitments
+	parts[0] = shares[0].Sign(sha256.Sum256([]byte("another verdict")))
+	require.False(t, VerifyPartialSignature(parts[0], h, c))
+	s, err = CombineSignatures(parts[:thresholdTestT])
+	require.NoError(t, err)
+	require.False(t, VerifySignature(s, h, sk.Pk))
+
+	_, err = CombineSignatures([]PartialSignature{parts[1], parts[1], parts[2]})
+	require.Error(t, err)
+	_, err = CombineSignatures([]PartialSignature{{index: 0, sig: parts[1].sig}})
+	require.Error(t, err)
+	_, err = CombineSignatures(nil)
+	require.Error(t, err)
+}
+
+func TestThresholdShares(t *testing.T) {
+	sks, _ := getRandSignPrivKeys(t, 1)
+	sk := sks[0]
+	for _, tn := range [][2]int{{0, 3}, {4, 3}, {1, maxShareNum + 1}} {
+		_, _, err := sk.Split(tn[0], tn[1])
+		require.Error(t, err)
+	}
+
+	// a single share is the key itself
+	shares, c, err := sk.Split(1, 2)
+	require.NoError(t, err)
+	require.True(t, shares[1].key.Equal(sk.key))
+	require.True(t, c.VerifyShare(shares[1]))
+
+	shares, c, err = sk.Split(thresholdTestT, thresholdTestN)
+	require.NoError(t, err)
+
+	// a tampered share, or a share of another index, fails the check
+	tampered := shares[2]
+	tampered.key = math.GaloisAdd(tampered.key, math.NewGaloisElem(1))
+	require.False(t, c.VerifyShare(tampered))
+	tampered = shares[2]
+	tampered.index = 4
+	require.False(t, c.VerifyShare(tampered))
+	_, other, err := sk.Split(thresholdTestT, thresholdTestN)
+	require.NoError(t, err)
+	require.False(t, other.VerifyShare(shares[2]))
+
+	share, err := ParseSignKeyShare(shares[2].Marshal())
+	require.NoError(t, err)
+	require.Equal(t, shares[2].Marshal(), share.Marshal())
+	restored, err := ParseShareCommitments(c.Marshal())
+	require.NoError(t, err)
+	require.True(t, restored.VerifyShare(share))
+
+	h := sha256.Sum256([]byte("audit verdict"))
+	part := share.Sign(h)
+	restoredPart, err := ParsePartialSignature(part.Marshal())
+	require.NoError(t, err)
+	require.Equal(t, 3, restoredPart.Index())
+	require.True(t, VerifyPartialSignature(restoredPart, h, restored))
+
+	for _, s := range []string{"", "0," + shares[0].key.Marshal(), "x,y", "1"} {
+		_, err = ParseSignKeyShare(s)
+		require.Error(t, err, s)
+		_, err = ParsePartialSignature(s)
+		require.Error(t, err, s)
+	}
+	_, err = ParseShareCommitments("")
+	require.Error(t, err)
+}
+
+func TestIdentityShareKey(t *testing.T) {
+	_, pks := getRandSignPrivKeys(t, 1)
+	g := math.GetGenerator()
+	inf := math.EllipticMul(g, math.EllipticNeg(g))
+
+	// the crafted commitments {-C, C} make g^f(1) the identity
+	c := ShareCommitments{math.EllipticNeg(pks[0].key), pks[0].key}
+	identity := c.SharePubKey(1)
+	require.True(t, identity.key.IsInfinity())
+	h := sha256.Sum256([]byte("forged verdict"))
+	require.False(t, VerifyPartialSignature(PartialSignature{index: 1, sig: inf}, h, c))
+	require.False(t, c.VerifyShare(SignKeyShare{index: 1, key: math.NewGaloisElem(0)}))
+
+	// the infinity commitments are never restored
+	_, err := ParseShareCommitments(ShareCommitments{pks[0].key, inf}.Marshal())
+	require.Error(t, err)
+	_, err = ParseShareCommitments(ShareCommitments{inf}.Marshal())
+	require.Error(t, err)
+	restored, err := ParseShareCommitments(c.Marshal())
+	require.NoError(t, err)
+	require.Len(t, restored, 2)
+}